@@ -20,6 +20,15 @@ var (
 func Initialize() {
 	// Initialize database using the existing method
 	database.InitDB()
+
+	// Start background jobs
+	handlers.StartWaitlistExpiry()
+	handlers.StartNotifications()
+	handlers.StartBedsCountCheck()
+	handlers.StartBedReservations()
+	handlers.StartBedRequestQueue()
+	handlers.StartBedEvents()
+
 	log.Println("Application initialized successfully")
 }
 
@@ -43,11 +52,41 @@ func SetupRouter() *mux.Router {
 	r.HandleFunc("/api/doctors", handlers.CreateDoctor).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/appointments/list", handlers.GetFilteredAppointments).Methods("GET")
 	r.HandleFunc("/api/appointments/{id}/status", handlers.UpdateAppointmentStatus).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/appointments/{id}/reschedule", handlers.RescheduleAppointment).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/appointments/walk-in", handlers.CreateWalkInAppointment).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/appointments/series", handlers.CreateAppointmentSeries).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/appointments/series/{id}", handlers.GetAppointmentSeries).Methods("GET")
+	r.HandleFunc("/api/appointments/series/{id}", handlers.UpdateAppointmentSeries).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/appointments/series/{id}/cancel", handlers.CancelAppointmentSeries).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/doctors/{id}/overbook-quota", handlers.GetOverbookQuota).Methods("GET")
+	r.HandleFunc("/api/doctors/{id}/overbook-quota", handlers.UpdateOverbookQuota).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/api/admin/stats", handlers.GetAdminStats).Methods("GET")
 	r.HandleFunc("/api/admin/activity", handlers.GetRecentActivity).Methods("GET")
 	r.HandleFunc("/api/patients", handlers.GetPatients).Methods("GET")
 	r.HandleFunc("/api/patients", handlers.CreatePatient).Methods("POST", "OPTIONS")
 
+	// Notification API endpoints
+	r.HandleFunc("/api/appointments/{id}/notifications", handlers.GetAppointmentNotifications).Methods("GET")
+	r.HandleFunc("/api/patients/{id}/notification-preferences", handlers.GetNotificationPreferences).Methods("GET")
+	r.HandleFunc("/api/patients/{id}/notification-preferences", handlers.UpdateNotificationPreferences).Methods("PUT", "OPTIONS")
+
+	// Appointment waitlist API endpoints
+	r.HandleFunc("/api/waitlist", handlers.GetWaitlist).Methods("GET")
+	r.HandleFunc("/api/waitlist", handlers.JoinWaitlist).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/waitlist/{id}/cancel", handlers.CancelWaitlistEntry).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/waitlist/offers/{id}/confirm", handlers.ConfirmSlotOffer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/waitlist/offers/{id}/decline", handlers.DeclineSlotOffer).Methods("POST", "OPTIONS")
+
+	// Patient portal API endpoints
+	r.HandleFunc("/api/portal/otp/request", handlers.PortalRateLimit(handlers.RequestPortalOTP)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/portal/otp/verify", handlers.PortalRateLimit(handlers.VerifyPortalOTP)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/portal/logout", handlers.PortalRateLimit(handlers.PortalLogout)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/portal/appointments", handlers.PortalRateLimit(handlers.GetPortalAppointments)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/portal/appointments", handlers.PortalRateLimit(handlers.BookPortalAppointment)).Methods("POST")
+	r.HandleFunc("/api/portal/appointments/{id}/reschedule", handlers.PortalRateLimit(handlers.ReschedulePortalAppointment)).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/portal/appointments/{id}/cancel", handlers.PortalRateLimit(handlers.CancelPortalAppointment)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/portal/admission", handlers.PortalRateLimit(handlers.GetPortalAdmission)).Methods("GET", "OPTIONS")
+
 	// Doctor appointments API endpoint (new)
 	r.HandleFunc("/api/doctor/appointments", handlers.GetDoctorAppointments).Methods("GET", "OPTIONS")
 	
@@ -55,6 +94,12 @@ func SetupRouter() *mux.Router {
 	r.HandleFunc("/api/doctor/profile", handlers.GetDoctorProfile).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/doctor/profile/update", handlers.UpdateDoctorProfile).Methods("PUT", "POST", "OPTIONS")
 
+	// Doctor calendar subscription API endpoints
+	r.HandleFunc("/api/doctor/calendar-tokens", handlers.GetCalendarTokens).Methods("GET")
+	r.HandleFunc("/api/doctor/calendar-tokens", handlers.CreateCalendarToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/doctor/calendar-tokens/{id}/revoke", handlers.RevokeCalendarToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/calendar/doctor/{token}.ics", handlers.GetDoctorCalendarFeed).Methods("GET")
+
 	// Doctor bed management API endpoints
 	r.HandleFunc("/api/doctor/beds", handlers.GetDoctorBeds).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/doctor/assign-bed", handlers.AssignBed).Methods("POST", "OPTIONS")
@@ -63,12 +108,95 @@ func SetupRouter() *mux.Router {
 
 	// Bed management API endpoints
 	r.HandleFunc("/api/beds/types", handlers.GetBedTypes).Methods("GET")
+	r.HandleFunc("/api/admin/bed-types", handlers.CreateBedType).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/admin/bed-types/{type}", handlers.UpdateBedType).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/admin/bed-types/{type}", handlers.DeleteBedType).Methods("DELETE")
 	r.HandleFunc("/api/beds/inventory", handlers.GetBedInventory).Methods("GET")
 	r.HandleFunc("/api/beds/add", handlers.CreateBed).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/bulk", handlers.CreateBedsBulk).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/import", handlers.ImportBedsCSV).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/beds/assignments", handlers.GetBedAssignments).Methods("GET")
 	r.HandleFunc("/api/beds/assignments/add", handlers.CreateBedAssignment).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/discharge", handlers.DischargePatient).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/{id}/cleaned", handlers.MarkBedCleaned).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/{id}/status", handlers.UpdateBedStatus).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/{id}/status-log", handlers.GetBedStatusLog).Methods("GET")
 	r.HandleFunc("/api/beds/stats", handlers.GetBedStats).Methods("GET")
+	r.HandleFunc("/api/beds/analytics", handlers.GetBedAnalytics).Methods("GET")
+	r.HandleFunc("/api/beds/forecast", handlers.GetBedForecast).Methods("GET")
 	r.HandleFunc("/api/beds/sync", handlers.SyncBedsCount).Methods("GET", "POST")
+	r.HandleFunc("/api/beds/consistency", handlers.CheckBedsCount).Methods("GET")
+	r.HandleFunc("/api/beds/{id}/location", handlers.UpdateBedLocation).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/beds/{id}/history", handlers.GetBedHistory).Methods("GET")
+	r.HandleFunc("/api/beds/swap", handlers.SwapBeds).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/patients/{id}/bed-timeline", handlers.GetPatientBedTimeline).Methods("GET")
+	r.HandleFunc("/api/beds/available", handlers.SearchAvailableBeds).Methods("GET")
+	r.HandleFunc("/api/beds/events", handlers.StreamBedEvents).Methods("GET")
+	r.HandleFunc("/api/beds/reservations", handlers.GetBedReservations).Methods("GET")
+	r.HandleFunc("/api/beds/reservations", handlers.CreateBedReservation).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/reservations/{id}/convert", handlers.ConvertBedReservation).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/reservations/{id}/cancel", handlers.CancelBedReservation).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/requests", handlers.GetBedRequests).Methods("GET")
+	r.HandleFunc("/api/beds/requests", handlers.CreateBedRequest).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/requests/{id}/suggestion", handlers.GetBedRequestSuggestion).Methods("GET")
+	r.HandleFunc("/api/beds/requests/{id}/allocate", handlers.AllocateBedRequest).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/beds/requests/{id}/cancel", handlers.CancelBedRequest).Methods("POST", "OPTIONS")
+
+	// Admission (inpatient encounter) API endpoints
+	r.HandleFunc("/api/admissions/current", handlers.GetCurrentAdmissions).Methods("GET")
+	r.HandleFunc("/api/admissions/{id}", handlers.GetAdmission).Methods("GET")
+	r.HandleFunc("/api/admissions/{id}", handlers.UpdateAdmission).Methods("PUT", "OPTIONS")
+
+	// Inter-hospital transfer API endpoints
+	r.HandleFunc("/api/transfers", handlers.GetHospitalTransfers).Methods("GET")
+	r.HandleFunc("/api/transfers", handlers.CreateHospitalTransfer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/transfers/{id}", handlers.GetHospitalTransfer).Methods("GET")
+	r.HandleFunc("/api/transfers/{id}/accept", handlers.AcceptHospitalTransfer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/transfers/{id}/reject", handlers.RejectHospitalTransfer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/transfers/{id}/cancel", handlers.CancelHospitalTransfer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/transfers/{id}/depart", handlers.DepartHospitalTransfer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/transfers/{id}/arrive", handlers.ArriveHospitalTransfer).Methods("POST", "OPTIONS")
+
+	// Housekeeping API endpoints
+	r.HandleFunc("/api/housekeeping/tasks", handlers.GetHousekeepingTasks).Methods("GET")
+	r.HandleFunc("/api/housekeeping/tasks/{id}/assign", handlers.AssignHousekeepingTask).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/housekeeping/tasks/{id}/start", handlers.StartHousekeepingTask).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/housekeeping/tasks/{id}/complete", handlers.CompleteHousekeepingTask).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/housekeeping/tasks/{id}/verify", handlers.VerifyHousekeepingTask).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/housekeeping/report", handlers.GetHousekeepingReport).Methods("GET")
+
+	// Emergency department API endpoints
+	r.HandleFunc("/api/ed/board", handlers.GetEDBoard).Methods("GET")
+	r.HandleFunc("/api/ed/visits", handlers.RegisterEDVisit).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/ed/visits/{id}", handlers.GetEDVisit).Methods("GET")
+	r.HandleFunc("/api/ed/visits/{id}/triage", handlers.TriageEDVisit).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/ed/visits/{id}/assign-doctor", handlers.AssignEDDoctor).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/ed/visits/{id}/disposition", handlers.DisposeEDVisit).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/ed/visits/{id}/patient", handlers.IdentifyEDPatient).Methods("PUT", "OPTIONS")
+
+	// Operating theatre API endpoints
+	r.HandleFunc("/api/theatres", handlers.GetTheatres).Methods("GET")
+	r.HandleFunc("/api/theatres", handlers.CreateTheatre).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/theatres/list", handlers.GetTheatreList).Methods("GET")
+	r.HandleFunc("/api/theatres/{id}", handlers.UpdateTheatre).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/surgical-cases", handlers.GetSurgicalCases).Methods("GET")
+	r.HandleFunc("/api/surgical-cases", handlers.BookSurgicalCase).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/surgical-cases/{id}", handlers.GetSurgicalCase).Methods("GET")
+	r.HandleFunc("/api/surgical-cases/{id}", handlers.UpdateSurgicalCase).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/surgical-cases/{id}/start", handlers.StartSurgicalCase).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/surgical-cases/{id}/recovery", handlers.MoveSurgicalCaseToRecovery).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/surgical-cases/{id}/complete", handlers.CompleteSurgicalCase).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/surgical-cases/{id}/cancel", handlers.CancelSurgicalCase).Methods("POST", "OPTIONS")
+
+	// Ward and room API endpoints
+	r.HandleFunc("/api/wards", handlers.GetWards).Methods("GET")
+	r.HandleFunc("/api/wards", handlers.CreateWard).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/wards/{id}", handlers.UpdateWard).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/wards/{id}", handlers.DeleteWard).Methods("DELETE")
+	r.HandleFunc("/api/wards/{id}/rooms", handlers.GetWardRooms).Methods("GET")
+	r.HandleFunc("/api/wards/{id}/rooms", handlers.CreateRoom).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/rooms/{id}", handlers.UpdateRoom).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/rooms/{id}", handlers.DeleteRoom).Methods("DELETE")
 
 	// Hospital management API endpoints
 	r.HandleFunc("/api/hospitals", handlers.GetHospitals).Methods("GET")
@@ -80,6 +208,14 @@ func SetupRouter() *mux.Router {
 	r.HandleFunc("/api/staff/appointments", handlers.GetStaffAppointments).Methods("GET")
 	r.HandleFunc("/api/staff/profile", handlers.GetStaffProfile).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/staff/profile/update", handlers.UpdateStaffProfile).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/staff/check-in", handlers.CheckInPatient).Methods("POST", "OPTIONS")
+
+	// Outpatient queue API endpoints
+	r.HandleFunc("/api/queue", handlers.GetDoctorQueue).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/queue/display", handlers.GetQueueDisplay).Methods("GET")
+	r.HandleFunc("/api/queue/call-next", handlers.CallNextToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/queue/{id}/skip", handlers.SkipToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/queue/{id}/recall", handlers.RecallToken).Methods("POST", "OPTIONS")
 
 	// Setup static file server for the frontend files
 	fs := http.FileServer(http.Dir(FrontendDir))
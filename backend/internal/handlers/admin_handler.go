@@ -9,10 +9,20 @@ import (
 )
 
 type DashboardStats struct {
-	TotalAppointments     int `json:"totalAppointments"`
-	TotalPatients         int `json:"totalPatients"`
-	TotalDoctors          int `json:"totalDoctors"`
-	CompletedAppointments int `json:"completedAppointments"`
+	TotalAppointments     int                    `json:"totalAppointments"`
+	TotalPatients         int                    `json:"totalPatients"`
+	TotalDoctors          int                    `json:"totalDoctors"`
+	CompletedAppointments int                    `json:"completedAppointments"`
+	WalkInAppointments    int                    `json:"walkInAppointments"`
+	WalkInsByDepartment   []DepartmentWalkInLoad `json:"walkInsByDepartment"`
+}
+
+// DepartmentWalkInLoad shows how much of a department's appointment load is walk-ins
+type DepartmentWalkInLoad struct {
+	Department        string `json:"department"`
+	TotalAppointments int    `json:"totalAppointments"`
+	WalkIns           int    `json:"walkIns"`
+	Overbooked        int    `json:"overbooked"`
 }
 
 type Activity struct {
@@ -73,6 +83,43 @@ func GetAdminStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get walk-in appointments
+	err = database.DB.QueryRow("SELECT COUNT(*) FROM Appointment WHERE AppointmentType = 'walk-in'").Scan(&stats.WalkInAppointments)
+	if err != nil {
+		log.Printf("Error counting walk-in appointments: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Get walk-in load per department
+	rows, err := database.DB.Query(`
+		SELECT
+			d.Department,
+			COUNT(*) AS TotalAppointments,
+			SUM(CASE WHEN a.AppointmentType = 'walk-in' THEN 1 ELSE 0 END) AS WalkIns,
+			SUM(CASE WHEN a.Overbooked THEN 1 ELSE 0 END) AS Overbooked
+		FROM Appointment a
+		JOIN Doctors d ON a.DoctorID = d.DoctorID
+		GROUP BY d.Department
+		ORDER BY d.Department
+	`)
+	if err != nil {
+		log.Printf("Error counting walk-ins by department: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	stats.WalkInsByDepartment = []DepartmentWalkInLoad{}
+	for rows.Next() {
+		var load DepartmentWalkInLoad
+		if err := rows.Scan(&load.Department, &load.TotalAppointments, &load.WalkIns, &load.Overbooked); err != nil {
+			log.Printf("Error scanning walk-in load row: %v", err)
+			continue
+		}
+		stats.WalkInsByDepartment = append(stats.WalkInsByDepartment, load)
+	}
+
 	json.NewEncoder(w).Encode(stats)
 }
 
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+var errAdmissionNotFound = errors.New("admission not found")
+
+// admissionDetails describes why and by whom a patient is admitted. They are
+// only used when occupying a bed opens a new admission.
+type admissionDetails struct {
+	AppointmentID     int
+	AdmittingDoctorID int
+	AttendingDoctorID int // defaults to the admitting doctor
+	Diagnosis         string
+	ReservationID     int // the reservation being taken up; 0 claims the patient's own, if any
+}
+
+// openAdmission returns the patient's open admission, creating one for
+// hospitalID when they aren't admitted. The caller must hold the patient lock.
+func openAdmission(tx *sql.Tx, patientID, hospitalID int, admittedAt string, adm admissionDetails) (int64, error) {
+	var admissionID int64
+	err := tx.QueryRow(
+		"SELECT AdmissionID FROM Admissions WHERE PatientID = ? AND Status = 'admitted' FOR UPDATE",
+		patientID).Scan(&admissionID)
+	if err != sql.ErrNoRows {
+		return admissionID, err
+	}
+
+	if adm.AttendingDoctorID == 0 {
+		adm.AttendingDoctorID = adm.AdmittingDoctorID
+	}
+	result, err := tx.Exec(`
+		INSERT INTO Admissions (PatientID, HospitalID, AppointmentID, AdmittingDoctorID, AttendingDoctorID, Diagnosis, AdmittedAt)
+		VALUES (?, ?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, ''), ?)
+	`, patientID, hospitalID, adm.AppointmentID, adm.AdmittingDoctorID, adm.AttendingDoctorID, adm.Diagnosis, admittedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// closeAdmission marks an admission discharged. Assignments from before
+// admissions existed have no admission and are skipped.
+func closeAdmission(tx *sql.Tx, admissionID int64, dischargedAt string) error {
+	if admissionID == 0 {
+		return nil
+	}
+	_, err := tx.Exec(
+		"UPDATE Admissions SET Status = 'discharged', DischargedAt = ? WHERE AdmissionID = ? AND Status = 'admitted'",
+		dischargedAt, admissionID)
+	return err
+}
+
+// admissionFilter narrows the current admissions; zero fields match everything
+type admissionFilter struct {
+	HospitalID int
+	WardID     int
+	DoctorID   int // admitting or attending doctor
+	PatientID  int
+}
+
+// admissionColumns and admissionJoins select an admission with its doctors
+// and, while admitted, its current bed
+const (
+	admissionColumns = `ad.AdmissionID, ad.PatientID, p.FullName, ad.HospitalID, COALESCE(ad.AppointmentID, 0),
+		COALESCE(ad.AdmittingDoctorID, 0), COALESCE(dm.FullName, ''),
+		COALESCE(ad.AttendingDoctorID, 0), COALESCE(dt.FullName, ''),
+		COALESCE(ad.Diagnosis, ''), DATE_FORMAT(ad.AdmittedAt, '%Y-%m-%d'),
+		COALESCE(DATE_FORMAT(ad.DischargedAt, '%Y-%m-%d'), ''), COALESCE(ad.DischargeType, ''),
+		COALESCE(ad.DischargingDoctorID, 0), COALESCE(ad.DischargeSummary, ''), ad.Status,
+		COALESCE(bi.BedID, 0), COALESCE(bi.BedType, ''), ` + bedWardColumns
+	admissionJoins = `JOIN Patients p ON ad.PatientID = p.PatientID
+		LEFT JOIN Doctors dm ON ad.AdmittingDoctorID = dm.DoctorID
+		LEFT JOIN Doctors dt ON ad.AttendingDoctorID = dt.DoctorID
+		LEFT JOIN BedAssignments ba ON ba.AdmissionID = ad.AdmissionID AND ba.DischargeDate IS NULL
+		LEFT JOIN BedInventory bi ON ba.BedID = bi.BedID
+		` + bedWardJoins
+)
+
+// scanAdmission reads a row selected with admissionColumns
+func scanAdmission(row interface{ Scan(...interface{}) error }) (models.Admission, error) {
+	var a models.Admission
+	var p bedPlacement
+	dest := append([]interface{}{
+		&a.AdmissionID, &a.PatientID, &a.PatientName, &a.HospitalID, &a.AppointmentID,
+		&a.AdmittingDoctorID, &a.AdmittingDoctor, &a.AttendingDoctorID, &a.AttendingDoctor,
+		&a.Diagnosis, &a.AdmittedAt, &a.DischargedAt, &a.DischargeType,
+		&a.DischargingDoctorID, &a.DischargeSummary, &a.Status,
+		&a.BedID, &a.BedType,
+	}, p.dest()...)
+	if err := row.Scan(dest...); err != nil {
+		return a, err
+	}
+	if a.BedID != 0 {
+		a.Location = bedLocation(a.BedID, p.WardName, p.RoomNumber, p.Label)
+	}
+	return a, nil
+}
+
+// currentAdmissions lists the patients currently admitted, newest first
+func currentAdmissions(q queryer, f admissionFilter) ([]models.Admission, error) {
+	conditions := []string{"ad.Status = 'admitted'"}
+	var args []interface{}
+	if f.HospitalID != 0 {
+		conditions = append(conditions, "ad.HospitalID = ?")
+		args = append(args, f.HospitalID)
+	}
+	if f.WardID != 0 {
+		conditions = append(conditions, "wd.WardID = ?")
+		args = append(args, f.WardID)
+	}
+	if f.DoctorID != 0 {
+		conditions = append(conditions, "? IN (ad.AdmittingDoctorID, ad.AttendingDoctorID)")
+		args = append(args, f.DoctorID)
+	}
+	if f.PatientID != 0 {
+		conditions = append(conditions, "ad.PatientID = ?")
+		args = append(args, f.PatientID)
+	}
+
+	rows, err := q.Query(`
+		SELECT `+admissionColumns+`
+		FROM Admissions ad
+		`+admissionJoins+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY ad.AdmittedAt DESC, ad.AdmissionID DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	admissions := []models.Admission{}
+	for rows.Next() {
+		a, err := scanAdmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		admissions = append(admissions, a)
+	}
+	return admissions, rows.Err()
+}
+
+// GetCurrentAdmissions lists currently admitted patients, optionally filtered
+// by hospitalId, wardId or doctorId
+func GetCurrentAdmissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var f admissionFilter
+	for name, dst := range map[string]*int{
+		"hospitalId": &f.HospitalID,
+		"wardId":     &f.WardID,
+		"doctorId":   &f.DoctorID,
+	} {
+		if value := r.URL.Query().Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				sendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dst = id
+		}
+	}
+
+	admissions, err := currentAdmissions(database.DB, f)
+	if err != nil {
+		log.Printf("Error querying current admissions: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"admissions": admissions,
+		"total":      len(admissions),
+	})
+}
+
+// loadAdmission returns an admission with all of its bed assignments
+func loadAdmission(admissionID int) (models.Admission, error) {
+	a, err := scanAdmission(database.DB.QueryRow(`
+		SELECT `+admissionColumns+`
+		FROM Admissions ad
+		`+admissionJoins+`
+		WHERE ad.AdmissionID = ?
+	`, admissionID))
+	if err == sql.ErrNoRows {
+		return a, errAdmissionNotFound
+	}
+	if err != nil {
+		return a, err
+	}
+	return a, loadAdmissionAssignments(database.DB, &a)
+}
+
+// loadAdmissionAssignments fills in the bed assignments of an admission's stay
+func loadAdmissionAssignments(q queryer, a *models.Admission) error {
+	rows, err := q.Query(`
+		SELECT ba.AssignmentID, ba.BedID, bi.BedType,
+		       DATE_FORMAT(ba.AdmissionDate, '%Y-%m-%d'), COALESCE(DATE_FORMAT(ba.DischargeDate, '%Y-%m-%d'), ''),
+		       `+bedWardColumns+`
+		FROM BedAssignments ba
+		JOIN BedInventory bi ON ba.BedID = bi.BedID
+		`+bedWardJoins+`
+		WHERE ba.AdmissionID = ?
+		ORDER BY ba.AssignmentID
+	`, a.AdmissionID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ba models.BedAssignment
+		var p bedPlacement
+		dest := append([]interface{}{&ba.AssignmentID, &ba.BedID, &ba.BedType, &ba.AdmissionDate, &ba.DischargeDate}, p.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		ba.PatientID = a.PatientID
+		ba.Status = "current"
+		if ba.DischargeDate != "" {
+			ba.Status = "discharged"
+		}
+		a.Assignments = append(a.Assignments, ba)
+	}
+	return rows.Err()
+}
+
+// patientAdmissions lists a patient's most recent admissions, current or
+// discharged, newest first, each with the bed assignments of the stay
+func patientAdmissions(q queryer, patientID, limit int) ([]models.Admission, error) {
+	rows, err := q.Query(`
+		SELECT `+admissionColumns+`
+		FROM Admissions ad
+		`+admissionJoins+`
+		WHERE ad.PatientID = ?
+		ORDER BY ad.AdmittedAt DESC, ad.AdmissionID DESC
+		LIMIT ?
+	`, patientID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	admissions := []models.Admission{}
+	for rows.Next() {
+		a, err := scanAdmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		admissions = append(admissions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range admissions {
+		if err := loadAdmissionAssignments(q, &admissions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return admissions, nil
+}
+
+// GetAdmission returns an admission with the bed assignments of the stay
+func GetAdmission(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	admissionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid admission ID", http.StatusBadRequest)
+		return
+	}
+
+	a, err := loadAdmission(admissionID)
+	if err == errAdmissionNotFound {
+		sendJSONError(w, "Admission not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching admission %d: %v", admissionID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(a)
+}
+
+// UpdateAdmission changes the attending doctor, diagnosis or discharge summary
+// of an admission. Omitted fields are left unchanged.
+func UpdateAdmission(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	admissionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid admission ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AttendingDoctorID *int    `json:"attendingDoctorId"`
+		Diagnosis         *string `json:"diagnosis"`
+		DischargeSummary  *string `json:"dischargeSummary"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding admission update: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var sets []string
+	var args []interface{}
+	if req.AttendingDoctorID != nil {
+		var doctorExists bool
+		err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Doctors WHERE DoctorID = ?)", *req.AttendingDoctorID).Scan(&doctorExists)
+		if err != nil {
+			log.Printf("Error checking attending doctor: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !doctorExists {
+			sendJSONError(w, "Attending doctor not found", http.StatusNotFound)
+			return
+		}
+		sets = append(sets, "AttendingDoctorID = ?")
+		args = append(args, *req.AttendingDoctorID)
+	}
+	if req.Diagnosis != nil {
+		sets = append(sets, "Diagnosis = NULLIF(?, '')")
+		args = append(args, strings.TrimSpace(*req.Diagnosis))
+	}
+	if req.DischargeSummary != nil {
+		sets = append(sets, "DischargeSummary = NULLIF(?, '')")
+		args = append(args, strings.TrimSpace(*req.DischargeSummary))
+	}
+	if len(sets) == 0 {
+		sendJSONError(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	args = append(args, admissionID)
+	result, err := database.DB.Exec("UPDATE Admissions SET "+strings.Join(sets, ", ")+" WHERE AdmissionID = ?", args...)
+	if err != nil {
+		log.Printf("Error updating admission %d: %v", admissionID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Admissions WHERE AdmissionID = ?)", admissionID).Scan(&exists)
+		if !exists {
+			sendJSONError(w, "Admission not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	a, err := loadAdmission(admissionID)
+	if err != nil {
+		log.Printf("Error fetching admission %d: %v", admissionID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"message":   "Admission updated successfully",
+		"admission": a,
+	})
+}
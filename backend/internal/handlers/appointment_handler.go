@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"hospital-management/backend/internal/database"
 	"hospital-management/backend/internal/models"
+	"hospital-management/backend/internal/notify"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -62,44 +65,11 @@ func CreateAppointment(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// Check if patient already exists based on email
-	var patientID int64
-	patientCheckQuery := "SELECT PatientID FROM Patients WHERE Email = ?"
-	err = tx.QueryRow(patientCheckQuery, req.Patient.Email).Scan(&patientID)
-
-	// If patient doesn't exist, create a new one
+	patientID, err := findOrCreatePatient(tx, req.Patient)
 	if err != nil {
-		log.Printf("Patient not found, creating new patient record: %v", err)
-		// Insert patient
-		patientQuery := `
-			INSERT INTO Patients (FullName, ContactNumber, Email, Address, City, State, PinCode, Gender, Adhar)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-		patientResult, err := tx.Exec(patientQuery,
-			req.Patient.FullName,
-			req.Patient.ContactNumber,
-			req.Patient.Email,
-			req.Patient.Address,
-			req.Patient.City,
-			req.Patient.State,
-			req.Patient.PinCode,
-			req.Patient.Gender,
-			req.Patient.Adhar,
-		)
-		if err != nil {
-			log.Printf("Error inserting patient: %v", err)
-			sendJSONError(w, "Error creating patient record", http.StatusInternalServerError)
-			return
-		}
-
-		patientID, err = patientResult.LastInsertId()
-		if err != nil {
-			log.Printf("Error getting patient ID: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		log.Printf("Using existing patient with ID: %d", patientID)
+		log.Printf("Error inserting patient: %v", err)
+		sendJSONError(w, "Error creating patient record", http.StatusInternalServerError)
+		return
 	}
 
 	// Parse and format appointment date
@@ -140,6 +110,8 @@ func CreateAppointment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	appointmentID, _ := appointmentResult.LastInsertId()
+	go notifyAppointment(appointmentID, notify.EventConfirmation, notify.TemplateData{})
+
 	response := map[string]interface{}{
 		"status":         "success",
 		"appointment_id": appointmentID,
@@ -151,6 +123,39 @@ func CreateAppointment(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// findOrCreatePatient returns the ID of the patient with the given email,
+// creating a new patient record if none exists
+func findOrCreatePatient(tx *sql.Tx, patient models.Patient) (int64, error) {
+	// Check if patient already exists based on email
+	var patientID int64
+	err := tx.QueryRow("SELECT PatientID FROM Patients WHERE Email = ?", patient.Email).Scan(&patientID)
+	if err == nil {
+		log.Printf("Using existing patient with ID: %d", patientID)
+		return patientID, nil
+	}
+
+	// If patient doesn't exist, create a new one
+	log.Printf("Patient not found, creating new patient record: %v", err)
+	patientResult, err := tx.Exec(`
+		INSERT INTO Patients (FullName, ContactNumber, Email, Address, City, State, PinCode, Gender, Adhar)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		patient.FullName,
+		patient.ContactNumber,
+		patient.Email,
+		patient.Address,
+		patient.City,
+		patient.State,
+		patient.PinCode,
+		patient.Gender,
+		patient.Adhar,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return patientResult.LastInsertId()
+}
+
 // Helper function to send JSON error responses
 func sendJSONError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -481,24 +486,183 @@ func UpdateAppointmentStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `UPDATE Appointment SET Status = ? WHERE AppointmentID = ?`
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var doctorID int
+	var appointmentDate, appointmentTime, oldStatus string
+	err = tx.QueryRow(`
+		SELECT DoctorID, DATE_FORMAT(AppointmentDate, '%Y-%m-%d'), AppointmentTime, Status
+		FROM Appointment WHERE AppointmentID = ? FOR UPDATE
+	`, appointmentID).Scan(&doctorID, &appointmentDate, &appointmentTime, &oldStatus)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error fetching appointment: %v", err)
+		}
+		sendJSONError(w, "Appointment not found", http.StatusNotFound)
+		return
+	}
+
+	query := `UPDATE Appointment SET Status = ?, Sequence = Sequence + 1 WHERE AppointmentID = ?`
 
-	result, err := database.DB.Exec(query, statusUpdate.Status, appointmentID)
+	_, err = tx.Exec(query, statusUpdate.Status, appointmentID)
 	if err != nil {
 		log.Printf("Error updating appointment status: %v", err)
 		sendJSONError(w, "Failed to update appointment status", http.StatusInternalServerError)
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		sendJSONError(w, "Appointment not found", http.StatusNotFound)
+	// A cancellation frees the slot for the waitlist
+	if statusUpdate.Status == "cancelled" && oldStatus != "cancelled" {
+		if _, err := offerFreedSlot(tx, doctorID, appointmentDate, appointmentTime); err != nil {
+			log.Printf("Error offering freed slot: %v", err)
+			sendJSONError(w, "Failed to update appointment status", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Failed to update appointment status", http.StatusInternalServerError)
 		return
 	}
 
+	if statusUpdate.Status == "cancelled" && oldStatus != "cancelled" {
+		id, _ := strconv.ParseInt(appointmentID, 10, 64)
+		go notifyAppointment(id, notify.EventCancellation, notify.TemplateData{})
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "success",
 		"message": "Appointment status updated successfully",
 	})
 }
+
+// RescheduleAppointment moves an appointment to another free slot and offers
+// the old slot to the waitlist
+func RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	appointmentID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		sendJSONError(w, "Invalid appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AppointmentDate string `json:"appointment_date"`
+		AppointmentTime string `json:"appointment_time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := rescheduleAppointment(appointmentID, 0, req.AppointmentDate, req.AppointmentTime); err != nil {
+		sendJSONError(w, err.Error(), status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "success",
+		"message":          "Appointment rescheduled successfully",
+		"appointment_date": req.AppointmentDate,
+		"appointment_time": req.AppointmentTime,
+	})
+}
+
+// rescheduleAppointment moves a scheduled appointment to a free slot, offers
+// the old slot to the waitlist and notifies the patient. When patientID is
+// non-zero the appointment must belong to that patient. On failure it returns
+// the HTTP status to report along with the error.
+func rescheduleAppointment(appointmentID int64, patientID int, date, slot string) (int, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Invalid date format")
+	}
+	if date < time.Now().Format("2006-01-02") {
+		return http.StatusBadRequest, fmt.Errorf("Cannot reschedule into the past")
+	}
+	if !isAppointmentSlot(slot) {
+		return http.StatusBadRequest, fmt.Errorf("appointment_time must be one of the bookable slots")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Database error")
+	}
+	defer tx.Rollback()
+
+	var doctorID, ownerID int
+	var oldDate, oldTime, status string
+	err = tx.QueryRow(`
+		SELECT DoctorID, PatientID, DATE_FORMAT(AppointmentDate, '%Y-%m-%d'), AppointmentTime, Status
+		FROM Appointment WHERE AppointmentID = ? FOR UPDATE
+	`, appointmentID).Scan(&doctorID, &ownerID, &oldDate, &oldTime, &status)
+	if err == sql.ErrNoRows || (err == nil && patientID != 0 && ownerID != patientID) {
+		return http.StatusNotFound, fmt.Errorf("Appointment not found")
+	}
+	if err != nil {
+		log.Printf("Error fetching appointment: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Database error")
+	}
+
+	if status != "scheduled" {
+		return http.StatusConflict, fmt.Errorf("Only scheduled appointments can be rescheduled")
+	}
+	if oldDate == date && oldTime == slot {
+		return http.StatusBadRequest, fmt.Errorf("Appointment is already at this time")
+	}
+
+	// Lock the doctor row so the new slot can't be taken concurrently
+	var locked int
+	if err := tx.QueryRow("SELECT 1 FROM Doctors WHERE DoctorID = ? FOR UPDATE", doctorID).Scan(&locked); err != nil {
+		log.Printf("Error locking doctor: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Database error")
+	}
+
+	taken, err := bookedSlots(tx, doctorID, date)
+	if err != nil {
+		log.Printf("Error fetching booked slots: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Database error")
+	}
+	if taken[slot] {
+		return http.StatusConflict, fmt.Errorf("The requested slot is not available")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE Appointment SET AppointmentDate = ?, AppointmentTime = ?, Sequence = Sequence + 1
+		WHERE AppointmentID = ?
+	`, date, slot, appointmentID)
+	if err != nil {
+		log.Printf("Error rescheduling appointment: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Failed to reschedule appointment")
+	}
+
+	if _, err := offerFreedSlot(tx, doctorID, oldDate, oldTime); err != nil {
+		log.Printf("Error offering freed slot: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Failed to reschedule appointment")
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("Database error")
+	}
+
+	go notifyAppointment(appointmentID, notify.EventReschedule, notify.TemplateData{OldDate: oldDate, OldTime: oldTime})
+	return http.StatusOK, nil
+}
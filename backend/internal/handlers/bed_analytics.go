@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hospital-management/backend/internal/database"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Historical bed occupancy analytics built from BedAssignments. Each
+// assignment is a bed stay running from the movement that opened it (or its
+// admission date for stays imported before the ledger) to its discharge date.
+// A patient counts in the midnight census of every day they are still in the
+// bed at the end of, so same-day stays add admissions and discharges but no
+// bed days. Occupancy is measured against the current bed inventory.
+
+// maxAnalyticsDays bounds the date range of one analytics request
+const maxAnalyticsDays = 3 * 366
+
+// analyticsStay is one bed stay overlapping the requested range. Dates are
+// local midnights, as the driver returns DATE columns.
+type analyticsStay struct {
+	HospitalID int
+	BedType    string
+	Start      time.Time
+	End        time.Time // zero while the patient is still in the bed
+	AdmittedAt time.Time // start of the admission the stay belongs to
+	Admission  bool      // the stay opened with an admission
+	Discharge  bool      // the stay closed with a discharge
+}
+
+// analyticsPoint is one bucket of a series
+type analyticsPoint struct {
+	Date            string  `json:"date"` // first day of the bucket
+	Days            int     `json:"days"`
+	BedDays         int     `json:"bedDays"`
+	AvgCensus       float64 `json:"avgCensus"`
+	PeakCensus      int     `json:"peakCensus"`
+	OccupancyRate   float64 `json:"occupancyRate"`
+	Admissions      int     `json:"admissions"`
+	Discharges      int     `json:"discharges"`
+	AvgLengthOfStay float64 `json:"avgLengthOfStay"` // days, over this bucket's discharges
+}
+
+// analyticsSeries is the time series for one hospital and bed type, or for
+// a whole hospital when grouped by hospital
+type analyticsSeries struct {
+	HospitalID         int              `json:"hospitalId"`
+	HospitalName       string           `json:"hospitalName"`
+	BedType            string           `json:"bedType,omitempty"`
+	Beds               int              `json:"beds"`
+	BedDays            int              `json:"bedDays"`
+	AvgCensus          float64          `json:"avgCensus"`
+	OccupancyRate      float64          `json:"occupancyRate"`
+	Admissions         int              `json:"admissions"`
+	Discharges         int              `json:"discharges"`
+	AvgLengthOfStay    float64          `json:"avgLengthOfStay"`
+	MedianLengthOfStay float64          `json:"medianLengthOfStay"`
+	TurnoverRate       float64          `json:"turnoverRate"` // discharges per bed over the range
+	Points             []analyticsPoint `json:"points"`
+
+	census     map[string]int // midnight census by day
+	admissions map[string]int
+	discharges map[string]int
+	stays      map[string][]int // lengths of stay by discharge day
+}
+
+// analyticsBucket returns the first day of the bucket containing day
+func analyticsBucket(day time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	return day
+}
+
+// daysBetween counts calendar days from one date to another; dates are local
+// midnights, so a daylight saving change makes a day 23 or 25 hours long
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// medianOf returns the median of values, which it sorts
+func medianOf(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Ints(values)
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return float64(values[mid])
+	}
+	return float64(values[mid-1]+values[mid]) / 2
+}
+
+// averageOf returns the mean of values
+func averageOf(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return float64(sum) / float64(len(values))
+}
+
+// round2 rounds to two decimal places for presentation
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// loadAnalyticsStays returns the bed stays overlapping [from, to]
+func loadAnalyticsStays(from, to time.Time, hospitalID int, bedType string) ([]analyticsStay, error) {
+	rows, err := database.DB.Query(`
+		SELECT bi.HospitalID, bi.BedType,
+		       DATE(COALESCE(mi.MovedAt, ba.AdmissionDate)), ba.DischargeDate,
+		       COALESCE(ad.AdmittedAt, ba.AdmissionDate),
+		       COALESCE(mi.MovementType, 'admit') = 'admit',
+		       ba.DischargeDate IS NOT NULL AND COALESCE(mo.MovementType, 'discharge') = 'discharge'
+		FROM BedAssignments ba
+		JOIN BedInventory bi ON ba.BedID = bi.BedID
+		LEFT JOIN Admissions ad ON ba.AdmissionID = ad.AdmissionID
+		LEFT JOIN BedMovements mi ON mi.MovementID =
+			(SELECT MIN(MovementID) FROM BedMovements WHERE ToAssignmentID = ba.AssignmentID)
+		LEFT JOIN BedMovements mo ON mo.MovementID =
+			(SELECT MAX(MovementID) FROM BedMovements WHERE FromAssignmentID = ba.AssignmentID)
+		WHERE ba.AdmissionDate <= ?
+		  AND (ba.DischargeDate IS NULL OR ba.DischargeDate >= ?)
+		  AND (? = 0 OR bi.HospitalID = ?)
+		  AND (? = '' OR bi.BedType = ?)
+	`, to.Format("2006-01-02"), from.Format("2006-01-02"), hospitalID, hospitalID, bedType, bedType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stays []analyticsStay
+	for rows.Next() {
+		var s analyticsStay
+		var end sql.NullTime
+		if err := rows.Scan(&s.HospitalID, &s.BedType, &s.Start, &end, &s.AdmittedAt, &s.Admission, &s.Discharge); err != nil {
+			return nil, err
+		}
+		if end.Valid {
+			s.End = end.Time
+		}
+		stays = append(stays, s)
+	}
+	return stays, rows.Err()
+}
+
+// GetBedAnalytics returns occupancy time series for a date range: midnight
+// census, bed days, occupancy, admissions, discharges, length of stay and bed
+// turnover. Parameters: from and to (YYYY-MM-DD, default the last 30 days),
+// interval (day, week or month), hospitalId, bedType, and groupBy=hospital to
+// combine bed types into one series per hospital.
+func GetBedAnalytics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	to := today.AddDate(0, 0, -1)
+	from := to.AddDate(0, 0, -29)
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := query.Get(name); value != "" {
+			date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				sendJSONError(w, "Invalid "+name+" date, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*dst = date
+		}
+	}
+	if to.Before(from) {
+		sendJSONError(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	if daysBetween(from, to) >= maxAnalyticsDays {
+		sendJSONError(w, "Date range is limited to "+strconv.Itoa(maxAnalyticsDays)+" days", http.StatusBadRequest)
+		return
+	}
+
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "day" && interval != "week" && interval != "month" {
+		sendJSONError(w, "interval must be day, week or month", http.StatusBadRequest)
+		return
+	}
+	groupBy := query.Get("groupBy")
+	if groupBy != "" && groupBy != "bedType" && groupBy != "hospital" {
+		sendJSONError(w, "groupBy must be bedType or hospital", http.StatusBadRequest)
+		return
+	}
+	hospitalID := 0
+	if value := query.Get("hospitalId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			sendJSONError(w, "Invalid hospitalId", http.StatusBadRequest)
+			return
+		}
+		hospitalID = id
+	}
+	bedType := query.Get("bedType")
+
+	// One series per hospital and bed type, sized by the current inventory
+	rows, err := database.DB.Query(`
+		SELECT bi.HospitalID, h.Address, bi.BedType, COUNT(*)
+		FROM BedInventory bi
+		JOIN Hospital h ON bi.HospitalID = h.HospitalID
+		WHERE (? = 0 OR bi.HospitalID = ?) AND (? = '' OR bi.BedType = ?)
+		GROUP BY bi.HospitalID, h.Address, bi.BedType
+		ORDER BY bi.HospitalID, bi.BedType
+	`, hospitalID, hospitalID, bedType, bedType)
+	if err != nil {
+		log.Printf("Error querying bed inventory for analytics: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	seriesKey := func(hospitalID int, bedType string) string {
+		if groupBy == "hospital" {
+			return strconv.Itoa(hospitalID)
+		}
+		return strconv.Itoa(hospitalID) + "/" + bedType
+	}
+	series := []*analyticsSeries{}
+	byKey := make(map[string]*analyticsSeries)
+	for rows.Next() {
+		var hID, beds int
+		var name, bt string
+		if err := rows.Scan(&hID, &name, &bt, &beds); err != nil {
+			log.Printf("Error scanning bed inventory row: %v", err)
+			continue
+		}
+		key := seriesKey(hID, bt)
+		s := byKey[key]
+		if s == nil {
+			s = &analyticsSeries{
+				HospitalID:   hID,
+				HospitalName: name,
+				census:       make(map[string]int),
+				admissions:   make(map[string]int),
+				discharges:   make(map[string]int),
+				stays:        make(map[string][]int),
+			}
+			if groupBy != "hospital" {
+				s.BedType = bt
+			}
+			byKey[key] = s
+			series = append(series, s)
+		}
+		s.Beds += beds
+	}
+	rows.Close()
+
+	stays, err := loadAnalyticsStays(from, to, hospitalID, bedType)
+	if err != nil {
+		log.Printf("Error querying bed stays for analytics: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, stay := range stays {
+		s := byKey[seriesKey(stay.HospitalID, stay.BedType)]
+		if s == nil {
+			continue
+		}
+		if stay.Admission && !stay.Start.Before(from) && !stay.Start.After(to) {
+			s.admissions[stay.Start.Format("2006-01-02")]++
+		}
+		if stay.Discharge && !stay.End.Before(from) && !stay.End.After(to) {
+			day := stay.End.Format("2006-01-02")
+			s.discharges[day]++
+			s.stays[day] = append(s.stays[day], daysBetween(stay.AdmittedAt, stay.End))
+		}
+
+		// In the bed at midnight from its first night until the night before
+		// it leaves
+		first, last := stay.Start, to
+		if !stay.End.IsZero() && stay.End.AddDate(0, 0, -1).Before(last) {
+			last = stay.End.AddDate(0, 0, -1)
+		}
+		if first.Before(from) {
+			first = from
+		}
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			s.census[day.Format("2006-01-02")]++
+		}
+	}
+
+	days := daysBetween(from, to) + 1
+	for _, s := range series {
+		var lengths []int
+		var point *analyticsPoint
+		var pointStays []int
+		flush := func() {
+			if point == nil {
+				return
+			}
+			point.AvgCensus = round2(float64(point.BedDays) / float64(point.Days))
+			point.OccupancyRate = round2(calculatePercentage(point.BedDays, s.Beds*point.Days))
+			point.AvgLengthOfStay = round2(averageOf(pointStays))
+			s.Points = append(s.Points, *point)
+		}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			bucket := analyticsBucket(day, interval).Format("2006-01-02")
+			if point == nil || point.Date != bucket {
+				flush()
+				point = &analyticsPoint{Date: bucket}
+				pointStays = nil
+			}
+			key := day.Format("2006-01-02")
+			census := s.census[key]
+			point.Days++
+			point.BedDays += census
+			if census > point.PeakCensus {
+				point.PeakCensus = census
+			}
+			point.Admissions += s.admissions[key]
+			point.Discharges += s.discharges[key]
+			pointStays = append(pointStays, s.stays[key]...)
+			lengths = append(lengths, s.stays[key]...)
+		}
+		flush()
+
+		for _, p := range s.Points {
+			s.BedDays += p.BedDays
+			s.Admissions += p.Admissions
+			s.Discharges += p.Discharges
+		}
+		s.AvgCensus = round2(float64(s.BedDays) / float64(days))
+		s.OccupancyRate = round2(calculatePercentage(s.BedDays, s.Beds*days))
+		s.AvgLengthOfStay = round2(averageOf(lengths))
+		s.MedianLengthOfStay = medianOf(lengths)
+		if s.Beds > 0 {
+			s.TurnoverRate = round2(float64(s.Discharges) / float64(s.Beds))
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"interval": interval,
+		"series":   series,
+	})
+}
@@ -0,0 +1,527 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Real-time bed board. A single poller tails BedMovements and BedStatusLog,
+// which every occupancy and status change writes in its transaction, so it
+// only ever sees committed changes however they were made. Each new row
+// becomes a bed event; after each batch the occupancy counters of the
+// affected hospitals are recomputed and pushed for any hospital or ward whose
+// counts changed. Events are kept in a bounded ring buffer and fanned out to
+// Server-Sent Events subscribers, so the database load does not grow with the
+// number of open bed boards. A client reconnecting with Last-Event-ID is sent
+// the events it missed, or a reset event if they have left the buffer.
+
+// bedEventPollInterval is how often the poller looks for new bed changes
+const bedEventPollInterval = time.Second
+
+// bedEventBufferSize is how many recent events are kept for replay
+const bedEventBufferSize = 1024
+
+// bedEventHeartbeat is how often an idle stream is sent a comment so proxies
+// keep the connection open
+const bedEventHeartbeat = 15 * time.Second
+
+// bedEventLookback is how far behind the newest row the poller looks again.
+// Auto-increment IDs are assigned before commit, so a row can become visible
+// after rows with higher IDs.
+const bedEventLookback = 100
+
+// bedEvent is one change pushed to bed boards
+type bedEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"` // assigned, transferred, swapped, discharged, cleaned, status, occupancy
+	HospitalID int       `json:"hospitalId"`
+	WardID     int       `json:"wardId,omitempty"`
+	BedID      int       `json:"bedId,omitempty"`
+	FromBedID  int       `json:"fromBedId,omitempty"`
+	ToBedID    int       `json:"toBedId,omitempty"`
+	PatientID  int       `json:"patientId,omitempty"`
+	Status     string    `json:"status,omitempty"` // the bed's status after the change
+	Location   string    `json:"location,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Occupancy  *wardLoad `json:"occupancy,omitempty"`
+	At         string    `json:"at"`
+
+	seq     uint64
+	wardIDs []int // every ward the event touches, for filtering
+}
+
+// wardLoad is the occupancy counters of a hospital, or of one of its wards
+type wardLoad struct {
+	Total         int     `json:"total"`
+	Occupied      int     `json:"occupied"`
+	Available     int     `json:"available"`
+	Reserved      int     `json:"reserved"`
+	Cleaning      int     `json:"cleaning"`
+	OutOfService  int     `json:"outOfService"` // maintenance or blocked
+	OccupancyRate float64 `json:"occupancyRate"`
+}
+
+// matches reports whether a subscriber filtered on hospitalID and wardID
+// (0 for any) should receive the event
+func (e bedEvent) matches(hospitalID, wardID int) bool {
+	if hospitalID != 0 && e.HospitalID != hospitalID {
+		return false
+	}
+	if wardID == 0 {
+		return true
+	}
+	for _, id := range e.wardIDs {
+		if id == wardID {
+			return true
+		}
+	}
+	return false
+}
+
+// bedEventHub holds the replay buffer and the open streams
+type bedEventHub struct {
+	mu          sync.Mutex
+	epoch       string // distinguishes event IDs from an earlier run of the server
+	seq         uint64
+	buffer      []bedEvent // ring buffer, oldest at start
+	start       int
+	subscribers map[chan bedEvent]struct{}
+}
+
+var bedEvents = &bedEventHub{
+	epoch:       strconv.FormatInt(time.Now().Unix(), 36),
+	subscribers: make(map[chan bedEvent]struct{}),
+}
+
+// publish numbers an event, adds it to the buffer and sends it to every
+// subscriber. A subscriber whose channel is full is dropped; its client
+// reconnects and catches up from the buffer.
+func (h *bedEventHub) publish(e bedEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	e.seq = h.seq
+	e.ID = fmt.Sprintf("%s-%d", h.epoch, h.seq)
+	if len(h.buffer) < bedEventBufferSize {
+		h.buffer = append(h.buffer, e)
+	} else {
+		h.buffer[h.start] = e
+		h.start = (h.start + 1) % bedEventBufferSize
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers a stream and returns the buffered events after
+// lastEventID. ok is false if those events are no longer all buffered.
+func (h *bedEventHub) subscribe(lastEventID string) (ch chan bedEvent, missed []bedEvent, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch = make(chan bedEvent, 64)
+	h.subscribers[ch] = struct{}{}
+	if lastEventID == "" {
+		return ch, nil, true
+	}
+
+	epoch, seqText, found := strings.Cut(lastEventID, "-")
+	last, err := strconv.ParseUint(seqText, 10, 64)
+	if !found || err != nil || epoch != h.epoch || last > h.seq {
+		return ch, nil, false
+	}
+	for i := 0; i < len(h.buffer); i++ {
+		e := h.buffer[(h.start+i)%len(h.buffer)]
+		if i == 0 && e.seq > last+1 {
+			return ch, nil, false
+		}
+		if e.seq > last {
+			missed = append(missed, e)
+		}
+	}
+	return ch, missed, true
+}
+
+// unsubscribe removes a stream unless publish already dropped it
+func (h *bedEventHub) unsubscribe(ch chan bedEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// tailCursor tracks which rows of an append-only table have been seen
+type tailCursor struct {
+	max  int64
+	seen map[int64]bool
+}
+
+// from is the ID to query after, leaving room for late commits
+func (c *tailCursor) from() int64 {
+	if c.max < bedEventLookback {
+		return 0
+	}
+	return c.max - bedEventLookback
+}
+
+// add reports whether id is new, and forgets IDs that fell out of the lookback
+func (c *tailCursor) add(id int64) bool {
+	if c.seen[id] {
+		return false
+	}
+	c.seen[id] = true
+	if id > c.max {
+		c.max = id
+		for old := range c.seen {
+			if old <= c.from() {
+				delete(c.seen, old)
+			}
+		}
+	}
+	return true
+}
+
+// bedEventPoller turns new ledger rows into bed events
+type bedEventPoller struct {
+	movements tailCursor
+	statuses  tailCursor
+	loads     map[[2]int]wardLoad // last counters per hospital and ward, ward 0 for the hospital
+}
+
+// newBedEventPoller starts tailing from the newest existing rows
+func newBedEventPoller() (*bedEventPoller, error) {
+	p := &bedEventPoller{
+		movements: tailCursor{seen: make(map[int64]bool)},
+		statuses:  tailCursor{seen: make(map[int64]bool)},
+		loads:     make(map[[2]int]wardLoad),
+	}
+	err := database.DB.QueryRow(`
+		SELECT (SELECT COALESCE(MAX(MovementID), 0) FROM BedMovements),
+		       (SELECT COALESCE(MAX(LogID), 0) FROM BedStatusLog)
+	`).Scan(&p.movements.max, &p.statuses.max)
+	if err != nil {
+		return nil, err
+	}
+	// Rows within the lookback already existed and must not be replayed
+	for id := p.movements.from() + 1; id <= p.movements.max; id++ {
+		p.movements.seen[id] = true
+	}
+	for id := p.statuses.from() + 1; id <= p.statuses.max; id++ {
+		p.statuses.seen[id] = true
+	}
+	return p, nil
+}
+
+// movementEventTypes names the event for each BedMovements type
+var movementEventTypes = map[string]string{
+	"admit":     "assigned",
+	"transfer":  "transferred",
+	"swap":      "swapped",
+	"discharge": "discharged",
+}
+
+// poll publishes events for rows committed since the last poll, followed by
+// occupancy updates for the hospitals they touched
+func (p *bedEventPoller) poll() error {
+	var events []bedEvent
+
+	rows, err := database.DB.Query(`
+		SELECT m.MovementID, m.MovementType, m.PatientID, COALESCE(m.FromBedID, 0), COALESCE(m.ToBedID, 0),
+		       COALESCE(fb.HospitalID, tb.HospitalID), COALESCE(fr.WardID, 0), COALESCE(tr.WardID, 0),
+		       COALESCE(tb.Status, fb.Status), COALESCE(m.Reason, ''), m.MovedAt,
+		       COALESCE(tw.Name, fw.Name, ''), COALESCE(tr.RoomNumber, fr.RoomNumber, ''), COALESCE(tb.Label, fb.Label, '')
+		FROM BedMovements m
+		LEFT JOIN BedInventory fb ON m.FromBedID = fb.BedID
+		LEFT JOIN Rooms fr ON fb.RoomID = fr.RoomID
+		LEFT JOIN Wards fw ON fr.WardID = fw.WardID
+		LEFT JOIN BedInventory tb ON m.ToBedID = tb.BedID
+		LEFT JOIN Rooms tr ON tb.RoomID = tr.RoomID
+		LEFT JOIN Wards tw ON tr.WardID = tw.WardID
+		WHERE m.MovementID > ?
+		ORDER BY m.MovementID
+	`, p.movements.from())
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var movementType, wardName, roomNumber, label string
+		var fromWard, toWard int
+		var movedAt time.Time
+		var e bedEvent
+		if err := rows.Scan(&id, &movementType, &e.PatientID, &e.FromBedID, &e.ToBedID,
+			&e.HospitalID, &fromWard, &toWard, &e.Status, &e.Reason, &movedAt,
+			&wardName, &roomNumber, &label); err != nil {
+			rows.Close()
+			return err
+		}
+		if !p.movements.add(id) {
+			continue
+		}
+		e.Type = movementEventTypes[movementType]
+		e.BedID = e.ToBedID
+		e.WardID = toWard
+		if e.BedID == 0 {
+			e.BedID, e.WardID = e.FromBedID, fromWard
+		}
+		e.Location = bedLocation(e.BedID, wardName, roomNumber, label)
+		e.At = movedAt.Format(time.RFC3339)
+		e.wardIDs = []int{fromWard, toWard}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Changes to or from occupied come with a movement; the rest, such as
+	// cleaning done or maintenance, are status events
+	rows, err = database.DB.Query(`
+		SELECT l.LogID, l.BedID, bi.HospitalID, COALESCE(rm.WardID, 0), COALESCE(l.FromStatus, ''), l.ToStatus,
+		       COALESCE(l.Reason, ''), l.ChangedAt, COALESCE(wd.Name, ''), COALESCE(rm.RoomNumber, ''), COALESCE(bi.Label, '')
+		FROM BedStatusLog l
+		JOIN BedInventory bi ON l.BedID = bi.BedID
+		LEFT JOIN Rooms rm ON bi.RoomID = rm.RoomID
+		LEFT JOIN Wards wd ON rm.WardID = wd.WardID
+		WHERE l.LogID > ?
+		ORDER BY l.LogID
+	`, p.statuses.from())
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var fromStatus, wardName, roomNumber, label string
+		var changedAt time.Time
+		var e bedEvent
+		if err := rows.Scan(&id, &e.BedID, &e.HospitalID, &e.WardID, &fromStatus, &e.Status,
+			&e.Reason, &changedAt, &wardName, &roomNumber, &label); err != nil {
+			rows.Close()
+			return err
+		}
+		if !p.statuses.add(id) || fromStatus == "occupied" || e.Status == "occupied" {
+			continue
+		}
+		e.Type = "status"
+		if fromStatus == "cleaning" && e.Status == "available" {
+			e.Type = "cleaned"
+		}
+		e.Location = bedLocation(e.BedID, wardName, roomNumber, label)
+		e.At = changedAt.Format(time.RFC3339)
+		e.wardIDs = []int{e.WardID}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	hospitals := make(map[int]bool)
+	for _, e := range events {
+		bedEvents.publish(e)
+		hospitals[e.HospitalID] = true
+	}
+	for hospitalID := range hospitals {
+		if err := p.publishOccupancy(hospitalID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishOccupancy recomputes a hospital's counters and publishes those that
+// changed, per ward and for the hospital as a whole
+func (p *bedEventPoller) publishOccupancy(hospitalID int) error {
+	rows, err := database.DB.Query(`
+		SELECT COALESCE(rm.WardID, 0), COUNT(*),
+		       SUM(bi.Status = 'occupied'), SUM(bi.Status = 'available'), SUM(bi.Status = 'reserved'),
+		       SUM(bi.Status = 'cleaning'), SUM(bi.Status IN ('maintenance', 'blocked'))
+		FROM BedInventory bi
+		LEFT JOIN Rooms rm ON bi.RoomID = rm.RoomID
+		WHERE bi.HospitalID = ?
+		GROUP BY COALESCE(rm.WardID, 0)
+	`, hospitalID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var hospital wardLoad
+	var changed []bedEvent
+	for rows.Next() {
+		var wardID int
+		var l wardLoad
+		if err := rows.Scan(&wardID, &l.Total, &l.Occupied, &l.Available, &l.Reserved, &l.Cleaning, &l.OutOfService); err != nil {
+			return err
+		}
+		hospital.Total += l.Total
+		hospital.Occupied += l.Occupied
+		hospital.Available += l.Available
+		hospital.Reserved += l.Reserved
+		hospital.Cleaning += l.Cleaning
+		hospital.OutOfService += l.OutOfService
+		if wardID != 0 {
+			if e, ok := p.loadChanged(hospitalID, wardID, l); ok {
+				changed = append(changed, e)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if e, ok := p.loadChanged(hospitalID, 0, hospital); ok {
+		changed = append(changed, e)
+	}
+	for _, e := range changed {
+		bedEvents.publish(e)
+	}
+	return nil
+}
+
+// loadChanged returns an occupancy event if a ward's counters differ from the
+// last ones published
+func (p *bedEventPoller) loadChanged(hospitalID, wardID int, l wardLoad) (bedEvent, bool) {
+	l.OccupancyRate = calculatePercentage(l.Occupied, l.Total)
+	key := [2]int{hospitalID, wardID}
+	if last, ok := p.loads[key]; ok && last == l {
+		return bedEvent{}, false
+	}
+	p.loads[key] = l
+	e := bedEvent{
+		Type:       "occupancy",
+		HospitalID: hospitalID,
+		WardID:     wardID,
+		Occupancy:  &l,
+		At:         time.Now().Format(time.RFC3339),
+	}
+	if wardID != 0 {
+		e.wardIDs = []int{wardID}
+	}
+	return e, true
+}
+
+// StartBedEvents runs the background poller that feeds the bed board stream
+func StartBedEvents() {
+	go func() {
+		var poller *bedEventPoller
+		ticker := time.NewTicker(bedEventPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			var err error
+			if poller == nil {
+				poller, err = newBedEventPoller()
+			} else {
+				err = poller.poll()
+			}
+			if err != nil {
+				log.Printf("Error polling bed events: %v", err)
+			}
+		}
+	}()
+	log.Println("Bed event stream started")
+}
+
+// writeBedEvent sends one event in Server-Sent Events format
+func writeBedEvent(w http.ResponseWriter, e bedEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// StreamBedEvents streams bed changes and occupancy updates as Server-Sent
+// Events, optionally only for one hospitalId or wardId. A reconnecting client
+// gets the events after its Last-Event-ID header (or lastEventId parameter)
+// replayed first; if they are no longer buffered it gets a reset event and
+// should reload the bed board before applying further events.
+func StreamBedEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendJSONError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var hospitalID, wardID int
+	for name, dst := range map[string]*int{"hospitalId": &hospitalID, "wardId": &wardID} {
+		if value := r.URL.Query().Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				sendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dst = id
+		}
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	ch, missed, complete := bedEvents.subscribe(lastEventID)
+	defer bedEvents.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	if !complete {
+		fmt.Fprintf(w, "event: reset\ndata: {\"reason\":\"missed events are no longer available\"}\n\n")
+	}
+	for _, e := range missed {
+		if e.matches(hospitalID, wardID) {
+			if err := writeBedEvent(w, e); err != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(bedEventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, open := <-ch:
+			if !open {
+				// Dropped for falling behind; the client reconnects and replays
+				return
+			}
+			if !e.matches(hospitalID, wardID) {
+				continue
+			}
+			if err := writeBedEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Occupancy forecasting. The projected midnight census of each hospital and
+// bed type is the sum of three parts:
+//   - patients in a bed now, who leave on their planned discharge date or,
+//     without one, stay on with a daily probability taken from the type's
+//     historical mean bed stay
+//   - active reservations, in their bed from start to end
+//   - unscheduled demand: the weekday's historical admission rate less the
+//     reservations starting that day, plus the pending bed request queue
+//     today, each staying on like the patients already admitted
+//
+// The band is a normal approximation from the variance of each part: the
+// weekday admission counts and the chance of each stay ending.
+
+const (
+	// forecastMaxDays is the longest forecast horizon
+	forecastMaxDays = 14
+	// forecastDefaultStayDays is the mean bed stay used for types with no
+	// discharge history
+	forecastDefaultStayDays = 4.0
+	// forecastZ is the normal quantile of the confidence band (95%)
+	forecastZ = 1.96
+)
+
+// occupancyWarningPercent is the projected occupancy that raises a warning.
+// It can be overridden with the OCCUPANCY_WARNING_PERCENT environment
+// variable, or per request with threshold.
+func occupancyWarningPercent() float64 {
+	if p, err := strconv.ParseFloat(os.Getenv("OCCUPANCY_WARNING_PERCENT"), 64); err == nil && p > 0 {
+		return p
+	}
+	return 85
+}
+
+// forecastPoint is the projection for one day
+type forecastPoint struct {
+	Date                string  `json:"date"`
+	Weekday             string  `json:"weekday"`
+	ExpectedAdmissions  float64 `json:"expectedAdmissions"`
+	ScheduledAdmissions int     `json:"scheduledAdmissions"` // reservations starting that day
+	PlannedDischarges   int     `json:"plannedDischarges"`
+	ProjectedCensus     float64 `json:"projectedCensus"`
+	Lower               float64 `json:"lower"`
+	Upper               float64 `json:"upper"`
+	OccupancyRate       float64 `json:"occupancyRate"`
+	UpperOccupancyRate  float64 `json:"upperOccupancyRate"`
+	ExceedsThreshold    bool    `json:"exceedsThreshold"`
+	MayExceedThreshold  bool    `json:"mayExceedThreshold"`
+
+	projected, variance  float64
+	reserved, plannedOut int
+}
+
+// forecastSeries is the forecast for one hospital and bed type
+type forecastSeries struct {
+	HospitalID     int                `json:"hospitalId"`
+	HospitalName   string             `json:"hospitalName"`
+	BedType        string             `json:"bedType"`
+	Beds           int                `json:"beds"` // in service: not under maintenance or blocked
+	OccupiedNow    int                `json:"occupiedNow"`
+	PendingQueue   int                `json:"pendingRequests"`
+	MeanStayDays   float64            `json:"meanStayDays"`
+	AdmissionRates map[string]float64 `json:"admissionRateByWeekday"`
+	Points         []forecastPoint    `json:"points"`
+
+	admissions [7][]int // daily admission counts over the lookback, by weekday
+	stayDays   []int
+}
+
+// stayOn is the probability that a patient in one of the series' beds is
+// still there at the end of the next day, for geometric stays with the
+// series' mean
+func (s *forecastSeries) stayOn() float64 {
+	return s.MeanStayDays / (1 + s.MeanStayDays)
+}
+
+// forecastWarning flags a day whose projected occupancy crosses the threshold
+type forecastWarning struct {
+	HospitalID    int     `json:"hospitalId"`
+	HospitalName  string  `json:"hospitalName"`
+	BedType       string  `json:"bedType"`
+	Date          string  `json:"date"`
+	Level         string  `json:"level"` // likely: the projection crosses; possible: only the upper band does
+	OccupancyRate float64 `json:"occupancyRate"`
+	Threshold     float64 `json:"threshold"`
+	Message       string  `json:"message"`
+}
+
+// meanAndVariance returns the sample mean and variance of counts
+func meanAndVariance(counts []int) (float64, float64) {
+	if len(counts) == 0 {
+		return 0, 0
+	}
+	mean := averageOf(counts)
+	if len(counts) < 2 {
+		return mean, mean
+	}
+	var ss float64
+	for _, c := range counts {
+		ss += (float64(c) - mean) * (float64(c) - mean)
+	}
+	return mean, ss / float64(len(counts)-1)
+}
+
+// GetBedForecast projects bed demand per hospital and bed type for the next
+// days (1-14, default 7) from today. Admission rates come from the last
+// lookbackWeeks weeks (default 8). Optional hospitalId and bedType filter the
+// forecast; threshold overrides the occupancy percentage that raises warnings.
+func GetBedForecast(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	days, lookbackWeeks, hospitalID := 7, 8, 0
+	for _, p := range []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"days", &days, 1, forecastMaxDays},
+		{"lookbackWeeks", &lookbackWeeks, 1, 52},
+		{"hospitalId", &hospitalID, 1, math.MaxInt32},
+	} {
+		if value := query.Get(p.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < p.min || n > p.max {
+				sendJSONError(w, fmt.Sprintf("%s must be a number from %d to %d", p.name, p.min, p.max), http.StatusBadRequest)
+				return
+			}
+			*p.value = n
+		}
+	}
+	threshold := occupancyWarningPercent()
+	if value := query.Get("threshold"); value != "" {
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil || t <= 0 || t > 100 {
+			sendJSONError(w, "threshold must be a percentage from 0 to 100", http.StatusBadRequest)
+			return
+		}
+		threshold = t
+	}
+	bedType := query.Get("bedType")
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	horizon := today.AddDate(0, 0, days)
+
+	series, byKey, err := loadForecastSeries(hospitalID, bedType)
+	if err != nil {
+		log.Printf("Error querying beds for forecast: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for _, s := range series {
+		s.Points = make([]forecastPoint, days)
+		for d := range s.Points {
+			date := today.AddDate(0, 0, d)
+			s.Points[d].Date = date.Format("2006-01-02")
+			s.Points[d].Weekday = date.Weekday().String()
+		}
+	}
+
+	// Weekday admission rates and mean bed stay from the lookback period
+	historyFrom := today.AddDate(0, 0, -7*lookbackWeeks)
+	historyTo := today.AddDate(0, 0, -1)
+	stays, err := loadAnalyticsStays(historyFrom, historyTo, hospitalID, bedType)
+	if err != nil {
+		log.Printf("Error querying bed stays for forecast: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	admitted := make(map[string]map[string]int)
+	for _, stay := range stays {
+		key := fmt.Sprintf("%d/%s", stay.HospitalID, stay.BedType)
+		s := byKey[key]
+		if s == nil {
+			continue
+		}
+		if stay.Admission && !stay.Start.Before(historyFrom) {
+			if admitted[key] == nil {
+				admitted[key] = make(map[string]int)
+			}
+			admitted[key][stay.Start.Format("2006-01-02")]++
+		}
+		if !stay.End.IsZero() {
+			s.stayDays = append(s.stayDays, daysBetween(stay.Start, stay.End))
+		}
+	}
+	for key, s := range byKey {
+		for day := historyFrom; !day.After(historyTo); day = day.AddDate(0, 0, 1) {
+			wd := day.Weekday()
+			s.admissions[wd] = append(s.admissions[wd], admitted[key][day.Format("2006-01-02")])
+		}
+		s.MeanStayDays = forecastDefaultStayDays
+		if len(s.stayDays) > 0 {
+			s.MeanStayDays = round2(math.Max(averageOf(s.stayDays), 0.5))
+		}
+	}
+
+	if err := addCurrentOccupancy(byKey, today, horizon); err != nil {
+		log.Printf("Error querying occupancy for forecast: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	warnings := []forecastWarning{}
+	for _, s := range series {
+		q := s.stayOn()
+		s.AdmissionRates = make(map[string]float64)
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			mean, _ := meanAndVariance(s.admissions[wd])
+			s.AdmissionRates[wd.String()] = round2(mean)
+		}
+
+		for j := range s.Points {
+			date := today.AddDate(0, 0, j)
+			mean, variance := meanAndVariance(s.admissions[date.Weekday()])
+			variance = math.Max(variance, mean) // at least Poisson
+			unscheduled := math.Max(mean-float64(s.Points[j].reserved), 0)
+			if j == 0 {
+				// The queue is waiting for beds now
+				unscheduled += float64(s.PendingQueue)
+			}
+			s.Points[j].ExpectedAdmissions = round2(unscheduled + float64(s.Points[j].reserved))
+
+			for d := j; d < len(s.Points); d++ {
+				p := math.Pow(q, float64(d-j+1))
+				s.Points[d].projected += unscheduled * p
+				s.Points[d].variance += unscheduled*p*(1-p) + variance*p*p
+			}
+		}
+
+		for d := range s.Points {
+			pt := &s.Points[d]
+			sd := math.Sqrt(pt.variance)
+			pt.ScheduledAdmissions = pt.reserved
+			pt.PlannedDischarges = pt.plannedOut
+			pt.ProjectedCensus = round2(pt.projected)
+			pt.Lower = round2(math.Max(pt.projected-forecastZ*sd, 0))
+			pt.Upper = round2(pt.projected + forecastZ*sd)
+			pt.OccupancyRate = round2(percentOf(pt.projected, s.Beds))
+			pt.UpperOccupancyRate = round2(percentOf(pt.projected+forecastZ*sd, s.Beds))
+			pt.ExceedsThreshold = pt.OccupancyRate >= threshold
+			pt.MayExceedThreshold = pt.UpperOccupancyRate >= threshold
+
+			if pt.MayExceedThreshold {
+				level, rate := "possible", pt.UpperOccupancyRate
+				if pt.ExceedsThreshold {
+					level, rate = "likely", pt.OccupancyRate
+				}
+				warnings = append(warnings, forecastWarning{
+					HospitalID:    s.HospitalID,
+					HospitalName:  s.HospitalName,
+					BedType:       s.BedType,
+					Date:          pt.Date,
+					Level:         level,
+					OccupancyRate: rate,
+					Threshold:     threshold,
+					Message: fmt.Sprintf("%s beds at %s are %s to reach %.0f%% occupancy on %s (threshold %.0f%%)",
+						s.BedType, s.HospitalName, level, rate, pt.Date, threshold),
+				})
+			}
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"generatedAt":   now.Format(time.RFC3339),
+		"days":          days,
+		"lookbackWeeks": lookbackWeeks,
+		"threshold":     threshold,
+		"series":        series,
+		"warnings":      warnings,
+	})
+}
+
+// percentOf returns part as a percentage of total
+func percentOf(part float64, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part / float64(total) * 100
+}
+
+// loadForecastSeries returns an empty series for each hospital and bed type
+// with beds, sized by the beds in service
+func loadForecastSeries(hospitalID int, bedType string) ([]*forecastSeries, map[string]*forecastSeries, error) {
+	rows, err := database.DB.Query(`
+		SELECT bi.HospitalID, h.Address, bi.BedType,
+		       SUM(bi.Status NOT IN ('maintenance', 'blocked')),
+		       (SELECT COUNT(*) FROM BedRequests br
+		        WHERE br.Status = 'pending' AND br.HospitalID = bi.HospitalID AND br.BedType = bi.BedType)
+		FROM BedInventory bi
+		JOIN Hospital h ON bi.HospitalID = h.HospitalID
+		WHERE (? = 0 OR bi.HospitalID = ?) AND (? = '' OR bi.BedType = ?)
+		GROUP BY bi.HospitalID, h.Address, bi.BedType
+		ORDER BY bi.HospitalID, bi.BedType
+	`, hospitalID, hospitalID, bedType, bedType)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	series := []*forecastSeries{}
+	byKey := make(map[string]*forecastSeries)
+	for rows.Next() {
+		s := &forecastSeries{}
+		if err := rows.Scan(&s.HospitalID, &s.HospitalName, &s.BedType, &s.Beds, &s.PendingQueue); err != nil {
+			return nil, nil, err
+		}
+		series = append(series, s)
+		byKey[fmt.Sprintf("%d/%s", s.HospitalID, s.BedType)] = s
+	}
+	return series, byKey, rows.Err()
+}
+
+// addCurrentOccupancy adds the patients in a bed now and the active
+// reservations to each day of the forecast, from today until horizon
+func addCurrentOccupancy(byKey map[string]*forecastSeries, today, horizon time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT bi.HospitalID, bi.BedType, ba.PlannedDischargeDate
+		FROM BedAssignments ba
+		JOIN BedInventory bi ON ba.BedID = bi.BedID
+		WHERE ba.DischargeDate IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hospitalID int
+		var bedType string
+		var planned sql.NullTime
+		if err := rows.Scan(&hospitalID, &bedType, &planned); err != nil {
+			return err
+		}
+		s := byKey[fmt.Sprintf("%d/%s", hospitalID, bedType)]
+		if s == nil {
+			continue
+		}
+		s.OccupiedNow++
+		q := s.stayOn()
+		for d := range s.Points {
+			date := today.AddDate(0, 0, d)
+			if planned.Valid {
+				// Leaves on the planned date, or today if it has passed
+				if date.Before(planned.Time) {
+					s.Points[d].projected++
+				} else if d == 0 || date.Equal(planned.Time) {
+					s.Points[d].plannedOut++
+				}
+				continue
+			}
+			p := math.Pow(q, float64(d+1))
+			s.Points[d].projected += p
+			s.Points[d].variance += p * (1 - p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT bi.HospitalID, bi.BedType, r.StartDate, r.EndDate
+		FROM BedReservations r
+		JOIN BedInventory bi ON r.BedID = bi.BedID
+		WHERE r.Status = 'active' AND r.StartDate < ? AND r.EndDate > ?
+	`, horizon.Format("2006-01-02"), today.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hospitalID int
+		var bedType string
+		var start, end time.Time
+		if err := rows.Scan(&hospitalID, &bedType, &start, &end); err != nil {
+			return err
+		}
+		s := byKey[fmt.Sprintf("%d/%s", hospitalID, bedType)]
+		if s == nil {
+			continue
+		}
+		if start.Before(today) {
+			start = today // held but not yet converted: expected today
+		}
+		for d := range s.Points {
+			date := today.AddDate(0, 0, d)
+			if date.Equal(start) {
+				s.Points[d].reserved++
+			}
+			if !date.Before(start) && date.Before(end) {
+				s.Points[d].projected++
+			}
+		}
+	}
+	return rows.Err()
+}
@@ -8,15 +8,25 @@ import (
 	"hospital-management/backend/internal/models"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"time"
 )
 
-// GetBedTypes returns all bed types with their descriptions
+// bedsCountCheckInterval is how often StartBedsCountCheck looks for drift
+const bedsCountCheckInterval = time.Hour
+
+// GetBedTypes returns the bed type catalogue with each type's attributes.
+// Retired types are left out unless includeRetired=true.
 func GetBedTypes(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	rows, err := database.DB.Query("SELECT BedType, Description FROM BedTypes")
+	query := "SELECT " + bedTypeColumns + " FROM BedTypes bt"
+	if r.URL.Query().Get("includeRetired") != "true" {
+		query += " WHERE bt.RetiredAt IS NULL"
+	}
+	rows, err := database.DB.Query(query + " ORDER BY bt.AcuityLevel, bt.BedType")
 	if err != nil {
 		log.Printf("Error querying bed types: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -26,8 +36,7 @@ func GetBedTypes(w http.ResponseWriter, r *http.Request) {
 
 	var bedTypes []models.BedType
 	for rows.Next() {
-		var bedType models.BedType
-		err := rows.Scan(&bedType.Type, &bedType.Description)
+		bedType, err := scanBedType(rows)
 		if err != nil {
 			log.Printf("Error scanning bed type row: %v", err)
 			continue
@@ -38,30 +47,46 @@ func GetBedTypes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(bedTypes)
 }
 
-// GetBedInventory returns all beds in the inventory
+// GetBedInventory returns all beds in the inventory. Optional hospitalId,
+// wardId, floor and roomId parameters filter the list; group=ward groups it by ward.
 func GetBedInventory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	rows, err := database.DB.Query(`
+	query := `
 		SELECT 
 			bi.BedID, 
 			bi.HospitalID, 
 			h.Address as HospitalName,
 			bi.BedType, 
-			CASE 
-				WHEN ba.DischargeDate IS NULL AND ba.BedID IS NOT NULL THEN 'occupied' 
-				ELSE 'available'
-			END AS Status
+			bi.Status,
+			COALESCE(rm.RoomID, 0),
+			` + bedWardColumns + `
 		FROM BedInventory bi
 		JOIN Hospital h ON bi.HospitalID = h.HospitalID
-		LEFT JOIN (
-			SELECT BedID, DischargeDate
-			FROM BedAssignments
-			WHERE DischargeDate IS NULL OR DischargeDate > CURDATE()
-		) ba ON bi.BedID = ba.BedID
-		ORDER BY bi.BedID
-	`)
+		` + bedWardJoins + `
+		WHERE 1=1
+	`
+
+	// Optional filters on the ward hierarchy
+	var args []interface{}
+	filters := []struct{ param, column string }{
+		{"hospitalId", "bi.HospitalID"},
+		{"wardId", "wd.WardID"},
+		{"floor", "wd.Floor"},
+		{"roomId", "rm.RoomID"},
+	}
+	for _, f := range filters {
+		if v := r.URL.Query().Get(f.param); v != "" {
+			query += " AND " + f.column + " = ?"
+			args = append(args, v)
+		}
+	}
+
+	// Beds not yet placed in a ward sort last
+	query += " ORDER BY bi.HospitalID, " + bedWardOrder + ", bi.BedID"
+
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		log.Printf("Error querying bed inventory: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -72,14 +97,21 @@ func GetBedInventory(w http.ResponseWriter, r *http.Request) {
 	var beds []models.Bed
 	for rows.Next() {
 		var bed models.Bed
-		err := rows.Scan(&bed.BedID, &bed.HospitalID, &bed.HospitalName, &bed.BedType, &bed.Status)
+		err := rows.Scan(&bed.BedID, &bed.HospitalID, &bed.HospitalName, &bed.BedType, &bed.Status,
+			&bed.RoomID, &bed.WardID, &bed.WardName, &bed.Floor, &bed.RoomNumber, &bed.Label)
 		if err != nil {
 			log.Printf("Error scanning bed row: %v", err)
 			continue
 		}
+		bed.Location = bedLocation(bed.BedID, bed.WardName, bed.RoomNumber, bed.Label)
 		beds = append(beds, bed)
 	}
 
+	if r.URL.Query().Get("group") == "ward" {
+		json.NewEncoder(w).Encode(groupBedsByWard(beds))
+		return
+	}
+
 	json.NewEncoder(w).Encode(beds)
 }
 
@@ -127,9 +159,9 @@ func CreateBed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if bed type exists
-	var count int
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM BedTypes WHERE BedType = ?", bed.BedType).Scan(&count)
+	// Check if bed type exists and is still in use
+	var count, retired int
+	err = database.DB.QueryRow("SELECT COUNT(*), COUNT(RetiredAt) FROM BedTypes WHERE BedType = ?", bed.BedType).Scan(&count, &retired)
 	if err != nil {
 		log.Printf("Error checking bed type: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -141,6 +173,11 @@ func CreateBed(w http.ResponseWriter, r *http.Request) {
 		bedSendJSONError(w, "Invalid bed type", http.StatusBadRequest)
 		return
 	}
+	if retired > 0 {
+		log.Printf("Retired bed type: %s", bed.BedType)
+		bedSendJSONError(w, "Bed type has been retired", http.StatusBadRequest)
+		return
+	}
 
 	// Start a transaction
 	tx, err := database.DB.Begin()
@@ -149,67 +186,18 @@ func CreateBed(w http.ResponseWriter, r *http.Request) {
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			log.Printf("Transaction rolled back due to error: %v", err)
-		}
-	}()
+	defer tx.Rollback()
 
-	// Insert new bed
+	// Insert new bed; BedsCount is updated alongside it
 	log.Printf("Inserting new bed: HospitalID=%d, BedType=%s", bed.HospitalID, bed.BedType)
-	result, err := tx.Exec(
-		"INSERT INTO BedInventory (HospitalID, BedType) VALUES (?, ?)",
-		bed.HospitalID, bed.BedType)
+	bedID, err := addBed(tx, bed.HospitalID, bed.BedType)
 	if err != nil {
 		log.Printf("Error inserting bed: %v", err)
 		bedSendJSONError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Get the new bed ID
-	bedID, err := result.LastInsertId()
-	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
-		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
 	log.Printf("New bed created with ID: %d", bedID)
 
-	// Check if BedsCount record exists
-	var bedsCountExists int
-	err = tx.QueryRow(
-		"SELECT COUNT(*) FROM BedsCount WHERE HospitalID = ? AND BedType = ?",
-		bed.HospitalID, bed.BedType).Scan(&bedsCountExists)
-	if err != nil {
-		log.Printf("Error checking BedsCount: %v", err)
-		bedSendJSONError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Update the BedsCount table
-	if bedsCountExists > 0 {
-		// Update existing record
-		_, err = tx.Exec(
-			`UPDATE BedsCount 
-			 SET TotalBeds = TotalBeds + 1, 
-				 VacantBeds = VacantBeds + 1
-			 WHERE HospitalID = ? AND BedType = ?`,
-			bed.HospitalID, bed.BedType)
-	} else {
-		// Insert new record
-		_, err = tx.Exec(
-			`INSERT INTO BedsCount (HospitalID, BedType, TotalBeds, OccupiedBeds, VacantBeds) 
-			 VALUES (?, ?, 1, 0, 1)`,
-			bed.HospitalID, bed.BedType)
-	}
-
-	if err != nil {
-		log.Printf("Error updating BedsCount: %v", err)
-		bedSendJSONError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	// Commit the transaction
 	err = tx.Commit()
 	if err != nil {
@@ -268,10 +256,12 @@ func GetBedAssignments(w http.ResponseWriter, r *http.Request) {
 				WHEN ba.DischargeDate IS NULL THEN 'current' 
 				WHEN ba.DischargeDate < CURDATE() THEN 'discharged'
 				ELSE 'scheduled'
-			END AS Status
+			END AS Status,
+			COALESCE(bm.Notes, '') as Notes
 		FROM BedAssignments ba
 		JOIN BedInventory bi ON ba.BedID = bi.BedID
 		JOIN Patients p ON ba.PatientID = p.PatientID
+		LEFT JOIN BedMovements bm ON bm.ToAssignmentID = ba.AssignmentID
 		ORDER BY ba.AdmissionDate DESC
 	`)
 	if err != nil {
@@ -296,6 +286,7 @@ func GetBedAssignments(w http.ResponseWriter, r *http.Request) {
 			&assignment.AdmissionDate,
 			&dischargeDate,
 			&assignment.Status,
+			&assignment.Notes,
 		)
 		if err != nil {
 			log.Printf("Error scanning bed assignment row: %v", err)
@@ -344,111 +335,45 @@ func CreateBedAssignment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if patient already has an active bed assignment
-	var hasExistingAssignment bool
-	err = database.DB.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 
-			FROM BedAssignments 
-			WHERE PatientID = ? 
-			AND (DischargeDate IS NULL OR DischargeDate > CURDATE())
-		)
-	`, assignment.PatientID).Scan(&hasExistingAssignment)
-
-	if err != nil {
-		log.Printf("Error checking existing patient assignments: %v", err)
-		bedSendJSONError(w, "Error checking patient assignment status", http.StatusInternalServerError)
-		return
-	}
-
-	if hasExistingAssignment {
-		log.Printf("Patient %d already has an active bed assignment", assignment.PatientID)
-		bedSendJSONError(w, "This patient already has an active bed assignment. Please discharge the patient from their current bed first.", http.StatusConflict)
-		return
-	}
-
-	// Check if bed exists and is available
-	var bedStatus string
-	var bedType string
-	err = database.DB.QueryRow(`
-		SELECT 
-			bi.BedType,
-			CASE 
-				WHEN EXISTS (
-					SELECT 1 FROM BedAssignments ba 
-					WHERE ba.BedID = bi.BedID AND (ba.DischargeDate IS NULL OR ba.DischargeDate > CURDATE())
-				) THEN 'occupied' 
-				ELSE 'available'
-			END AS Status
-		FROM BedInventory bi
-		WHERE bi.BedID = ?
-	`, assignment.BedID).Scan(&bedType, &bedStatus)
+	tx, err := database.DB.Begin()
 	if err != nil {
-		log.Printf("Error checking bed availability: %v", err)
+		log.Printf("Error starting transaction: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	if bedStatus != "available" {
-		bedSendJSONError(w, "Bed is not available", http.StatusBadRequest)
-		return
-	}
-
-	// Check if patient exists
-	var patientExists int
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM Patients WHERE PatientID = ?", assignment.PatientID).Scan(&patientExists)
+	assignmentID, bed, err := occupyBed(tx, assignment.BedID, assignment.PatientID, assignment.AdmissionDate,
+		bedMove{Notes: assignment.Notes}, admissionDetails{})
 	if err != nil {
-		log.Printf("Error checking patient: %v", err)
-		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	if patientExists == 0 {
-		bedSendJSONError(w, "Patient not found", http.StatusBadRequest)
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error creating bed assignment: %v", err)
+		}
+		if err == errPatientHasBed {
+			message = "This patient already has an active bed assignment. Please discharge the patient from their current bed first."
+		}
+		bedSendJSONError(w, message, status)
 		return
 	}
 
-	// Insert new assignment
-	var result interface{}
-	var dischargeDate interface{} = nil
+	// A discharge date given up front is recorded as a planned discharge
 	if assignment.DischargeDate != "" {
-		dischargeDate = assignment.DischargeDate
-	}
-
-	result, err = database.DB.Exec(
-		"INSERT INTO BedAssignments (BedID, PatientID, AdmissionDate, DischargeDate) VALUES (?, ?, ?, ?)",
-		assignment.BedID, assignment.PatientID, assignment.AdmissionDate, dischargeDate)
-	if err != nil {
-		log.Printf("Error inserting bed assignment: %v", err)
-		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
+		_, err = tx.Exec("UPDATE BedAssignments SET PlannedDischargeDate = ? WHERE AssignmentID = ?",
+			assignment.DischargeDate, assignmentID)
+		if err != nil {
+			log.Printf("Error setting planned discharge date: %v", err)
+			bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	// Get the new assignment ID
-	assignmentID, err := result.(interface{ LastInsertId() (int64, error) }).LastInsertId()
-	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-
-	// Update the BedsCount table
-	var hospitalID int
-	err = database.DB.QueryRow("SELECT HospitalID FROM BedInventory WHERE BedID = ?", assignment.BedID).Scan(&hospitalID)
-	if err != nil {
-		log.Printf("Error getting hospital ID: %v", err)
-		// Don't fail the request, just log the error
-	} else {
-		_, err = database.DB.Exec(
-			`UPDATE BedsCount 
-             SET OccupiedBeds = OccupiedBeds + 1, VacantBeds = VacantBeds - 1
-             WHERE HospitalID = ? AND BedType = ?`,
-			hospitalID, bedType)
-		if err != nil {
-			log.Printf("Error updating BedsCount: %v", err)
-			// Don't fail the request, just log the error
-		}
-	}
+	bedType := bed.BedType
 
 	// Return the new assignment with ID
 	assignment.AssignmentID = int(assignmentID)
@@ -473,27 +398,53 @@ func calculatePercentage(part, total int) float64 {
 	return float64(part) / float64(total) * 100
 }
 
-// GetBedStats returns bed statistics by bed type
+// bedStatsGroups are the bed type attributes GetBedStats can aggregate by
+var bedStatsGroups = map[string]func(models.BedType) interface{}{
+	"acuityLevel":      func(t models.BedType) interface{} { return t.AcuityLevel },
+	"needsVentilator":  func(t models.BedType) interface{} { return t.NeedsVentilator },
+	"needsMonitor":     func(t models.BedType) interface{} { return t.NeedsMonitor },
+	"isolationCapable": func(t models.BedType) interface{} { return t.IsolationCapable },
+	"nurseToBedRatio":  func(t models.BedType) interface{} { return t.NurseToBedRatio },
+}
+
+// GetBedStats returns bed statistics by bed type, with each type's attributes,
+// daily revenue from occupied beds and the nurses needed to staff them.
+// groupBy=acuityLevel, needsVentilator, needsMonitor, isolationCapable or
+// nurseToBedRatio aggregates the types by that attribute instead.
 func GetBedStats(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	groupBy := r.URL.Query().Get("groupBy")
+	groupKey, ok := bedStatsGroups[groupBy]
+	if groupBy != "" && groupBy != "type" && !ok {
+		bedSendJSONError(w, "groupBy must be type, acuityLevel, needsVentilator, needsMonitor, isolationCapable or nurseToBedRatio", http.StatusBadRequest)
+		return
+	}
+
 	query := `
 		SELECT 
-			bi.BedType, 
+			` + bedTypeColumns + `,
 			COUNT(bi.BedID) AS TotalBeds,
 			SUM(CASE WHEN ba.BedID IS NULL THEN 1 ELSE 0 END) AS AvailableBeds,
 			SUM(CASE WHEN ba.BedID IS NOT NULL THEN 1 ELSE 0 END) AS OccupiedBeds
 		FROM 
 			BedInventory bi
+		JOIN 
+			BedTypes bt ON bi.BedType = bt.BedType
 		LEFT JOIN 
 			(SELECT BedID FROM BedAssignments WHERE DischargeDate IS NULL) ba 
 			ON bi.BedID = ba.BedID
-		GROUP BY bi.BedType
-		ORDER BY bi.BedType
+		WHERE 1=1
 	`
+	var args []interface{}
+	if hospitalID := r.URL.Query().Get("hospitalId"); hospitalID != "" {
+		query += " AND bi.HospitalID = ?"
+		args = append(args, hospitalID)
+	}
+	query += " GROUP BY bt.BedType ORDER BY bt.BedType"
 
-	rows, err := database.DB.Query(query)
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		log.Printf("Error querying bed stats: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -501,175 +452,170 @@ func GetBedStats(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var stats []map[string]interface{}
+	var types []models.BedType
 	for rows.Next() {
-		var bedType string
-		var totalBeds, availableBeds, occupiedBeds int
-
-		err := rows.Scan(&bedType, &totalBeds, &availableBeds, &occupiedBeds)
+		var t models.BedType
+		err := rows.Scan(&t.Type, &t.Description, &t.AcuityLevel, &t.DailyTariff,
+			&t.NeedsVentilator, &t.NeedsMonitor, &t.BedsPerNurse, &t.IsolationCapable, &t.Retired,
+			&t.Total, &t.Vacant, &t.Occupied)
 		if err != nil {
 			log.Printf("Error scanning bed stats row: %v", err)
 			continue
 		}
+		t.NurseToBedRatio = nurseToBedRatio(t.BedsPerNurse)
+		types = append(types, t)
+	}
 
-		stat := map[string]interface{}{
-			"type":          bedType,
-			"totalBeds":     totalBeds,
-			"availableBeds": availableBeds,
-			"occupiedBeds":  occupiedBeds,
-			"occupancyRate": calculatePercentage(occupiedBeds, totalBeds),
+	var stats []map[string]interface{}
+	if groupKey == nil {
+		for _, t := range types {
+			stats = append(stats, map[string]interface{}{
+				"type":             t.Type,
+				"totalBeds":        t.Total,
+				"availableBeds":    t.Vacant,
+				"occupiedBeds":     t.Occupied,
+				"occupancyRate":    calculatePercentage(t.Occupied, t.Total),
+				"acuityLevel":      t.AcuityLevel,
+				"dailyTariff":      t.DailyTariff,
+				"needsVentilator":  t.NeedsVentilator,
+				"needsMonitor":     t.NeedsMonitor,
+				"nurseToBedRatio":  t.NurseToBedRatio,
+				"isolationCapable": t.IsolationCapable,
+				"retired":          t.Retired,
+				"dailyRevenue":     float64(t.Occupied) * t.DailyTariff,
+				"nursesRequired":   math.Ceil(float64(t.Occupied) / t.BedsPerNurse),
+			})
 		}
-
-		stats = append(stats, stat)
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+
+	// Types are already sorted by name, so groups come out in the order
+	// their first type appears
+	index := make(map[interface{}]int)
+	for _, t := range types {
+		key := groupKey(t)
+		i, seen := index[key]
+		if !seen {
+			i = len(stats)
+			index[key] = i
+			stats = append(stats, map[string]interface{}{
+				groupBy:          key,
+				"types":          []string{},
+				"totalBeds":      0,
+				"availableBeds":  0,
+				"occupiedBeds":   0,
+				"dailyRevenue":   0.0,
+				"nursesRequired": 0.0,
+			})
+		}
+		stat := stats[i]
+		stat["types"] = append(stat["types"].([]string), t.Type)
+		stat["totalBeds"] = stat["totalBeds"].(int) + t.Total
+		stat["availableBeds"] = stat["availableBeds"].(int) + t.Vacant
+		stat["occupiedBeds"] = stat["occupiedBeds"].(int) + t.Occupied
+		stat["dailyRevenue"] = stat["dailyRevenue"].(float64) + float64(t.Occupied)*t.DailyTariff
+		stat["nursesRequired"] = stat["nursesRequired"].(float64) + math.Ceil(float64(t.Occupied)/t.BedsPerNurse)
+	}
+	for _, stat := range stats {
+		stat["occupancyRate"] = calculatePercentage(stat["occupiedBeds"].(int), stat["totalBeds"].(int))
 	}
 
 	json.NewEncoder(w).Encode(stats)
 }
 
-// SyncBedsCount synchronizes the BedsCount table with actual data from BedInventory and BedAssignments
+// SyncBedsCount repairs BedsCount rows that have drifted from BedInventory and BedAssignments
 func SyncBedsCount(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Start a transaction for consistency
-	tx, err := database.DB.Begin()
+	updates, err := syncBedCounts()
 	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
-		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback() // Will be ignored if tx.Commit() is called
-
-	// 1. Get a list of all hospital and bed type combinations
-	rows, err := tx.Query(`
-		SELECT DISTINCT h.HospitalID, bt.BedType 
-		FROM Hospital h
-		CROSS JOIN BedTypes bt
-		WHERE EXISTS (
-			SELECT 1 
-			FROM BedInventory bi 
-			WHERE bi.HospitalID = h.HospitalID AND bi.BedType = bt.BedType
-		)
-	`)
-	if err != nil {
-		log.Printf("Error getting hospital and bed type combinations: %v", err)
+		log.Printf("Error synchronizing BedsCount: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	type HospitalBedType struct {
-		HospitalID int
-		BedType    string
+	response := map[string]interface{}{
+		"success": true,
+		"message": "BedsCount table synchronized successfully",
+		"updates": updates,
 	}
 
-	var combinations []HospitalBedType
-	for rows.Next() {
-		var combo HospitalBedType
-		if err := rows.Scan(&combo.HospitalID, &combo.BedType); err != nil {
-			log.Printf("Error scanning hospital and bed type: %v", err)
-			continue
-		}
-		combinations = append(combinations, combo)
-	}
-	rows.Close()
+	log.Printf("BedsCount table synchronized, %d rows corrected", len(updates))
+	json.NewEncoder(w).Encode(response)
+}
 
-	if len(combinations) == 0 {
-		log.Println("No hospital and bed type combinations found")
-		bedSendJSONError(w, "No data to synchronize", http.StatusNotFound)
-		return
+// syncBedCounts finds and repairs drift in one transaction and describes each corrected row
+func syncBedCounts() ([]string, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	// 2. For each combination, calculate the correct counts
-	var updates []string
-	for _, combo := range combinations {
-		// Get total beds
-		var totalBeds int
-		err := tx.QueryRow(`
-			SELECT COUNT(*) 
-			FROM BedInventory 
-			WHERE HospitalID = ? AND BedType = ?
-		`, combo.HospitalID, combo.BedType).Scan(&totalBeds)
-
-		if err != nil {
-			log.Printf("Error counting total beds for hospital %d, bed type %s: %v",
-				combo.HospitalID, combo.BedType, err)
-			continue
-		}
-
-		// Get occupied beds
-		var occupiedBeds int
-		err = tx.QueryRow(`
-			SELECT COUNT(*) 
-			FROM BedInventory bi
-			JOIN BedAssignments ba ON bi.BedID = ba.BedID
-			WHERE bi.HospitalID = ? 
-			AND bi.BedType = ? 
-			AND (ba.DischargeDate IS NULL OR ba.DischargeDate > CURDATE())
-		`, combo.HospitalID, combo.BedType).Scan(&occupiedBeds)
-
-		if err != nil {
-			log.Printf("Error counting occupied beds for hospital %d, bed type %s: %v",
-				combo.HospitalID, combo.BedType, err)
-			continue
-		}
-
-		// Calculate vacant beds
-		vacantBeds := totalBeds - occupiedBeds
-
-		// 3. Update or insert the BedsCount record
-		var exists bool
-		err = tx.QueryRow(`
-			SELECT EXISTS (
-				SELECT 1 
-				FROM BedsCount 
-				WHERE HospitalID = ? AND BedType = ?
-			)
-		`, combo.HospitalID, combo.BedType).Scan(&exists)
-
-		if err != nil {
-			log.Printf("Error checking if BedsCount record exists: %v", err)
-			continue
-		}
-
-		if exists {
-			_, err = tx.Exec(`
-				UPDATE BedsCount 
-				SET TotalBeds = ?, OccupiedBeds = ?, VacantBeds = ?
-				WHERE HospitalID = ? AND BedType = ?
-			`, totalBeds, occupiedBeds, vacantBeds, combo.HospitalID, combo.BedType)
-		} else {
-			_, err = tx.Exec(`
-				INSERT INTO BedsCount (HospitalID, BedType, TotalBeds, OccupiedBeds, VacantBeds)
-				VALUES (?, ?, ?, ?, ?)
-			`, combo.HospitalID, combo.BedType, totalBeds, occupiedBeds, vacantBeds)
-		}
+	// Lock the counts so occupancy changes wait for the repair
+	var rows int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM BedsCount FOR UPDATE").Scan(&rows); err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			log.Printf("Error updating BedsCount for hospital %d, bed type %s: %v",
-				combo.HospitalID, combo.BedType, err)
-			continue
-		}
+	drift, err := findBedCountDrift(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := repairBedCounts(tx, drift); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
-		updates = append(updates, fmt.Sprintf("Hospital %d, %s: %d total, %d occupied, %d vacant",
-			combo.HospitalID, combo.BedType, totalBeds, occupiedBeds, vacantBeds))
+	updates := []string{}
+	for _, d := range drift {
+		updates = append(updates, fmt.Sprintf("Hospital %d, %s: %d total, %d occupied, %d vacant (was %d/%d/%d)",
+			d.HospitalID, d.BedType, d.ActualTotal, d.ActualOccupied, d.ActualTotal-d.ActualOccupied,
+			d.RecordedTotal, d.RecordedOccupied, d.RecordedVacant))
 	}
+	return updates, nil
+}
 
-	// 4. Commit the transaction
-	if err := tx.Commit(); err != nil {
-		log.Printf("Error committing transaction: %v", err)
+// CheckBedsCount reports BedsCount rows that disagree with the inventory and
+// active assignments without changing anything
+func CheckBedsCount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	drift, err := findBedCountDrift(database.DB)
+	if err != nil {
+		log.Printf("Error checking BedsCount: %v", err)
 		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	// 5. Return success response
-	response := map[string]interface{}{
-		"success": true,
-		"message": "BedsCount table synchronized successfully",
-		"updates": updates,
-	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"consistent": len(drift) == 0,
+		"drift":      drift,
+	})
+}
 
-	log.Println("BedsCount table synchronized successfully")
-	json.NewEncoder(w).Encode(response)
+// StartBedsCountCheck periodically repairs and logs any BedsCount drift
+func StartBedsCountCheck() {
+	go func() {
+		ticker := time.NewTicker(bedsCountCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			updates, err := syncBedCounts()
+			if err != nil {
+				log.Printf("Error checking BedsCount: %v", err)
+				continue
+			}
+			for _, u := range updates {
+				log.Printf("Corrected BedsCount drift: %s", u)
+			}
+		}
+	}()
+	log.Println("BedsCount consistency check started")
 }
 
 // Helper function to send JSON error responses
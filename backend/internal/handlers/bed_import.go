@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Bulk bed provisioning. Rows are validated as a whole batch and either all
+// beds are added or none are; BedsCount is adjusted once per hospital and bed
+// type. Rooms named in a row that don't exist yet are created in the ward, so
+// a new ward only needs its Wards row before its beds are imported.
+
+// maxBedImportRows caps the size of one batch
+const maxBedImportRows = 2000
+
+// bedImportRow is one bed to add. Hospital may be given as hospitalId or as
+// hospital, which takes an ID or the hospital's name. Ward, room and label
+// are optional, but a room needs a ward and a label needs a room.
+type bedImportRow struct {
+	Row        int      `json:"row"`
+	HospitalID int      `json:"hospitalId"`
+	Hospital   string   `json:"hospital,omitempty"`
+	Ward       string   `json:"ward,omitempty"`
+	Room       string   `json:"room,omitempty"`
+	Label      string   `json:"label,omitempty"`
+	BedType    string   `json:"bedType"`
+	NewRoom    bool     `json:"newRoom,omitempty"`
+	BedID      int      `json:"bedId,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+
+	wardID int
+	roomID int
+}
+
+type bedImportRoom struct {
+	wardID int
+	number string
+}
+
+// bedImportCount is the change a batch makes to one BedsCount row
+type bedImportCount struct {
+	HospitalID  int    `json:"hospitalId"`
+	BedType     string `json:"bedType"`
+	Added       int    `json:"added"`
+	TotalBefore int    `json:"totalBefore"`
+	TotalAfter  int    `json:"totalAfter"`
+}
+
+// bedImporter validates and inserts a batch inside one transaction, caching
+// lookups so a 60-bed ward costs a handful of queries per distinct value
+type bedImporter struct {
+	tx        *sql.Tx
+	hospitals map[string]int    // hospital as given -> HospitalID, 0 if unknown
+	bedTypes  map[string]string // bed type -> validation error, "" if usable
+	wards     map[string]int    // "hospitalID/ward" -> WardID, 0 if unknown
+	rooms     map[bedImportRoom]int
+	labels    map[string]int // "wardID/room/label" -> row that claimed it
+}
+
+func newBedImporter(tx *sql.Tx) *bedImporter {
+	return &bedImporter{
+		tx:        tx,
+		hospitals: make(map[string]int),
+		bedTypes:  make(map[string]string),
+		wards:     make(map[string]int),
+		rooms:     make(map[bedImportRoom]int),
+		labels:    make(map[string]int),
+	}
+}
+
+// hospital resolves a hospital ID or name
+func (im *bedImporter) hospital(hospital string) (int, error) {
+	if id, ok := im.hospitals[hospital]; ok {
+		return id, nil
+	}
+
+	var ids []int
+	query, arg := "SELECT HospitalID FROM Hospital WHERE Address = ?", interface{}(hospital)
+	if id, err := strconv.Atoi(hospital); err == nil {
+		query, arg = "SELECT HospitalID FROM Hospital WHERE HospitalID = ?", id
+	}
+	rows, err := im.tx.Query(query, arg)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	id := 0
+	if len(ids) == 1 {
+		id = ids[0]
+	}
+	im.hospitals[hospital] = id
+	return id, nil
+}
+
+// bedType checks that a bed type exists and is not retired
+func (im *bedImporter) bedType(bedType string) (string, error) {
+	if problem, ok := im.bedTypes[bedType]; ok {
+		return problem, nil
+	}
+	var retired bool
+	err := im.tx.QueryRow("SELECT RetiredAt IS NOT NULL FROM BedTypes WHERE BedType = ?", bedType).Scan(&retired)
+	problem := ""
+	switch {
+	case err == sql.ErrNoRows:
+		problem = fmt.Sprintf("unknown bed type %q", bedType)
+	case err != nil:
+		return "", err
+	case retired:
+		problem = fmt.Sprintf("bed type %q is retired", bedType)
+	}
+	im.bedTypes[bedType] = problem
+	return problem, nil
+}
+
+// ward finds a ward by name within a hospital
+func (im *bedImporter) ward(hospitalID int, name string) (int, error) {
+	key := fmt.Sprintf("%d/%s", hospitalID, name)
+	if id, ok := im.wards[key]; ok {
+		return id, nil
+	}
+	var id int
+	err := im.tx.QueryRow("SELECT WardID FROM Wards WHERE HospitalID = ? AND Name = ?", hospitalID, name).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	im.wards[key] = id
+	return id, nil
+}
+
+// room finds a room by number within a ward; 0 means it will be created
+func (im *bedImporter) room(wardID int, number string) (int, error) {
+	key := bedImportRoom{wardID, number}
+	if id, ok := im.rooms[key]; ok {
+		return id, nil
+	}
+	var id int
+	err := im.tx.QueryRow("SELECT RoomID FROM Rooms WHERE WardID = ? AND RoomNumber = ?", wardID, number).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	im.rooms[key] = id
+	return id, nil
+}
+
+// validate resolves a row's hospital, ward and room and records any problems
+// in row.Errors
+func (im *bedImporter) validate(row *bedImportRow) error {
+	row.Hospital = strings.TrimSpace(row.Hospital)
+	row.Ward = strings.TrimSpace(row.Ward)
+	row.Room = strings.TrimSpace(row.Room)
+	row.Label = strings.TrimSpace(row.Label)
+	row.BedType = strings.TrimSpace(row.BedType)
+	row.Errors = nil
+
+	if row.BedType == "" {
+		row.Errors = append(row.Errors, "bed type is required")
+	} else if problem, err := im.bedType(row.BedType); err != nil {
+		return err
+	} else if problem != "" {
+		row.Errors = append(row.Errors, problem)
+	}
+
+	if row.Room != "" && row.Ward == "" {
+		row.Errors = append(row.Errors, "a room needs a ward")
+	}
+	if row.Label != "" && row.Room == "" {
+		row.Errors = append(row.Errors, "a label needs a room")
+	}
+	if len(row.Label) > 20 || len(row.Room) > 20 {
+		row.Errors = append(row.Errors, "room and label must be at most 20 characters")
+	}
+
+	hospital := row.Hospital
+	if row.HospitalID != 0 {
+		hospital = strconv.Itoa(row.HospitalID)
+	}
+	if hospital == "" {
+		row.Errors = append(row.Errors, "hospital is required")
+		return nil
+	}
+	hospitalID, err := im.hospital(hospital)
+	if err != nil {
+		return err
+	}
+	if hospitalID == 0 {
+		row.Errors = append(row.Errors, fmt.Sprintf("hospital %q not found or not unique", hospital))
+		return nil
+	}
+	row.HospitalID = hospitalID
+
+	if row.Ward == "" {
+		return nil
+	}
+	if row.wardID, err = im.ward(hospitalID, row.Ward); err != nil {
+		return err
+	}
+	if row.wardID == 0 {
+		row.Errors = append(row.Errors, fmt.Sprintf("ward %q not found in hospital %d", row.Ward, hospitalID))
+		return nil
+	}
+
+	if row.Room == "" {
+		return nil
+	}
+	if row.roomID, err = im.room(row.wardID, row.Room); err != nil {
+		return err
+	}
+	row.NewRoom = row.roomID == 0
+
+	if row.Label == "" {
+		return nil
+	}
+	key := fmt.Sprintf("%d/%s/%s", row.wardID, row.Room, row.Label)
+	if other, ok := im.labels[key]; ok {
+		row.Errors = append(row.Errors, fmt.Sprintf("label %q is also used in row %d", row.Label, other))
+		return nil
+	}
+	im.labels[key] = row.Row
+	if row.roomID != 0 {
+		var taken bool
+		err := im.tx.QueryRow("SELECT EXISTS (SELECT 1 FROM BedInventory WHERE RoomID = ? AND Label = ?)",
+			row.roomID, row.Label).Scan(&taken)
+		if err != nil {
+			return err
+		}
+		if taken {
+			row.Errors = append(row.Errors, fmt.Sprintf("room %s already has a bed labelled %q", row.Room, row.Label))
+		}
+	}
+	return nil
+}
+
+// insert adds a validated row's bed, creating its room first if needed
+func (im *bedImporter) insert(row *bedImportRow) error {
+	if row.Room != "" && row.roomID == 0 {
+		key := bedImportRoom{row.wardID, row.Room}
+		if im.rooms[key] == 0 {
+			result, err := im.tx.Exec("INSERT INTO Rooms (WardID, RoomNumber) VALUES (?, ?)", row.wardID, row.Room)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			im.rooms[key] = int(id)
+		}
+		row.roomID = im.rooms[key]
+	}
+
+	var roomID, label interface{}
+	if row.roomID != 0 {
+		roomID = row.roomID
+	}
+	if row.Label != "" {
+		label = row.Label
+	}
+	result, err := im.tx.Exec("INSERT INTO BedInventory (HospitalID, BedType, RoomID, Label) VALUES (?, ?, ?, ?)",
+		row.HospitalID, row.BedType, roomID, label)
+	if err != nil {
+		return err
+	}
+	bedID, err := result.LastInsertId()
+	row.BedID = int(bedID)
+	return err
+}
+
+// bedImportSummary describes what a batch adds: the rooms it creates and
+// the change to each hospital's count of each bed type
+func bedImportSummary(tx *sql.Tx, rows []bedImportRow) (map[string]interface{}, []bedImportCount, error) {
+	var counts []bedImportCount
+	index := make(map[string]int)
+	rooms := []map[string]interface{}{}
+	seenRooms := make(map[bedImportRoom]bool)
+	for _, row := range rows {
+		key := fmt.Sprintf("%d/%s", row.HospitalID, row.BedType)
+		i, ok := index[key]
+		if !ok {
+			i = len(counts)
+			index[key] = i
+			counts = append(counts, bedImportCount{HospitalID: row.HospitalID, BedType: row.BedType})
+		}
+		counts[i].Added++
+
+		room := bedImportRoom{row.wardID, row.Room}
+		if row.NewRoom && !seenRooms[room] {
+			seenRooms[room] = true
+			rooms = append(rooms, map[string]interface{}{
+				"hospitalId": row.HospitalID,
+				"ward":       row.Ward,
+				"room":       row.Room,
+			})
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].HospitalID != counts[j].HospitalID {
+			return counts[i].HospitalID < counts[j].HospitalID
+		}
+		return counts[i].BedType < counts[j].BedType
+	})
+
+	for i := range counts {
+		err := tx.QueryRow("SELECT COALESCE(MAX(TotalBeds), 0) FROM BedsCount WHERE HospitalID = ? AND BedType = ?",
+			counts[i].HospitalID, counts[i].BedType).Scan(&counts[i].TotalBefore)
+		if err != nil {
+			return nil, nil, err
+		}
+		counts[i].TotalAfter = counts[i].TotalBefore + counts[i].Added
+	}
+
+	return map[string]interface{}{
+		"beds":         len(rows),
+		"roomsCreated": rooms,
+		"counts":       counts,
+	}, counts, nil
+}
+
+// importBeds validates rows and, unless dryRun is set, adds them all
+func importBeds(w http.ResponseWriter, rows []bedImportRow, dryRun bool) {
+	if len(rows) == 0 {
+		bedSendJSONError(w, "No beds to import", http.StatusBadRequest)
+		return
+	}
+	if len(rows) > maxBedImportRows {
+		bedSendJSONError(w, fmt.Sprintf("At most %d beds can be imported at once", maxBedImportRows), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	im := newBedImporter(tx)
+	invalid := 0
+	for i := range rows {
+		if err := im.validate(&rows[i]); err != nil {
+			log.Printf("Error validating bed import row %d: %v", rows[i].Row, err)
+			bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if len(rows[i].Errors) > 0 {
+			invalid++
+		}
+	}
+
+	if invalid > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       fmt.Sprintf("%d of %d rows are invalid; no beds were added", invalid, len(rows)),
+			"invalidRows": invalid,
+			"rows":        rows,
+		})
+		return
+	}
+
+	summary, counts, err := bedImportSummary(tx, rows)
+	if err != nil {
+		log.Printf("Error summarising bed import: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if dryRun {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "preview",
+			"summary": summary,
+			"rows":    rows,
+		})
+		return
+	}
+
+	for i := range rows {
+		if err := im.insert(&rows[i]); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+				bedSendJSONError(w, fmt.Sprintf("Row %d conflicts with a bed or room added meanwhile; no beds were added", rows[i].Row), http.StatusConflict)
+				return
+			}
+			log.Printf("Error importing bed row %d: %v", rows[i].Row, err)
+			bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	for _, count := range counts {
+		if err := adjustBedsCount(tx, count.HospitalID, count.BedType, count.Added, 0); err != nil {
+			log.Printf("Error updating beds count: %v", err)
+			bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Imported %d beds", len(rows))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"summary": summary,
+		"rows":    rows,
+	})
+}
+
+// CreateBedsBulk adds a batch of beds given as JSON:
+// {"dryRun": false, "beds": [{"hospitalId": 1, "ward": "3B", "room": "12", "label": "A", "bedType": "ICU"}]}
+func CreateBedsBulk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req struct {
+		DryRun bool           `json:"dryRun"`
+		Beds   []bedImportRow `json:"beds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		bedSendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for i := range req.Beds {
+		req.Beds[i].Row = i + 1
+		req.Beds[i].BedID = 0
+		req.Beds[i].NewRoom = false
+	}
+
+	importBeds(w, req.Beds, req.DryRun || r.URL.Query().Get("dryRun") == "true")
+}
+
+// bedImportColumns maps CSV header names, lowercased with spaces and
+// underscores removed, to row fields
+var bedImportColumns = map[string]string{
+	"hospital":   "hospital",
+	"hospitalid": "hospital",
+	"ward":       "ward",
+	"room":       "room",
+	"label":      "label",
+	"bedlabel":   "label",
+	"bedtype":    "bedType",
+	"type":       "bedType",
+}
+
+// parseBedImportCSV reads rows from a CSV file with a header line naming the
+// hospital, ward, room, label and bed type columns
+func parseBedImportCSV(body io.Reader) ([]bedImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.NewReplacer(" ", "", "_", "", "\ufeff", "").Replace(name))
+		if field, ok := bedImportColumns[name]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["hospital"]; !ok {
+		return nil, errors.New("missing hospital column")
+	}
+	if _, ok := columns["bedType"]; !ok {
+		return nil, errors.New("missing bed type column")
+	}
+
+	var rows []bedImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		rows = append(rows, bedImportRow{
+			Row:      line,
+			Hospital: field("hospital"),
+			Ward:     field("ward"),
+			Room:     field("room"),
+			Label:    field("label"),
+			BedType:  field("bedType"),
+		})
+		if len(rows) > maxBedImportRows {
+			break
+		}
+	}
+	return rows, nil
+}
+
+// ImportBedsCSV adds a batch of beds from a CSV file, sent either as the
+// request body or as the "file" field of a multipart form. Rows are numbered
+// by their line in the file. dryRun=true previews the import.
+func ImportBedsCSV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var body io.Reader = r.Body
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			bedSendJSONError(w, "A CSV file is required in the file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		dryRun = dryRun || r.FormValue("dryRun") == "true"
+	}
+
+	rows, err := parseBedImportCSV(body)
+	if err != nil {
+		bedSendJSONError(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	importBeds(w, rows, dryRun)
+}
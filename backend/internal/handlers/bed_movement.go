@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hospital-management/backend/internal/database"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Bed movement ledger. Every admission, transfer, swap and discharge made by
+// the occupancy service appends a row to BedMovements, in the same
+// transaction, with who made it and why. The ledger is the source for patient
+// timelines and bed occupancy history; time in bed runs from one movement to
+// the next.
+
+// bedMove describes who made an occupancy change and why
+type bedMove struct {
+	Reason  string
+	Notes   string
+	ActorID int // EmployeeID, 0 for changes made by the system
+}
+
+// reasonOr returns the move's reason, or def if none was given
+func (m bedMove) reasonOr(def string) string {
+	if m.Reason != "" {
+		return m.Reason
+	}
+	return def
+}
+
+// bedSide is the bed and assignment on one side of a movement; both are zero
+// for the outside of an admission or discharge
+type bedSide struct {
+	BedID        int
+	AssignmentID int64
+}
+
+// recordMovement appends a movement to the ledger. Movements dated today are
+// stamped with the current time; backdated ones with the start of their day.
+func recordMovement(tx *sql.Tx, movementType string, patientID int, from, to bedSide, date string, m bedMove) error {
+	_, err := tx.Exec(`
+		INSERT INTO BedMovements
+			(PatientID, MovementType, FromBedID, FromAssignmentID, ToBedID, ToAssignmentID,
+			 Reason, Notes, ActorID, MovedAt)
+		VALUES (?, ?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0),
+			NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), IF(? = CURDATE(), NOW(), ?))
+	`, patientID, movementType, from.BedID, from.AssignmentID, to.BedID, to.AssignmentID,
+		m.Reason, m.Notes, m.ActorID, date, date)
+	return err
+}
+
+// swapBeds exchanges the beds of two patients admitted to the same hospital.
+// Both assignments are closed and reopened on the other bed, keeping each
+// patient's admission date; the beds stay occupied so statuses and counts
+// don't change.
+func swapBeds(tx *sql.Tx, a, b activeAssignment, date string, m bedMove) (int64, int64, error) {
+	if a.Bed.BedID == b.Bed.BedID {
+		return 0, 0, errBedUnavailable
+	}
+	if a.Bed.HospitalID != b.Bed.HospitalID {
+		return 0, 0, errCrossHospitalMove
+	}
+	if date < a.AdmissionDate || date < b.AdmissionDate {
+		return 0, 0, errDischargeBeforeAdmit
+	}
+	if err := checkWardGender(tx, b.Bed.BedID, a.PatientID); err != nil {
+		return 0, 0, err
+	}
+	if err := checkWardGender(tx, a.Bed.BedID, b.PatientID); err != nil {
+		return 0, 0, err
+	}
+	// Each bed must not be reserved for someone else before its new occupant is due to leave
+	if _, err := claimReservation(tx, b.Bed, a.PatientID, date, a.PlannedDischargeDate); err != nil {
+		return 0, 0, err
+	}
+	if _, err := claimReservation(tx, a.Bed, b.PatientID, date, b.PlannedDischargeDate); err != nil {
+		return 0, 0, err
+	}
+
+	_, err := tx.Exec(
+		"UPDATE BedAssignments SET DischargeDate = ? WHERE AssignmentID IN (?, ?)",
+		date, a.AssignmentID, b.AssignmentID)
+	if err != nil {
+		return 0, 0, err
+	}
+	aID, err := insertAssignment(tx, b.Bed.BedID, a.PatientID, a.AdmissionDate, a.AdmissionID)
+	if err != nil {
+		return 0, 0, err
+	}
+	bID, err := insertAssignment(tx, a.Bed.BedID, b.PatientID, b.AdmissionDate, b.AdmissionID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := keepPlannedDischarge(tx, aID, a); err != nil {
+		return 0, 0, err
+	}
+	if err := keepPlannedDischarge(tx, bID, b); err != nil {
+		return 0, 0, err
+	}
+
+	err = recordMovement(tx, "swap", a.PatientID,
+		bedSide{a.Bed.BedID, int64(a.AssignmentID)}, bedSide{b.Bed.BedID, aID}, date, m)
+	if err == nil {
+		err = recordMovement(tx, "swap", b.PatientID,
+			bedSide{b.Bed.BedID, int64(b.AssignmentID)}, bedSide{a.Bed.BedID, bID}, date, m)
+	}
+	return aID, bID, err
+}
+
+// SwapBeds exchanges the beds of two admitted patients
+func SwapBeds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req struct {
+		PatientID      int    `json:"patientId"`
+		OtherPatientID int    `json:"otherPatientId"`
+		EmployeeID     int    `json:"employeeId"`
+		Reason         string `json:"reason"`
+		Notes          string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.PatientID == 0 || req.OtherPatientID == 0 || req.PatientID == req.OtherPatientID {
+		sendJSONError(w, "Two different patients are required", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		sendJSONError(w, "A reason is required to swap beds", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the two patients in ID order so concurrent swaps can't deadlock
+	first, second := req.PatientID, req.OtherPatientID
+	if first > second {
+		first, second = second, first
+	}
+	assignments := map[int]activeAssignment{}
+	for _, patientID := range []int{first, second} {
+		a, err := currentAssignment(tx, patientID)
+		if err != nil {
+			status, message := bedErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Printf("Error fetching bed assignment: %v", err)
+			}
+			sendJSONError(w, message, status)
+			return
+		}
+		assignments[patientID] = a
+	}
+	a, b := assignments[req.PatientID], assignments[req.OtherPatientID]
+
+	date := time.Now().Format("2006-01-02")
+	m := bedMove{Reason: req.Reason, Notes: req.Notes, ActorID: req.EmployeeID}
+	aID, bID, err := swapBeds(tx, a, b, date, m)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error swapping beds: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Beds swapped successfully",
+		"swaps": []map[string]interface{}{
+			{"patientId": a.PatientID, "fromBedId": a.Bed.BedID, "toBedId": b.Bed.BedID, "assignmentId": aID},
+			{"patientId": b.PatientID, "fromBedId": b.Bed.BedID, "toBedId": a.Bed.BedID, "assignmentId": bID},
+		},
+		"swapDate": date,
+	})
+}
+
+// bedMovementEntry is a ledger row as returned by the timeline endpoints
+type bedMovementEntry struct {
+	MovementID   int     `json:"movementId"`
+	Type         string  `json:"type"`
+	FromBedID    int     `json:"fromBedId,omitempty"`
+	FromLocation string  `json:"fromLocation,omitempty"`
+	ToBedID      int     `json:"toBedId,omitempty"`
+	ToLocation   string  `json:"toLocation,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+	Notes        string  `json:"notes,omitempty"`
+	Actor        string  `json:"actor,omitempty"`
+	MovedAt      string  `json:"movedAt"`
+	HoursInBed   float64 `json:"hoursInBed,omitempty"` // time in ToBedID until the next movement, or until now
+	movedAt      time.Time
+}
+
+// hoursBetween rounds the time between two instants to a tenth of an hour
+func hoursBetween(from, to time.Time) float64 {
+	return math.Round(to.Sub(from).Hours()*10) / 10
+}
+
+// GetPatientBedTimeline returns a patient's bed movements grouped into stays.
+// A stay runs from an admission to the discharge that ends it; the current
+// stay has no discharge yet. Each movement into a bed carries the time the
+// patient spent in that bed.
+func GetPatientBedTimeline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	patientID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid patient ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT m.MovementID, m.MovementType,
+		       COALESCE(m.FromBedID, 0), COALESCE(fw.Name, ''), COALESCE(fr.RoomNumber, ''), COALESCE(fb.Label, ''),
+		       COALESCE(m.ToBedID, 0), COALESCE(tw.Name, ''), COALESCE(tr.RoomNumber, ''), COALESCE(tb.Label, ''),
+		       COALESCE(m.Reason, ''), COALESCE(m.Notes, ''), COALESCE(e.FullName, ''), m.MovedAt
+		FROM BedMovements m
+		LEFT JOIN BedInventory fb ON m.FromBedID = fb.BedID
+		LEFT JOIN Rooms fr ON fb.RoomID = fr.RoomID
+		LEFT JOIN Wards fw ON fr.WardID = fw.WardID
+		LEFT JOIN BedInventory tb ON m.ToBedID = tb.BedID
+		LEFT JOIN Rooms tr ON tb.RoomID = tr.RoomID
+		LEFT JOIN Wards tw ON tr.WardID = tw.WardID
+		LEFT JOIN Employees e ON m.ActorID = e.EmployeeID
+		WHERE m.PatientID = ?
+		ORDER BY m.MovedAt, m.MovementID
+	`, patientID)
+	if err != nil {
+		log.Printf("Error querying bed movements: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type stay struct {
+		AdmittedAt   string             `json:"admittedAt"`
+		DischargedAt string             `json:"dischargedAt,omitempty"`
+		TotalHours   float64            `json:"totalHours"`
+		Movements    []bedMovementEntry `json:"movements"`
+	}
+	var stays []*stay
+	var current *stay
+	for rows.Next() {
+		var e bedMovementEntry
+		var fromWard, fromRoom, fromLabel, toWard, toRoom, toLabel string
+		err := rows.Scan(&e.MovementID, &e.Type,
+			&e.FromBedID, &fromWard, &fromRoom, &fromLabel,
+			&e.ToBedID, &toWard, &toRoom, &toLabel,
+			&e.Reason, &e.Notes, &e.Actor, &e.movedAt)
+		if err != nil {
+			log.Printf("Error scanning bed movement row: %v", err)
+			continue
+		}
+		e.MovedAt = e.movedAt.Format("2006-01-02 15:04")
+		if e.FromBedID != 0 {
+			e.FromLocation = bedLocation(e.FromBedID, fromWard, fromRoom, fromLabel)
+		}
+		if e.ToBedID != 0 {
+			e.ToLocation = bedLocation(e.ToBedID, toWard, toRoom, toLabel)
+		}
+
+		if e.Type == "admit" || current == nil {
+			current = &stay{AdmittedAt: e.MovedAt}
+			stays = append(stays, current)
+		}
+		// The previous movement's time in bed ends here
+		if n := len(current.Movements); n > 0 {
+			prev := &current.Movements[n-1]
+			prev.HoursInBed = hoursBetween(prev.movedAt, e.movedAt)
+			current.TotalHours += prev.HoursInBed
+		}
+		current.Movements = append(current.Movements, e)
+		if e.Type == "discharge" {
+			current.DischargedAt = e.MovedAt
+			current = nil
+		}
+	}
+
+	// The patient is still in the bed of the last movement of an open stay
+	if current != nil {
+		last := &current.Movements[len(current.Movements)-1]
+		last.HoursInBed = hoursBetween(last.movedAt, time.Now())
+		current.TotalHours += last.HoursInBed
+	}
+	for _, s := range stays {
+		s.TotalHours = math.Round(s.TotalHours*10) / 10
+	}
+
+	// Most recent stay first
+	for i, j := 0, len(stays)-1; i < j; i, j = i+1, j-1 {
+		stays[i], stays[j] = stays[j], stays[i]
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"patientId": patientID,
+		"stays":     stays,
+	})
+}
+
+// GetBedHistory returns the occupancy history of a bed: each patient who
+// occupied it, how they arrived and left, and the time they spent in it,
+// with totals
+func GetBedHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	bedID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid bed ID", http.StatusBadRequest)
+		return
+	}
+
+	// A period in the bed starts with a movement into it and ends with the
+	// movement out of the assignment it opened
+	rows, err := database.DB.Query(`
+		SELECT i.PatientID, p.FullName, i.MovementType, i.MovedAt, COALESCE(i.Reason, ''),
+		       COALESCE(o.MovementType, ''), o.MovedAt, COALESCE(o.Reason, '')
+		FROM BedMovements i
+		JOIN Patients p ON i.PatientID = p.PatientID
+		LEFT JOIN BedMovements o ON o.FromAssignmentID = i.ToAssignmentID
+		WHERE i.ToBedID = ?
+		ORDER BY i.MovedAt DESC, i.MovementID DESC
+	`, bedID)
+	if err != nil {
+		log.Printf("Error querying bed history: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []map[string]interface{}{}
+	totalHours := 0.0
+	patients := map[int]bool{}
+	for rows.Next() {
+		var patientID int
+		var patientName, inType, inReason, outType, outReason string
+		var inAt time.Time
+		var outAt sql.NullTime
+		if err := rows.Scan(&patientID, &patientName, &inType, &inAt, &inReason, &outType, &outAt, &outReason); err != nil {
+			log.Printf("Error scanning bed history row: %v", err)
+			continue
+		}
+
+		end := time.Now()
+		if outAt.Valid {
+			end = outAt.Time
+		}
+		hours := hoursBetween(inAt, end)
+		totalHours += hours
+		patients[patientID] = true
+
+		entry := map[string]interface{}{
+			"patientId":   patientID,
+			"patientName": patientName,
+			"in":          inType,
+			"inAt":        inAt.Format("2006-01-02 15:04"),
+			"inReason":    inReason,
+			"hoursInBed":  hours,
+			"current":     !outAt.Valid,
+		}
+		if outAt.Valid {
+			entry["out"] = outType
+			entry["outAt"] = outAt.Time.Format("2006-01-02 15:04")
+			entry["outReason"] = outReason
+		}
+		history = append(history, entry)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bedId":       bedID,
+		"occupancies": len(history),
+		"patients":    len(patients),
+		"totalHours":  math.Round(totalHours*10) / 10,
+		"totalDays":   math.Round(totalHours/24*10) / 10,
+		"history":     history,
+	})
+}
@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry is MySQL's ER_DUP_ENTRY error number
+const mysqlDuplicateEntry = 1062
+
+// Bed occupancy service. Every change to who occupies which bed goes through
+// these functions, inside the caller's transaction, and each of them updates
+// BedsCount in the same transaction. A bed is occupied while it has an
+// assignment with no DischargeDate.
+//
+// Concurrent changes are serialised by locking the BedInventory row of each
+// bed involved and the Patients row of the patient with SELECT ... FOR UPDATE
+// before checking availability. The unique ActiveBedID and ActivePatientID
+// columns on BedAssignments back this up in the schema: at most one active
+// assignment can exist per bed and per patient.
+
+var (
+	errBedNotFound          = errors.New("bed not found")
+	errBedUnavailable       = errors.New("bed is not available")
+	errPatientNotFound      = errors.New("patient not found")
+	errPatientHasBed        = errors.New("patient already has an active bed assignment")
+	errNoActiveAssignment   = errors.New("no active bed assignment found")
+	errDischargeBeforeAdmit = errors.New("discharge date cannot be before the admission date")
+	errWardGender           = errors.New("bed is in a ward restricted to another gender")
+	errBedReserved          = errors.New("bed is reserved for another patient")
+	errCrossHospitalMove    = errors.New("bed is at another hospital; use an inter-hospital transfer instead")
+)
+
+// bedErrorStatus maps occupancy errors to HTTP statuses; anything else is a database error
+func bedErrorStatus(err error) (int, string) {
+	switch err {
+	case errBedNotFound, errPatientNotFound, errNoActiveAssignment:
+		return http.StatusNotFound, err.Error()
+	case errBedUnavailable, errPatientHasBed, errWardGender, errBedReserved:
+		return http.StatusConflict, err.Error()
+	case errDischargeBeforeAdmit, errCrossHospitalMove:
+		return http.StatusBadRequest, err.Error()
+	}
+	return http.StatusInternalServerError, "Database error"
+}
+
+// bedInfo describes the bed an occupancy change applied to
+type bedInfo struct {
+	BedID      int
+	HospitalID int
+	BedType    string
+	Status     string
+}
+
+// activeAssignment is a patient's current bed assignment
+type activeAssignment struct {
+	AssignmentID         int
+	AdmissionID          int64
+	PatientID            int
+	AdmissionDate        string
+	PlannedDischargeDate string // empty unless a discharge is planned
+	Bed                  bedInfo
+}
+
+// adjustBedsCount applies deltas to a hospital's count for a bed type,
+// creating the row if needed. VacantBeds is always Total - Occupied.
+func adjustBedsCount(tx *sql.Tx, hospitalID int, bedType string, totalDelta, occupiedDelta int) error {
+	_, err := tx.Exec(`
+		INSERT INTO BedsCount (HospitalID, BedType, TotalBeds, OccupiedBeds, VacantBeds)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			TotalBeds = TotalBeds + VALUES(TotalBeds),
+			OccupiedBeds = OccupiedBeds + VALUES(OccupiedBeds),
+			VacantBeds = TotalBeds - OccupiedBeds
+	`, hospitalID, bedType, totalDelta, occupiedDelta, totalDelta-occupiedDelta)
+	return err
+}
+
+// addBed puts a new bed into the inventory
+func addBed(tx *sql.Tx, hospitalID int, bedType string) (int64, error) {
+	result, err := tx.Exec("INSERT INTO BedInventory (HospitalID, BedType) VALUES (?, ?)", hospitalID, bedType)
+	if err != nil {
+		return 0, err
+	}
+	bedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return bedID, adjustBedsCount(tx, hospitalID, bedType, 1, 0)
+}
+
+// availableBed locks a bed and checks that it is in service and unoccupied.
+// Reserved beds are returned too; claimReservation decides who may take them.
+// The lock is held until the transaction ends, so a second transaction
+// assigning the same bed waits here and then sees the first assignment.
+func availableBed(tx *sql.Tx, bedID int) (bedInfo, error) {
+	bed := bedInfo{BedID: bedID}
+	err := tx.QueryRow(
+		"SELECT HospitalID, BedType, Status FROM BedInventory WHERE BedID = ? FOR UPDATE",
+		bedID).Scan(&bed.HospitalID, &bed.BedType, &bed.Status)
+	if err == sql.ErrNoRows {
+		return bed, errBedNotFound
+	}
+	if err != nil {
+		return bed, err
+	}
+
+	var occupied bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM BedAssignments WHERE BedID = ? AND DischargeDate IS NULL)",
+		bedID).Scan(&occupied)
+	if err != nil {
+		return bed, err
+	}
+	if occupied || (bed.Status != "available" && bed.Status != "reserved") {
+		return bed, errBedUnavailable
+	}
+	return bed, nil
+}
+
+// checkWardGender rejects a bed whose ward is restricted to a gender other
+// than the patient's. Beds outside any ward and patients without a recorded
+// gender are not restricted.
+func checkWardGender(tx *sql.Tx, bedID, patientID int) error {
+	var restriction, gender string
+	err := tx.QueryRow(`
+		SELECT COALESCE(w.GenderRestriction, 'any'), COALESCE(p.Gender, '')
+		FROM BedInventory bi
+		LEFT JOIN Rooms r ON bi.RoomID = r.RoomID
+		LEFT JOIN Wards w ON r.WardID = w.WardID
+		JOIN Patients p ON p.PatientID = ?
+		WHERE bi.BedID = ?
+	`, patientID, bedID).Scan(&restriction, &gender)
+	if err != nil {
+		return err
+	}
+	gender = strings.ToLower(strings.TrimSpace(gender))
+	if restriction == "any" || gender == "" || gender == restriction {
+		return nil
+	}
+	return errWardGender
+}
+
+// insertAssignment adds an active assignment, translating a violation of the
+// one-active-assignment constraints into the matching occupancy error
+func insertAssignment(tx *sql.Tx, bedID, patientID int, admissionDate string, admissionID int64) (int64, error) {
+	result, err := tx.Exec(`
+		INSERT INTO BedAssignments (BedID, PatientID, AdmissionDate, DischargeDate, AdmissionID)
+		VALUES (?, ?, ?, NULL, NULLIF(?, 0))
+	`, bedID, patientID, admissionDate, admissionID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			if strings.Contains(mysqlErr.Message, "uq_active_patient") {
+				return 0, errPatientHasBed
+			}
+			return 0, errBedUnavailable
+		}
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// keepPlannedDischarge copies a planned discharge onto the assignment that
+// replaced a, so moving bed doesn't lose it
+func keepPlannedDischarge(tx *sql.Tx, assignmentID int64, a activeAssignment) error {
+	if a.PlannedDischargeDate == "" {
+		return nil
+	}
+	_, err := tx.Exec(`
+		UPDATE BedAssignments ba
+		JOIN BedAssignments prev ON prev.AssignmentID = ?
+		SET ba.PlannedDischargeDate = prev.PlannedDischargeDate, ba.DischargeType = prev.DischargeType,
+		    ba.DischargingDoctorID = prev.DischargingDoctorID, ba.DischargeNotes = prev.DischargeNotes
+		WHERE ba.AssignmentID = ?
+	`, a.AssignmentID, assignmentID)
+	return err
+}
+
+// occupyBed admits a patient to a free bed, opening an admission for the stay.
+// The patient row is locked first so that the same patient can't be admitted
+// to two beds at once.
+func occupyBed(tx *sql.Tx, bedID, patientID int, admissionDate string, m bedMove, adm admissionDetails) (int64, bedInfo, error) {
+	var locked int
+	err := tx.QueryRow("SELECT PatientID FROM Patients WHERE PatientID = ? FOR UPDATE", patientID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return 0, bedInfo{}, errPatientNotFound
+	}
+	if err != nil {
+		return 0, bedInfo{}, err
+	}
+
+	if _, err := currentAssignment(tx, patientID); err != errNoActiveAssignment {
+		if err == nil {
+			err = errPatientHasBed
+		}
+		return 0, bedInfo{}, err
+	}
+
+	bed, err := availableBed(tx, bedID)
+	if err != nil {
+		return 0, bed, err
+	}
+	if err := checkWardGender(tx, bedID, patientID); err != nil {
+		return 0, bed, err
+	}
+	reservationID := adm.ReservationID
+	if reservationID != 0 {
+		err = claimReservationByID(tx, bed, patientID, reservationID)
+	} else {
+		reservationID, err = claimReservation(tx, bed, patientID, admissionDate, "")
+	}
+	if err != nil {
+		return 0, bed, err
+	}
+
+	admissionID, err := openAdmission(tx, patientID, bed.HospitalID, admissionDate, adm)
+	if err != nil {
+		return 0, bed, err
+	}
+	if err := closeEDVisitOnAdmission(tx, patientID, admissionID); err != nil {
+		return 0, bed, err
+	}
+	assignmentID, err := insertAssignment(tx, bedID, patientID, admissionDate, admissionID)
+	if err != nil {
+		return 0, bed, err
+	}
+	if err := convertReservation(tx, reservationID, assignmentID); err != nil {
+		return 0, bed, err
+	}
+	if err := recordBedStatus(tx, bedID, bed.Status, "occupied", m.reasonOr("Patient admitted"), m.ActorID); err != nil {
+		return 0, bed, err
+	}
+	err = recordMovement(tx, "admit", patientID, bedSide{}, bedSide{bedID, assignmentID}, admissionDate, m)
+	if err != nil {
+		return 0, bed, err
+	}
+	return assignmentID, bed, adjustBedsCount(tx, bed.HospitalID, bed.BedType, 0, 1)
+}
+
+// currentAssignment returns a patient's active bed assignment, locked for update
+func currentAssignment(tx *sql.Tx, patientID int) (activeAssignment, error) {
+	return loadActiveAssignment(tx, "ba.PatientID = ?", patientID)
+}
+
+// assignmentByID returns an assignment if it is still active
+func assignmentByID(tx *sql.Tx, assignmentID int) (activeAssignment, error) {
+	return loadActiveAssignment(tx, "ba.AssignmentID = ?", assignmentID)
+}
+
+func loadActiveAssignment(tx *sql.Tx, condition string, arg interface{}) (activeAssignment, error) {
+	var a activeAssignment
+	err := tx.QueryRow(`
+		SELECT ba.AssignmentID, COALESCE(ba.AdmissionID, 0), ba.PatientID, DATE_FORMAT(ba.AdmissionDate, '%Y-%m-%d'),
+		       COALESCE(DATE_FORMAT(ba.PlannedDischargeDate, '%Y-%m-%d'), ''), bi.BedID, bi.HospitalID, bi.BedType
+		FROM BedAssignments ba
+		JOIN BedInventory bi ON ba.BedID = bi.BedID
+		WHERE ba.DischargeDate IS NULL AND `+condition+`
+		FOR UPDATE`, arg).Scan(
+		&a.AssignmentID, &a.AdmissionID, &a.PatientID, &a.AdmissionDate, &a.PlannedDischargeDate,
+		&a.Bed.BedID, &a.Bed.HospitalID, &a.Bed.BedType)
+	if err == sql.ErrNoRows {
+		return a, errNoActiveAssignment
+	}
+	return a, err
+}
+
+// vacateBed discharges a patient: it closes their active assignment and
+// admission on dischargeDate and sends the bed for cleaning
+func vacateBed(tx *sql.Tx, a activeAssignment, dischargeDate string, m bedMove) error {
+	if err := closeAssignment(tx, a, dischargeDate, m.reasonOr("Patient discharged"), m.ActorID); err != nil {
+		return err
+	}
+	if err := closeAdmission(tx, a.AdmissionID, dischargeDate); err != nil {
+		return err
+	}
+	return recordMovement(tx, "discharge", a.PatientID, bedSide{a.Bed.BedID, int64(a.AssignmentID)}, bedSide{}, dischargeDate, m)
+}
+
+// closeAssignment ends an active assignment on date and sends its bed for cleaning
+func closeAssignment(tx *sql.Tx, a activeAssignment, date, reason string, changedBy int) error {
+	if date < a.AdmissionDate {
+		return errDischargeBeforeAdmit
+	}
+	_, err := tx.Exec("UPDATE BedAssignments SET DischargeDate = ? WHERE AssignmentID = ?", date, a.AssignmentID)
+	if err != nil {
+		return err
+	}
+	if err := recordBedStatus(tx, a.Bed.BedID, "occupied", "cleaning", reason, changedBy); err != nil {
+		return err
+	}
+	return adjustBedsCount(tx, a.Bed.HospitalID, a.Bed.BedType, 0, -1)
+}
+
+// moveBed transfers a patient's active assignment to another free bed in the
+// same hospital. The new assignment keeps the original admission date.
+func moveBed(tx *sql.Tx, a activeAssignment, newBedID int, transferDate string, m bedMove) (int64, bedInfo, error) {
+	newBed, err := availableBed(tx, newBedID)
+	if err != nil {
+		return 0, newBed, err
+	}
+	if newBed.HospitalID != a.Bed.HospitalID {
+		return 0, newBed, errCrossHospitalMove
+	}
+	if err := checkWardGender(tx, newBedID, a.PatientID); err != nil {
+		return 0, newBed, err
+	}
+	reservationID, err := claimReservation(tx, newBed, a.PatientID, transferDate, a.PlannedDischargeDate)
+	if err != nil {
+		return 0, newBed, err
+	}
+	if err := closeAssignment(tx, a, transferDate, m.reasonOr("Patient transferred"), m.ActorID); err != nil {
+		return 0, newBed, err
+	}
+
+	assignmentID, err := insertAssignment(tx, newBedID, a.PatientID, a.AdmissionDate, a.AdmissionID)
+	if err != nil {
+		return 0, newBed, err
+	}
+	if err := keepPlannedDischarge(tx, assignmentID, a); err != nil {
+		return 0, newBed, err
+	}
+	if err := convertReservation(tx, reservationID, assignmentID); err != nil {
+		return 0, newBed, err
+	}
+	if err := recordBedStatus(tx, newBedID, newBed.Status, "occupied", m.reasonOr("Patient transferred in"), m.ActorID); err != nil {
+		return 0, newBed, err
+	}
+	err = recordMovement(tx, "transfer", a.PatientID,
+		bedSide{a.Bed.BedID, int64(a.AssignmentID)}, bedSide{newBedID, assignmentID}, transferDate, m)
+	if err != nil {
+		return 0, newBed, err
+	}
+	return assignmentID, newBed, adjustBedsCount(tx, newBed.HospitalID, newBed.BedType, 0, 1)
+}
+
+// bedCountDrift is a BedsCount row that disagrees with the inventory and assignments
+type bedCountDrift struct {
+	HospitalID       int    `json:"hospitalId"`
+	BedType          string `json:"bedType"`
+	RecordedTotal    int    `json:"recordedTotal"`
+	RecordedOccupied int    `json:"recordedOccupied"`
+	RecordedVacant   int    `json:"recordedVacant"`
+	ActualTotal      int    `json:"actualTotal"`
+	ActualOccupied   int    `json:"actualOccupied"`
+}
+
+// findBedCountDrift compares BedsCount with counts derived from BedInventory
+// and active BedAssignments, including rows missing on either side
+func findBedCountDrift(q queryer) ([]bedCountDrift, error) {
+	rows, err := q.Query(`
+		SELECT k.HospitalID, k.BedType,
+		       COALESCE(bc.TotalBeds, 0), COALESCE(bc.OccupiedBeds, 0), COALESCE(bc.VacantBeds, 0),
+		       COALESCE(a.Total, 0), COALESCE(a.Occupied, 0)
+		FROM (
+			SELECT HospitalID, BedType FROM BedInventory
+			UNION
+			SELECT HospitalID, BedType FROM BedsCount
+		) k
+		LEFT JOIN BedsCount bc ON bc.HospitalID = k.HospitalID AND bc.BedType = k.BedType
+		LEFT JOIN (
+			SELECT bi.HospitalID, bi.BedType, COUNT(*) AS Total, COUNT(ba.AssignmentID) AS Occupied
+			FROM BedInventory bi
+			LEFT JOIN BedAssignments ba ON ba.BedID = bi.BedID AND ba.DischargeDate IS NULL
+			GROUP BY bi.HospitalID, bi.BedType
+		) a ON a.HospitalID = k.HospitalID AND a.BedType = k.BedType
+		ORDER BY k.HospitalID, k.BedType
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drift := []bedCountDrift{}
+	for rows.Next() {
+		var d bedCountDrift
+		if err := rows.Scan(&d.HospitalID, &d.BedType, &d.RecordedTotal, &d.RecordedOccupied,
+			&d.RecordedVacant, &d.ActualTotal, &d.ActualOccupied); err != nil {
+			return nil, err
+		}
+		if d.RecordedTotal != d.ActualTotal || d.RecordedOccupied != d.ActualOccupied ||
+			d.RecordedVacant != d.ActualTotal-d.ActualOccupied {
+			drift = append(drift, d)
+		}
+	}
+	return drift, rows.Err()
+}
+
+// repairBedCounts rewrites drifted BedsCount rows from the derived counts
+func repairBedCounts(tx *sql.Tx, drift []bedCountDrift) error {
+	for _, d := range drift {
+		_, err := tx.Exec(`
+			INSERT INTO BedsCount (HospitalID, BedType, TotalBeds, OccupiedBeds, VacantBeds)
+			VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				TotalBeds = VALUES(TotalBeds),
+				OccupiedBeds = VALUES(OccupiedBeds),
+				VacantBeds = VALUES(VacantBeds)
+		`, d.HospitalID, d.BedType, d.ActualTotal, d.ActualOccupied, d.ActualTotal-d.ActualOccupied)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The occupancy tests run against a MySQL database with hospital_db.sql
+// loaded, named by TEST_MYSQL_DSN, e.g.
+//
+//	TEST_MYSQL_DSN='root:secret@tcp(localhost:3306)/hospital_db?parseTime=True&loc=Local'
+//
+// They are skipped when it isn't set; CI sets it for a MySQL started with
+// lower_case_table_names=1 (see .github/workflows/backend-tests.yml). Each
+// test creates its own hospital, bed types, beds and patients, and only
+// checks counts for that hospital, so the database doesn't need to be empty.
+
+// openTestDB points database.DB at the test database
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set; skipping test that needs MySQL")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	db.SetMaxOpenConns(20)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+}
+
+// occupancyFixture is a hospital with beds of two types and some patients
+type occupancyFixture struct {
+	hospitalID int
+	bedTypes   []string
+	beds       []int
+	patients   []int
+}
+
+// newOccupancyFixture creates a hospital with the given number of beds,
+// alternating between two new bed types, and patients
+func newOccupancyFixture(t *testing.T, beds, patients int) occupancyFixture {
+	t.Helper()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	f := occupancyFixture{bedTypes: []string{"test-a-" + suffix, "test-b-" + suffix}}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		t.Fatalf("starting fixture transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO Hospital (Address, City, State, Country)
+		VALUES ('1 Test Road', 'Test City', 'Test State', 'Test Country')
+	`)
+	if err != nil {
+		t.Fatalf("creating hospital: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		t.Fatalf("creating hospital: %v", err)
+	}
+	f.hospitalID = int(id)
+
+	for _, bedType := range f.bedTypes {
+		if _, err := tx.Exec("INSERT INTO BedTypes (BedType, Description) VALUES (?, 'Occupancy test')", bedType); err != nil {
+			t.Fatalf("creating bed type: %v", err)
+		}
+	}
+	for i := 0; i < beds; i++ {
+		bedID, err := addBed(tx, f.hospitalID, f.bedTypes[i%len(f.bedTypes)])
+		if err != nil {
+			t.Fatalf("creating bed: %v", err)
+		}
+		f.beds = append(f.beds, int(bedID))
+	}
+	for i := 0; i < patients; i++ {
+		result, err := tx.Exec(`
+			INSERT INTO Patients (FullName, ContactNumber, Email) VALUES (?, '0000000000', ?)
+		`, fmt.Sprintf("Occupancy Test %d", i), fmt.Sprintf("occupancy-%s-%d@test.invalid", suffix, i))
+		if err != nil {
+			t.Fatalf("creating patient: %v", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			t.Fatalf("creating patient: %v", err)
+		}
+		f.patients = append(f.patients, int(id))
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("committing fixture: %v", err)
+	}
+	return f
+}
+
+// drift returns the fixture hospital's BedsCount rows that disagree with the
+// inventory and assignments
+func (f occupancyFixture) drift(t *testing.T) []bedCountDrift {
+	t.Helper()
+	all, err := findBedCountDrift(database.DB)
+	if err != nil {
+		t.Fatalf("checking bed counts: %v", err)
+	}
+	var drift []bedCountDrift
+	for _, d := range all {
+		if d.HospitalID == f.hospitalID {
+			drift = append(drift, d)
+		}
+	}
+	return drift
+}
+
+// occupiedCount returns the recorded occupied beds in the fixture hospital
+func (f occupancyFixture) occupiedCount(t *testing.T) int {
+	t.Helper()
+	var occupied int
+	err := database.DB.QueryRow(
+		"SELECT COALESCE(SUM(OccupiedBeds), 0) FROM BedsCount WHERE HospitalID = ?",
+		f.hospitalID).Scan(&occupied)
+	if err != nil {
+		t.Fatalf("reading bed count: %v", err)
+	}
+	return occupied
+}
+
+// errSkipStep marks a random step that had nothing to do
+var errSkipStep = errors.New("nothing to do")
+
+// TestOccupancyKeepsBedCounts runs a random sequence of admissions,
+// transfers, swaps, discharges and cleanings and checks after every step that
+// BedsCount still matches the inventory and active assignments. Set
+// TEST_OCCUPANCY_SEED to replay a sequence.
+func TestOccupancyKeepsBedCounts(t *testing.T) {
+	openTestDB(t)
+	f := newOccupancyFixture(t, 8, 10)
+
+	seed := time.Now().UnixNano()
+	if s := os.Getenv("TEST_OCCUPANCY_SEED"); s != "" {
+		var err error
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			t.Fatalf("invalid TEST_OCCUPANCY_SEED: %v", err)
+		}
+	}
+	t.Logf("seed %d", seed)
+	rng := rand.New(rand.NewSource(seed))
+
+	today := time.Now().Format("2006-01-02")
+	m := bedMove{Reason: "Occupancy test"}
+	pick := func(ids []int) int { return ids[rng.Intn(len(ids))] }
+
+	steps := map[string]func(tx *sql.Tx) error{
+		"occupy": func(tx *sql.Tx) error {
+			_, _, err := occupyBed(tx, pick(f.beds), pick(f.patients), today, m, admissionDetails{})
+			return err
+		},
+		"move": func(tx *sql.Tx) error {
+			a, err := currentAssignment(tx, pick(f.patients))
+			if err != nil {
+				return err
+			}
+			_, _, err = moveBed(tx, a, pick(f.beds), today, m)
+			return err
+		},
+		"swap": func(tx *sql.Tx) error {
+			first, second := pick(f.patients), pick(f.patients)
+			if first == second {
+				return errSkipStep
+			}
+			if first > second {
+				first, second = second, first
+			}
+			a, err := currentAssignment(tx, first)
+			if err != nil {
+				return err
+			}
+			b, err := currentAssignment(tx, second)
+			if err != nil {
+				return err
+			}
+			_, _, err = swapBeds(tx, a, b, today, m)
+			return err
+		},
+		"vacate": func(tx *sql.Tx) error {
+			a, err := currentAssignment(tx, pick(f.patients))
+			if err != nil {
+				return err
+			}
+			return vacateBed(tx, a, today, m)
+		},
+		"clean": func(tx *sql.Tx) error {
+			bedID := pick(f.beds)
+			var status string
+			if err := tx.QueryRow("SELECT Status FROM BedInventory WHERE BedID = ? FOR UPDATE", bedID).Scan(&status); err != nil {
+				return err
+			}
+			if status != "cleaning" {
+				return errSkipStep
+			}
+			return recordBedStatus(tx, bedID, "cleaning", "available", "Occupancy test", 0)
+		},
+	}
+	names := []string{"occupy", "occupy", "move", "swap", "vacate", "clean", "clean"}
+
+	applied := map[string]int{}
+	for i := 0; i < 300; i++ {
+		name := names[rng.Intn(len(names))]
+		err := withTx(steps[name])
+		switch err {
+		case nil:
+			applied[name]++
+		case errSkipStep, errBedUnavailable, errPatientHasBed, errNoActiveAssignment:
+		default:
+			t.Fatalf("step %d (%s): %v", i, name, err)
+		}
+		if drift := f.drift(t); len(drift) > 0 {
+			t.Fatalf("step %d (%s) left bed counts drifted: %+v", i, name, drift)
+		}
+	}
+	t.Logf("applied steps: %v", applied)
+	if applied["occupy"] == 0 || applied["vacate"] == 0 {
+		t.Fatalf("sequence never admitted and discharged a patient: %v", applied)
+	}
+
+	// Drift injected by hand is found and repaired
+	_, err := database.DB.Exec(`
+		UPDATE BedsCount SET OccupiedBeds = OccupiedBeds + 3, VacantBeds = VacantBeds - 1
+		WHERE HospitalID = ? AND BedType = ?
+	`, f.hospitalID, f.bedTypes[0])
+	if err != nil {
+		t.Fatalf("injecting drift: %v", err)
+	}
+	if _, err := database.DB.Exec("DELETE FROM BedsCount WHERE HospitalID = ? AND BedType = ?", f.hospitalID, f.bedTypes[1]); err != nil {
+		t.Fatalf("injecting drift: %v", err)
+	}
+	drift := f.drift(t)
+	if len(drift) != 2 {
+		t.Fatalf("expected drift in both bed types, got %+v", drift)
+	}
+	if err := withTx(func(tx *sql.Tx) error { return repairBedCounts(tx, drift) }); err != nil {
+		t.Fatalf("repairing bed counts: %v", err)
+	}
+	if drift := f.drift(t); len(drift) > 0 {
+		t.Fatalf("bed counts still drifted after repair: %+v", drift)
+	}
+}
+
+// raceOccupancy runs occupyBed for each bed and patient pair at once, each in
+// its own transaction, and returns the error from each
+func raceOccupancy(t *testing.T, beds, patients []int) []error {
+	t.Helper()
+	today := time.Now().Format("2006-01-02")
+	errs := make([]error, len(beds))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range beds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tx, err := database.DB.Begin()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer tx.Rollback()
+
+			<-start
+			if _, _, err := occupyBed(tx, beds[i], patients[i], today, bedMove{Reason: "Race test"}, admissionDetails{}); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = tx.Commit()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// expectOneWinner checks that exactly one of errs is nil and the rest are want
+func expectOneWinner(t *testing.T, errs []error, want error) {
+	t.Helper()
+	won := 0
+	for i, err := range errs {
+		switch err {
+		case nil:
+			won++
+		case want:
+		default:
+			t.Errorf("attempt %d: got %v, want %v", i, err, want)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d attempts committed, want exactly 1", won)
+	}
+}
+
+// TestOccupyBedRace admits several patients into the same bed at once, and
+// one patient into several beds at once; only one admission may win each race
+func TestOccupyBedRace(t *testing.T) {
+	openTestDB(t)
+	const racers = 8
+
+	t.Run("same bed", func(t *testing.T) {
+		f := newOccupancyFixture(t, 1, racers)
+		before := f.occupiedCount(t)
+
+		beds := make([]int, racers)
+		for i := range beds {
+			beds[i] = f.beds[0]
+		}
+		expectOneWinner(t, raceOccupancy(t, beds, f.patients), errBedUnavailable)
+
+		var active int
+		err := database.DB.QueryRow(
+			"SELECT COUNT(*) FROM BedAssignments WHERE BedID = ? AND DischargeDate IS NULL",
+			f.beds[0]).Scan(&active)
+		if err != nil {
+			t.Fatalf("counting assignments: %v", err)
+		}
+		if active != 1 {
+			t.Errorf("bed has %d active assignments, want 1", active)
+		}
+		if got := f.occupiedCount(t) - before; got != 1 {
+			t.Errorf("occupied beds rose by %d, want 1", got)
+		}
+		if drift := f.drift(t); len(drift) > 0 {
+			t.Errorf("bed counts drifted: %+v", drift)
+		}
+	})
+
+	t.Run("same patient", func(t *testing.T) {
+		f := newOccupancyFixture(t, racers, 1)
+		before := f.occupiedCount(t)
+
+		patients := make([]int, racers)
+		for i := range patients {
+			patients[i] = f.patients[0]
+		}
+		expectOneWinner(t, raceOccupancy(t, f.beds, patients), errPatientHasBed)
+
+		var active int
+		err := database.DB.QueryRow(
+			"SELECT COUNT(*) FROM BedAssignments WHERE PatientID = ? AND DischargeDate IS NULL",
+			f.patients[0]).Scan(&active)
+		if err != nil {
+			t.Fatalf("counting assignments: %v", err)
+		}
+		if active != 1 {
+			t.Errorf("patient has %d active assignments, want 1", active)
+		}
+		if got := f.occupiedCount(t) - before; got != 1 {
+			t.Errorf("occupied beds rose by %d, want 1", got)
+		}
+		if drift := f.drift(t); len(drift) > 0 {
+			t.Errorf("bed counts drifted: %+v", drift)
+		}
+	})
+}
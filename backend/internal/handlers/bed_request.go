@@ -0,0 +1,748 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Bed requests queue patients who need a bed of a given type when none is
+// free. The allocator suggests the best free bed for a request and staff
+// confirm it or pick another. Pending requests are also filled automatically,
+// most urgent first, whenever a bed becomes available.
+
+// bedRequestInterval is how often the queue is swept for beds that have freed up
+const bedRequestInterval = 5 * time.Minute
+
+// bedUrgencyOrder sorts BedRequests rows r from most to least urgent
+const bedUrgencyOrder = "FIELD(r.Urgency, 'emergency', 'urgent', 'routine')"
+
+var (
+	errBedRequestNotFound = errors.New("bed request not found")
+	errBedRequestClosed   = errors.New("bed request is no longer pending")
+	errNoBedSuggestion    = errors.New("no suitable bed is free")
+	errBedAlreadyQueued   = errors.New("patient already has a pending bed request")
+)
+
+func validBedUrgency(urgency string) bool {
+	return urgency == "routine" || urgency == "urgent" || urgency == "emergency"
+}
+
+// bedRequest is what the allocator needs to know about a queued request
+type bedRequest struct {
+	RequestID       int
+	PatientID       int
+	HospitalID      int
+	BedType         string
+	Isolation       bool
+	Gender          string
+	PreferredWardID int
+}
+
+// bedSuggestion is a free bed proposed for a request
+type bedSuggestion struct {
+	BedID      int    `json:"bedId"`
+	BedType    string `json:"bedType"`
+	WardID     int    `json:"wardId"`
+	WardName   string `json:"wardName"`
+	RoomNumber string `json:"roomNumber"`
+	Label      string `json:"label"`
+	Isolation  bool   `json:"isolation"`
+	Location   string `json:"location"`
+}
+
+// suggestBed picks the best free bed for a request. The bed must be in the
+// requested hospital and of the requested type, in a ward that accepts the
+// patient's gender, and in an isolation room if isolation is needed. Beds in
+// the preferred ward come first, and isolation rooms are kept for patients
+// who need them.
+func suggestBed(q queryer, req bedRequest) (bedSuggestion, error) {
+	query := `
+		SELECT bi.BedID, bi.BedType, COALESCE(rm.Isolation, FALSE), ` + bedWardColumns + `
+		FROM BedInventory bi
+		` + bedWardJoins + `
+		WHERE bi.HospitalID = ? AND bi.BedType = ? AND bi.Status = 'available'
+		  AND NOT EXISTS (
+			SELECT 1 FROM BedAssignments ba WHERE ba.BedID = bi.BedID AND ba.DischargeDate IS NULL)
+		  AND ` + bedNotReserved + `
+		  AND (COALESCE(wd.GenderRestriction, 'any') = 'any' OR wd.GenderRestriction = ?)
+	`
+	args := []interface{}{req.HospitalID, req.BedType, req.Gender}
+	if req.Isolation {
+		query += " AND rm.Isolation = TRUE"
+	}
+	query += " ORDER BY (wd.WardID <=> ?) DESC, COALESCE(rm.Isolation, FALSE), " + bedWardOrder + ", bi.BedID LIMIT 1"
+	args = append(args, req.PreferredWardID)
+
+	var s bedSuggestion
+	var placement bedPlacement
+	dest := append([]interface{}{&s.BedID, &s.BedType, &s.Isolation}, placement.dest()...)
+	err := q.QueryRow(query, args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return s, errNoBedSuggestion
+	}
+	if err != nil {
+		return s, err
+	}
+	s.WardID, s.WardName, s.RoomNumber, s.Label = placement.WardID, placement.WardName, placement.RoomNumber, placement.Label
+	s.Location = bedLocation(s.BedID, placement.WardName, placement.RoomNumber, placement.Label)
+	return s, nil
+}
+
+// loadBedRequest reads a request for the allocator
+func loadBedRequest(q queryer, requestID int) (bedRequest, string, error) {
+	req := bedRequest{RequestID: requestID}
+	var status string
+	err := q.QueryRow(`
+		SELECT PatientID, HospitalID, BedType, Isolation, COALESCE(Gender, ''), COALESCE(PreferredWardID, 0), Status
+		FROM BedRequests WHERE RequestID = ?
+	`, requestID).Scan(&req.PatientID, &req.HospitalID, &req.BedType, &req.Isolation, &req.Gender, &req.PreferredWardID, &status)
+	if err == sql.ErrNoRows {
+		return req, "", errBedRequestNotFound
+	}
+	return req, status, err
+}
+
+// createBedRequest queues a patient for a bed. The gender used for ward
+// restrictions defaults to the one on the patient's record. The patient row is
+// locked first, as in occupyBed, so two concurrent requests for the same
+// patient can't both pass the pending check.
+func createBedRequest(tx *sql.Tx, req bedRequest, urgency string, requestedBy int, notes string) (int64, error) {
+	var gender sql.NullString
+	err := tx.QueryRow("SELECT Gender FROM Patients WHERE PatientID = ? FOR UPDATE", req.PatientID).Scan(&gender)
+	if err == sql.ErrNoRows {
+		return 0, errPatientNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if req.Gender == "" {
+		req.Gender = gender.String
+	}
+	req.Gender = strings.ToLower(strings.TrimSpace(req.Gender))
+
+	var pending bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM BedRequests WHERE PatientID = ? AND Status = 'pending')",
+		req.PatientID).Scan(&pending)
+	if err != nil {
+		return 0, err
+	}
+	if pending {
+		return 0, errBedAlreadyQueued
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO BedRequests (PatientID, HospitalID, BedType, Urgency, Isolation, Gender, PreferredWardID, RequestedBy, Notes)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), ?)
+	`, req.PatientID, req.HospitalID, req.BedType, urgency, req.Isolation, req.Gender, req.PreferredWardID, requestedBy, notes)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// allocateBedRequest admits a pending request's patient to a bed and marks the
+// request fulfilled. The request is locked first, then occupyBed locks the
+// patient and the bed.
+func allocateBedRequest(tx *sql.Tx, requestID, bedID int, admissionDate string, employeeID int) (int64, error) {
+	var patientID, requestedBy int
+	var status string
+	err := tx.QueryRow(
+		"SELECT PatientID, COALESCE(RequestedBy, 0), Status FROM BedRequests WHERE RequestID = ? FOR UPDATE",
+		requestID).Scan(&patientID, &requestedBy, &status)
+	if err == sql.ErrNoRows {
+		return 0, errBedRequestNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != "pending" {
+		return 0, errBedRequestClosed
+	}
+
+	assignmentID, _, err := occupyBed(tx, bedID, patientID, admissionDate, bedMove{
+		Reason:  fmt.Sprintf("Admitted from bed request #%d", requestID),
+		ActorID: employeeID,
+	}, admissionDetails{AdmittingDoctorID: requestedBy})
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		UPDATE BedRequests
+		SET Status = 'fulfilled', BedID = ?, AssignmentID = ?, FulfilledBy = NULLIF(?, 0), FulfilledAt = NOW()
+		WHERE RequestID = ?
+	`, bedID, assignmentID, employeeID, requestID)
+	return assignmentID, err
+}
+
+// cancelBedRequest closes a pending request
+func cancelBedRequest(tx *sql.Tx, requestID int, reason string) error {
+	result, err := tx.Exec(`
+		UPDATE BedRequests SET Status = 'cancelled', Notes = CONCAT_WS('\n', Notes, ?)
+		WHERE RequestID = ? AND Status = 'pending'
+	`, reason, requestID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM BedRequests WHERE RequestID = ?)", requestID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return errBedRequestNotFound
+		}
+		return errBedRequestClosed
+	}
+	return reopenEDVisitForRequest(tx, requestID)
+}
+
+// bedRequestErrorStatus extends bedErrorStatus with the queue errors
+func bedRequestErrorStatus(err error) (int, string) {
+	switch err {
+	case errBedRequestNotFound:
+		return http.StatusNotFound, err.Error()
+	case errBedRequestClosed, errNoBedSuggestion, errBedAlreadyQueued:
+		return http.StatusConflict, err.Error()
+	}
+	return bedErrorStatus(err)
+}
+
+// fillBedFromQueue gives a bed that has just become available to the most
+// urgent pending request it suits. Requests whose patient has been given a
+// bed some other way are cancelled along the way. It runs after the change
+// that freed the bed has committed, in its own transaction.
+func fillBedFromQueue(bedID int) {
+	for attempt := 0; attempt < 5; attempt++ {
+		var requestID int
+		err := database.DB.QueryRow(`
+			SELECT r.RequestID
+			FROM BedRequests r
+			JOIN BedInventory bi ON bi.BedID = ?
+			`+bedWardJoins+`
+			WHERE r.Status = 'pending' AND r.HospitalID = bi.HospitalID AND r.BedType = bi.BedType
+			  AND (COALESCE(wd.GenderRestriction, 'any') = 'any' OR wd.GenderRestriction = r.Gender)
+			  AND (r.Isolation = FALSE OR rm.Isolation = TRUE)
+			ORDER BY `+bedUrgencyOrder+`, r.Isolation DESC, (r.PreferredWardID <=> wd.WardID) DESC, r.CreatedAt
+			LIMIT 1
+		`, bedID).Scan(&requestID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Printf("Error finding bed request for bed %d: %v", bedID, err)
+			return
+		}
+
+		var assignmentID int64
+		err = withTx(func(tx *sql.Tx) error {
+			var err error
+			assignmentID, err = allocateBedRequest(tx, requestID, bedID, time.Now().Format("2006-01-02"), 0)
+			return err
+		})
+		switch err {
+		case nil:
+			log.Printf("Bed request %d filled automatically with bed %d (assignment %d)", requestID, bedID, assignmentID)
+			return
+		case errPatientHasBed:
+			if err := withTx(func(tx *sql.Tx) error {
+				return cancelBedRequest(tx, requestID, "Cancelled: patient already has a bed")
+			}); err != nil {
+				log.Printf("Error cancelling stale bed request %d: %v", requestID, err)
+				return
+			}
+		case errBedRequestClosed:
+			// Taken by someone else in the meantime; look again
+		default:
+			// The bed is no longer free, or a database error
+			if status, _ := bedRequestErrorStatus(err); status == http.StatusInternalServerError {
+				log.Printf("Error filling bed request %d: %v", requestID, err)
+			}
+			return
+		}
+	}
+}
+
+// sweepBedRequests fills pending requests, most urgent first, from any beds
+// that are free. It catches beds freed by paths that don't call
+// fillBedFromQueue, such as new beds added to the inventory.
+func sweepBedRequests() error {
+	rows, err := database.DB.Query(`
+		SELECT r.RequestID FROM BedRequests r
+		WHERE r.Status = 'pending'
+		ORDER BY ` + bedUrgencyOrder + `, r.CreatedAt
+	`)
+	if err != nil {
+		return err
+	}
+	var pending []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		pending = append(pending, id)
+	}
+	rows.Close()
+
+	for _, id := range pending {
+		req, _, err := loadBedRequest(database.DB, id)
+		if err != nil {
+			return err
+		}
+		suggestion, err := suggestBed(database.DB, req)
+		if err == errNoBedSuggestion {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = withTx(func(tx *sql.Tx) error {
+			_, err := allocateBedRequest(tx, id, suggestion.BedID, time.Now().Format("2006-01-02"), 0)
+			return err
+		})
+		if err == nil {
+			log.Printf("Bed request %d filled automatically with bed %d", id, suggestion.BedID)
+		} else if status, _ := bedRequestErrorStatus(err); status == http.StatusInternalServerError {
+			log.Printf("Error filling bed request %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// StartBedRequestQueue periodically fills queued bed requests from free beds
+func StartBedRequestQueue() {
+	go func() {
+		ticker := time.NewTicker(bedRequestInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := sweepBedRequests(); err != nil {
+				log.Printf("Error sweeping bed requests: %v", err)
+			}
+		}
+	}()
+	log.Println("Bed request queue started")
+}
+
+// doctorForEmployee returns the DoctorID and hospital of an employee, with a
+// DoctorID of 0 for employees who aren't doctors
+func doctorForEmployee(employeeID int) (int, int, error) {
+	var doctorID, hospitalID int
+	err := database.DB.QueryRow(`
+		SELECT COALESCE(de.DoctorID, 0), e.HospitalID
+		FROM employees e
+		LEFT JOIN doctoremployee de ON de.EmployeeID = e.EmployeeID
+		WHERE e.EmployeeID = ?
+	`, employeeID).Scan(&doctorID, &hospitalID)
+	return doctorID, hospitalID, err
+}
+
+// doctorWorksAt reports whether a doctor is employed at a hospital
+func doctorWorksAt(q queryer, doctorID, hospitalID int) (bool, error) {
+	var works bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM doctoremployee de
+			JOIN employees e ON de.EmployeeID = e.EmployeeID
+			WHERE de.DoctorID = ? AND e.HospitalID = ?
+		)
+	`, doctorID, hospitalID).Scan(&works)
+	return works, err
+}
+
+// CreateBedRequest queues a patient for a bed and returns the allocator's
+// current suggestion, if any bed is free
+func CreateBedRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var body struct {
+		PatientID       int    `json:"patientId"`
+		BedType         string `json:"bedType"`
+		Urgency         string `json:"urgency"`
+		Isolation       bool   `json:"isolation"`
+		Gender          string `json:"gender"`
+		PreferredWardID int    `json:"preferredWardId"`
+		EmployeeID      int    `json:"employeeId"`
+		Notes           string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.PatientID == 0 || body.BedType == "" || body.EmployeeID == 0 {
+		sendJSONError(w, "patientId, bedType and employeeId are required", http.StatusBadRequest)
+		return
+	}
+	if body.Urgency == "" {
+		body.Urgency = "routine"
+	}
+	if !validBedUrgency(body.Urgency) {
+		sendJSONError(w, "urgency must be routine, urgent or emergency", http.StatusBadRequest)
+		return
+	}
+
+	doctorID, hospitalID, err := doctorForEmployee(body.EmployeeID)
+	if err == sql.ErrNoRows {
+		sendJSONError(w, "Employee not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching requesting employee: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if doctorID == 0 {
+		sendJSONError(w, "Only doctors can request beds", http.StatusForbidden)
+		return
+	}
+
+	req := bedRequest{
+		PatientID:       body.PatientID,
+		HospitalID:      hospitalID,
+		BedType:         body.BedType,
+		Isolation:       body.Isolation,
+		Gender:          body.Gender,
+		PreferredWardID: body.PreferredWardID,
+	}
+
+	var requestID int64
+	err = withTx(func(tx *sql.Tx) error {
+		var err error
+		requestID, err = createBedRequest(tx, req, body.Urgency, doctorID, body.Notes)
+		return err
+	})
+	if err != nil {
+		status, message := bedRequestErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error creating bed request: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	writeBedRequestCreated(w, int(requestID))
+}
+
+// queueForBed adds a bed request on behalf of AssignBed when the chosen bed
+// is taken
+func queueForBed(w http.ResponseWriter, employeeID int, req bedRequest, urgency, notes string) {
+	if urgency == "" {
+		urgency = "routine"
+	}
+	if !validBedUrgency(urgency) {
+		sendJSONError(w, "urgency must be routine, urgent or emergency", http.StatusBadRequest)
+		return
+	}
+	doctorID, _, err := doctorForEmployee(employeeID)
+	if err != nil {
+		log.Printf("Error fetching requesting employee: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var requestID int64
+	err = withTx(func(tx *sql.Tx) error {
+		var err error
+		requestID, err = createBedRequest(tx, req, urgency, doctorID, notes)
+		return err
+	})
+	if err != nil {
+		status, message := bedRequestErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error queueing bed request: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+	writeBedRequestCreated(w, int(requestID))
+}
+
+// writeBedRequestCreated responds to a newly queued request with the
+// allocator's suggestion for it
+func writeBedRequestCreated(w http.ResponseWriter, requestID int) {
+	response := map[string]interface{}{
+		"success":   true,
+		"requestId": requestID,
+		"status":    "pending",
+		"message":   "Bed request queued",
+	}
+	if req, _, err := loadBedRequest(database.DB, requestID); err == nil {
+		if suggestion, err := suggestBed(database.DB, req); err == nil {
+			response["suggestion"] = suggestion
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBedRequests lists the queue in priority order, with a suggested bed for
+// each pending request. Filters: status (default pending), hospitalId.
+func GetBedRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	query := `
+		SELECT r.RequestID, r.PatientID, p.FullName, r.HospitalID, r.BedType, r.Urgency, r.Isolation,
+		       COALESCE(r.Gender, ''), COALESCE(r.PreferredWardID, 0), COALESCE(d.FullName, ''),
+		       r.Status, COALESCE(r.BedID, 0), COALESCE(r.AssignmentID, 0), COALESCE(r.Notes, ''),
+		       DATE_FORMAT(r.CreatedAt, '%Y-%m-%d %H:%i')
+		FROM BedRequests r
+		JOIN Patients p ON r.PatientID = p.PatientID
+		LEFT JOIN Doctors d ON r.RequestedBy = d.DoctorID
+		WHERE 1=1
+	`
+	var args []interface{}
+	if status != "all" {
+		query += " AND r.Status = ?"
+		args = append(args, status)
+	}
+	if hospitalID := r.URL.Query().Get("hospitalId"); hospitalID != "" {
+		query += " AND r.HospitalID = ?"
+		args = append(args, hospitalID)
+	}
+	query += " ORDER BY r.Status <> 'pending', " + bedUrgencyOrder + ", r.CreatedAt"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying bed requests: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var requests []map[string]interface{}
+	var pending []bedRequest
+	position := 0
+	for rows.Next() {
+		var req bedRequest
+		var patientName, urgency, doctorName, reqStatus, notes, createdAt string
+		var bedID, assignmentID int
+		err := rows.Scan(&req.RequestID, &req.PatientID, &patientName, &req.HospitalID, &req.BedType, &urgency,
+			&req.Isolation, &req.Gender, &req.PreferredWardID, &doctorName, &reqStatus, &bedID, &assignmentID,
+			&notes, &createdAt)
+		if err != nil {
+			log.Printf("Error scanning bed request row: %v", err)
+			continue
+		}
+
+		item := map[string]interface{}{
+			"requestId":       req.RequestID,
+			"patientId":       req.PatientID,
+			"patientName":     patientName,
+			"hospitalId":      req.HospitalID,
+			"bedType":         req.BedType,
+			"urgency":         urgency,
+			"isolation":       req.Isolation,
+			"gender":          req.Gender,
+			"preferredWardId": req.PreferredWardID,
+			"requestedBy":     doctorName,
+			"status":          reqStatus,
+			"notes":           notes,
+			"createdAt":       createdAt,
+		}
+		if reqStatus == "pending" {
+			position++
+			item["position"] = position
+			pending = append(pending, req)
+		}
+		if bedID != 0 {
+			item["bedId"] = bedID
+			item["assignmentId"] = assignmentID
+		}
+		requests = append(requests, item)
+	}
+	rows.Close()
+
+	// Suggestions are worked out after the rows are closed so the queries
+	// don't hold a second connection per request
+	i := 0
+	for _, item := range requests {
+		if item["status"] != "pending" {
+			continue
+		}
+		if suggestion, err := suggestBed(database.DB, pending[i]); err == nil {
+			item["suggestion"] = suggestion
+		}
+		i++
+	}
+
+	json.NewEncoder(w).Encode(requests)
+}
+
+// GetBedRequestSuggestion returns the allocator's current choice for a request
+func GetBedRequestSuggestion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	requestID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	req, status, err := loadBedRequest(database.DB, requestID)
+	if err == nil && status != "pending" {
+		err = errBedRequestClosed
+	}
+	var suggestion bedSuggestion
+	if err == nil {
+		suggestion, err = suggestBed(database.DB, req)
+	}
+	if err != nil {
+		code, message := bedRequestErrorStatus(err)
+		if code == http.StatusInternalServerError {
+			log.Printf("Error suggesting bed: %v", err)
+		}
+		sendJSONError(w, message, code)
+		return
+	}
+
+	json.NewEncoder(w).Encode(suggestion)
+}
+
+// AllocateBedRequest confirms the suggested bed for a request, or assigns a
+// bed chosen by staff when bedId is given
+func AllocateBedRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	requestID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		BedID         int    `json:"bedId"`
+		EmployeeID    int    `json:"employeeId"`
+		AdmissionDate string `json:"admissionDate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.AdmissionDate == "" {
+		body.AdmissionDate = time.Now().Format("2006-01-02")
+	}
+
+	overridden := body.BedID != 0
+	if !overridden {
+		req, status, err := loadBedRequest(database.DB, requestID)
+		if err == nil && status != "pending" {
+			err = errBedRequestClosed
+		}
+		var suggestion bedSuggestion
+		if err == nil {
+			suggestion, err = suggestBed(database.DB, req)
+		}
+		if err != nil {
+			code, message := bedRequestErrorStatus(err)
+			if code == http.StatusInternalServerError {
+				log.Printf("Error suggesting bed: %v", err)
+			}
+			sendJSONError(w, message, code)
+			return
+		}
+		body.BedID = suggestion.BedID
+	}
+
+	var assignmentID int64
+	err = withTx(func(tx *sql.Tx) error {
+		var err error
+		assignmentID, err = allocateBedRequest(tx, requestID, body.BedID, body.AdmissionDate, body.EmployeeID)
+		return err
+	})
+	if err != nil {
+		code, message := bedRequestErrorStatus(err)
+		if code == http.StatusInternalServerError {
+			log.Printf("Error allocating bed request: %v", err)
+		}
+		sendJSONError(w, message, code)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"requestId":     requestID,
+		"bedId":         body.BedID,
+		"assignmentId":  assignmentID,
+		"admissionDate": body.AdmissionDate,
+		"overridden":    overridden,
+	})
+}
+
+// CancelBedRequest removes a pending request from the queue
+func CancelBedRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	requestID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	reason := "Cancelled"
+	if body.Reason != "" {
+		reason = fmt.Sprintf("Cancelled: %s", body.Reason)
+	}
+
+	err = withTx(func(tx *sql.Tx) error {
+		return cancelBedRequest(tx, requestID, reason)
+	})
+	if err != nil {
+		code, message := bedRequestErrorStatus(err)
+		if code == http.StatusInternalServerError {
+			log.Printf("Error cancelling bed request: %v", err)
+		}
+		sendJSONError(w, message, code)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"requestId": requestID,
+		"message":   "Bed request cancelled",
+	})
+}
@@ -0,0 +1,771 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Bed reservations hold a bed for a planned admission from StartDate for
+// ExpectedStayDays. While a reservation is active no other patient can be
+// admitted to the bed during that period, and availability searches leave it
+// out. On its start date the bed is moved to the reserved state. The
+// reservation converts to an assignment when the patient is admitted to the
+// bed, and is released automatically if they haven't arrived by ExpiresAt.
+
+// bedReservationInterval is how often reservations are expired and beds held
+const bedReservationInterval = 5 * time.Minute
+
+// reservationNoShowGrace is the default time after the start date at which an
+// unclaimed reservation expires
+const reservationNoShowGrace = 24 * time.Hour
+
+var (
+	errReservationNotFound = errors.New("reservation not found")
+	errReservationClosed   = errors.New("reservation is no longer active")
+	errBedBookedForPeriod  = errors.New("bed is already reserved or occupied during that period")
+)
+
+// bedNotReserved excludes beds with an active reservation in effect today or
+// starting later from a query on BedInventory bi. An admission has no end
+// date, so a bed reserved from next week isn't free for one today.
+const bedNotReserved = `NOT EXISTS (
+		SELECT 1 FROM BedReservations br
+		WHERE br.BedID = bi.BedID AND br.Status = 'active' AND br.EndDate > CURDATE())`
+
+// claimReservation decides whether a patient may take a bed given its
+// reservations. The patient's own active reservation on the bed is returned
+// so it can be converted. Another patient's reservation that is in effect on
+// the admission date or starts later makes the bed unavailable, unless the
+// stay has a planned discharge on or before the reservation starts. A
+// reserved status set by hand with no reservation behind it does too.
+func claimReservation(tx *sql.Tx, bed bedInfo, patientID int, admissionDate, plannedDischarge string) (int, error) {
+	var own int
+	err := tx.QueryRow(`
+		SELECT ReservationID FROM BedReservations
+		WHERE BedID = ? AND PatientID = ? AND Status = 'active'
+		ORDER BY StartDate LIMIT 1
+		FOR UPDATE
+	`, bed.BedID, patientID).Scan(&own)
+	if err == nil {
+		return own, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	var reserved bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM BedReservations
+			WHERE BedID = ? AND Status = 'active' AND EndDate > ?
+			  AND (NULLIF(?, '') IS NULL OR StartDate < ?)
+		)
+	`, bed.BedID, admissionDate, plannedDischarge, plannedDischarge).Scan(&reserved)
+	if err != nil {
+		return 0, err
+	}
+	if reserved {
+		return 0, errBedReserved
+	}
+	if bed.Status == "reserved" {
+		return 0, errBedUnavailable
+	}
+	return 0, nil
+}
+
+// claimReservationByID checks that a named reservation is still active and
+// holds this bed for this patient, and locks it for conversion
+func claimReservationByID(tx *sql.Tx, bed bedInfo, patientID, reservationID int) error {
+	var locked int
+	err := tx.QueryRow(`
+		SELECT ReservationID FROM BedReservations
+		WHERE ReservationID = ? AND BedID = ? AND PatientID = ? AND Status = 'active'
+		FOR UPDATE
+	`, reservationID, bed.BedID, patientID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return errReservationClosed
+	}
+	return err
+}
+
+// convertReservation marks a claimed reservation as converted to an assignment
+func convertReservation(tx *sql.Tx, reservationID int, assignmentID int64) error {
+	if reservationID == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+		UPDATE BedReservations SET Status = 'converted', AssignmentID = ?
+		WHERE ReservationID = ?
+	`, assignmentID, reservationID)
+	return err
+}
+
+// releaseReservedBed returns a reserved bed to available once no active
+// reservation is in effect for it
+func releaseReservedBed(tx *sql.Tx, bedID int, reason string) error {
+	var status string
+	err := tx.QueryRow("SELECT Status FROM BedInventory WHERE BedID = ? FOR UPDATE", bedID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != "reserved" {
+		return nil
+	}
+
+	var held bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM BedReservations
+			WHERE BedID = ? AND Status = 'active' AND StartDate <= CURDATE()
+		)
+	`, bedID).Scan(&held)
+	if err != nil || held {
+		return err
+	}
+	return recordBedStatus(tx, bedID, "reserved", "available", reason, 0)
+}
+
+// closeReservation ends an active reservation with the given status and
+// releases its bed, returning the bed's ID. The bed is locked before the
+// reservation, in the same order as occupyBed, so closing and admitting
+// can't deadlock.
+func closeReservation(tx *sql.Tx, reservationID int, status, reason string) (int, error) {
+	var bedID int
+	err := tx.QueryRow("SELECT BedID FROM BedReservations WHERE ReservationID = ?", reservationID).Scan(&bedID)
+	if err == sql.ErrNoRows {
+		return 0, errReservationNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	var locked int
+	if err := tx.QueryRow("SELECT BedID FROM BedInventory WHERE BedID = ? FOR UPDATE", bedID).Scan(&locked); err != nil {
+		return bedID, err
+	}
+
+	var current string
+	err = tx.QueryRow(
+		"SELECT Status FROM BedReservations WHERE ReservationID = ? FOR UPDATE",
+		reservationID).Scan(&current)
+	if err != nil {
+		return bedID, err
+	}
+	if current != "active" {
+		return bedID, errReservationClosed
+	}
+
+	_, err = tx.Exec("UPDATE BedReservations SET Status = ? WHERE ReservationID = ?", status, reservationID)
+	if err != nil {
+		return bedID, err
+	}
+	return bedID, releaseReservedBed(tx, bedID, reason)
+}
+
+// reservationErrorStatus extends bedErrorStatus with the reservation errors
+func reservationErrorStatus(err error) (int, string) {
+	switch err {
+	case errReservationNotFound:
+		return http.StatusNotFound, err.Error()
+	case errReservationClosed, errBedBookedForPeriod:
+		return http.StatusConflict, err.Error()
+	}
+	return bedErrorStatus(err)
+}
+
+// insertReservation reserves a locked bed for a patient from startDate to
+// endDate (exclusive). The period must not overlap another reservation of the
+// bed or of the patient, or a current stay that isn't planned to end before it
+// starts. A reservation starting today holds the bed straight away.
+func insertReservation(tx *sql.Tx, bedID int, bedStatus string, patientID int, startDate, endDate string,
+	stayDays int, expiresAt time.Time, notes string, employeeID int) (int64, error) {
+	var conflict bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM BedReservations
+			WHERE (BedID = ? OR PatientID = ?) AND Status = 'active'
+			  AND StartDate < ? AND EndDate > ?
+		) OR EXISTS (
+			SELECT 1 FROM BedAssignments
+			WHERE BedID = ? AND DischargeDate IS NULL
+			  AND (PlannedDischargeDate IS NULL OR PlannedDischargeDate > ?)
+		)
+	`, bedID, patientID, endDate, startDate, bedID, startDate).Scan(&conflict)
+	if err != nil {
+		return 0, err
+	}
+	if conflict {
+		return 0, errBedBookedForPeriod
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO BedReservations (BedID, PatientID, StartDate, ExpectedStayDays, ExpiresAt, Notes, CreatedBy)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, 0))
+	`, bedID, patientID, startDate, stayDays, expiresAt, notes, employeeID)
+	if err != nil {
+		return 0, err
+	}
+	reservationID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if startDate <= time.Now().Format("2006-01-02") && bedStatus == "available" {
+		var occupied bool
+		err := tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM BedAssignments WHERE BedID = ? AND DischargeDate IS NULL)",
+			bedID).Scan(&occupied)
+		if err == nil && !occupied {
+			err = recordBedStatus(tx, bedID, "available", "reserved",
+				fmt.Sprintf("Held for reservation #%d", reservationID), employeeID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return reservationID, nil
+}
+
+// processBedReservations expires reservations whose patient hasn't arrived
+// and moves beds whose reservation starts today to the reserved state
+func processBedReservations() error {
+	rows, err := database.DB.Query(
+		"SELECT ReservationID FROM BedReservations WHERE Status = 'active' AND ExpiresAt <= NOW()")
+	if err != nil {
+		return err
+	}
+	var expired []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		expired = append(expired, id)
+	}
+	rows.Close()
+
+	for _, id := range expired {
+		var bedID int
+		err := withTx(func(tx *sql.Tx) error {
+			var err error
+			bedID, err = closeReservation(tx, id, "expired", fmt.Sprintf("Reservation #%d expired", id))
+			return err
+		})
+		if err == errReservationClosed {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error expiring bed reservation %d: %v", id, err)
+			continue
+		}
+		log.Printf("Bed reservation %d expired without admission", id)
+		fillBedFromQueue(bedID)
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT br.ReservationID, br.BedID
+		FROM BedReservations br
+		JOIN BedInventory bi ON br.BedID = bi.BedID
+		WHERE br.Status = 'active' AND br.StartDate <= CURDATE() AND bi.Status = 'available'
+	`)
+	if err != nil {
+		return err
+	}
+	type hold struct{ reservationID, bedID int }
+	var holds []hold
+	for rows.Next() {
+		var h hold
+		if err := rows.Scan(&h.reservationID, &h.bedID); err != nil {
+			rows.Close()
+			return err
+		}
+		holds = append(holds, h)
+	}
+	rows.Close()
+
+	for _, h := range holds {
+		err := withTx(func(tx *sql.Tx) error {
+			bed, err := availableBed(tx, h.bedID)
+			if err != nil || bed.Status != "available" {
+				// Occupied or changed since the query; try again next run
+				return nil
+			}
+			return recordBedStatus(tx, h.bedID, "available", "reserved",
+				fmt.Sprintf("Held for reservation #%d", h.reservationID), 0)
+		})
+		if err != nil {
+			log.Printf("Error holding bed %d for reservation %d: %v", h.bedID, h.reservationID, err)
+		}
+	}
+	return nil
+}
+
+// withTx runs fn in a transaction, committing if it returns nil
+func withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// StartBedReservations runs a background loop that releases no-show
+// reservations and holds beds for reservations starting today
+func StartBedReservations() {
+	go func() {
+		ticker := time.NewTicker(bedReservationInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := processBedReservations(); err != nil {
+				log.Printf("Error processing bed reservations: %v", err)
+			}
+		}
+	}()
+	log.Println("Bed reservation worker started")
+}
+
+// parseReservationExpiry accepts a local date and time or an RFC 3339 timestamp
+func parseReservationExpiry(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// CreateBedReservation holds a bed for a patient's planned admission
+func CreateBedReservation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req struct {
+		BedID            int    `json:"bedId"`
+		PatientID        int    `json:"patientId"`
+		StartDate        string `json:"startDate"`
+		ExpectedStayDays int    `json:"expectedStayDays"`
+		ExpiresAt        string `json:"expiresAt"`
+		Notes            string `json:"notes"`
+		EmployeeID       int    `json:"employeeId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		bedSendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.BedID == 0 || req.PatientID == 0 || req.StartDate == "" {
+		bedSendJSONError(w, "bedId, patientId and startDate are required", http.StatusBadRequest)
+		return
+	}
+	if req.ExpectedStayDays < 1 {
+		bedSendJSONError(w, "expectedStayDays must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		bedSendJSONError(w, "Invalid startDate format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if req.StartDate < time.Now().Format("2006-01-02") {
+		bedSendJSONError(w, "startDate cannot be in the past", http.StatusBadRequest)
+		return
+	}
+	end := start.AddDate(0, 0, req.ExpectedStayDays)
+
+	expiresAt := start.Add(reservationNoShowGrace)
+	if req.ExpiresAt != "" {
+		expiresAt, err = parseReservationExpiry(req.ExpiresAt)
+		if err != nil {
+			bedSendJSONError(w, "Invalid expiresAt format, use YYYY-MM-DD HH:MM", http.StatusBadRequest)
+			return
+		}
+		if !expiresAt.After(start) || !expiresAt.Before(end) {
+			bedSendJSONError(w, "expiresAt must fall within the reserved stay", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the bed so overlapping reservations and admissions are serialised
+	var bedStatus string
+	err = tx.QueryRow("SELECT Status FROM BedInventory WHERE BedID = ? FOR UPDATE", req.BedID).Scan(&bedStatus)
+	if err == sql.ErrNoRows {
+		bedSendJSONError(w, "Bed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error locking bed: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if bedStatus == "maintenance" || bedStatus == "blocked" {
+		bedSendJSONError(w, "Bed is out of service", http.StatusConflict)
+		return
+	}
+
+	var patientExists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM Patients WHERE PatientID = ?)", req.PatientID).Scan(&patientExists); err != nil {
+		log.Printf("Error checking patient: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !patientExists {
+		bedSendJSONError(w, "Patient not found", http.StatusNotFound)
+		return
+	}
+	if err := checkWardGender(tx, req.BedID, req.PatientID); err != nil {
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error checking ward restriction: %v", err)
+		}
+		bedSendJSONError(w, message, status)
+		return
+	}
+
+	startDate, endDate := start.Format("2006-01-02"), end.Format("2006-01-02")
+	reservationID, err := insertReservation(tx, req.BedID, bedStatus, req.PatientID, startDate, endDate,
+		req.ExpectedStayDays, expiresAt, req.Notes, req.EmployeeID)
+	if err != nil {
+		status, message := reservationErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error creating bed reservation: %v", err)
+		}
+		bedSendJSONError(w, message, status)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.BedReservation{
+		ReservationID:    int(reservationID),
+		BedID:            req.BedID,
+		PatientID:        req.PatientID,
+		StartDate:        startDate,
+		ExpectedStayDays: req.ExpectedStayDays,
+		EndDate:          endDate,
+		ExpiresAt:        expiresAt.Format("2006-01-02 15:04"),
+		Status:           "active",
+		Notes:            req.Notes,
+	})
+}
+
+// GetBedReservations lists reservations, filtered by status, bedId, patientId
+// or hospitalId
+func GetBedReservations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := `
+		SELECT br.ReservationID, br.BedID, br.PatientID, p.FullName,
+		       DATE_FORMAT(br.StartDate, '%Y-%m-%d'), br.ExpectedStayDays,
+		       DATE_FORMAT(br.EndDate, '%Y-%m-%d'), DATE_FORMAT(br.ExpiresAt, '%Y-%m-%d %H:%i'),
+		       br.Status, COALESCE(br.AssignmentID, 0), COALESCE(br.Notes, ''),
+		       ` + bedWardColumns + `
+		FROM BedReservations br
+		JOIN Patients p ON br.PatientID = p.PatientID
+		JOIN BedInventory bi ON br.BedID = bi.BedID
+		` + bedWardJoins + `
+		WHERE 1=1
+	`
+	var args []interface{}
+	filters := []struct{ param, column string }{
+		{"status", "br.Status"},
+		{"bedId", "br.BedID"},
+		{"patientId", "br.PatientID"},
+		{"hospitalId", "bi.HospitalID"},
+	}
+	for _, f := range filters {
+		if v := r.URL.Query().Get(f.param); v != "" && v != "all" {
+			query += " AND " + f.column + " = ?"
+			args = append(args, v)
+		}
+	}
+	query += " ORDER BY br.StartDate, br.ReservationID"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying bed reservations: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	reservations := []models.BedReservation{}
+	for rows.Next() {
+		var res models.BedReservation
+		var placement bedPlacement
+		dest := []interface{}{&res.ReservationID, &res.BedID, &res.PatientID, &res.PatientName,
+			&res.StartDate, &res.ExpectedStayDays, &res.EndDate, &res.ExpiresAt,
+			&res.Status, &res.AssignmentID, &res.Notes}
+		if err := rows.Scan(append(dest, placement.dest()...)...); err != nil {
+			log.Printf("Error scanning bed reservation row: %v", err)
+			continue
+		}
+		res.Location = bedLocation(res.BedID, placement.WardName, placement.RoomNumber, placement.Label)
+		reservations = append(reservations, res)
+	}
+
+	json.NewEncoder(w).Encode(reservations)
+}
+
+// ConvertBedReservation admits the patient to their reserved bed
+func ConvertBedReservation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	reservationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		bedSendJSONError(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AdmissionDate string `json:"admissionDate"`
+		EmployeeID    int    `json:"employeeId"`
+	}
+	// The body is optional; admission defaults to today
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.AdmissionDate == "" {
+		req.AdmissionDate = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", req.AdmissionDate); err != nil {
+		bedSendJSONError(w, "Invalid admissionDate format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var bedID, patientID int
+	var status string
+	err = tx.QueryRow(
+		"SELECT BedID, PatientID, Status FROM BedReservations WHERE ReservationID = ?",
+		reservationID).Scan(&bedID, &patientID, &status)
+	if err == nil && status != "active" {
+		err = errReservationClosed
+	}
+	if err == sql.ErrNoRows {
+		err = errReservationNotFound
+	}
+
+	// occupyBed claims this reservation rather than whichever of the patient's
+	// reservations on the bed comes first, and fails if it was closed meanwhile
+	var assignmentID int64
+	if err == nil {
+		assignmentID, _, err = occupyBed(tx, bedID, patientID, req.AdmissionDate, bedMove{
+			Reason:  fmt.Sprintf("Admitted from reservation #%d", reservationID),
+			ActorID: req.EmployeeID,
+		}, admissionDetails{ReservationID: reservationID})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		status, message := reservationErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error converting bed reservation: %v", err)
+		}
+		bedSendJSONError(w, message, status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"reservationId": reservationID,
+		"assignmentId":  assignmentID,
+		"bedId":         bedID,
+		"patientId":     patientID,
+		"admissionDate": req.AdmissionDate,
+	})
+}
+
+// CancelBedReservation releases a reservation before the patient arrives
+func CancelBedReservation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	reservationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		bedSendJSONError(w, "Invalid reservation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	reason := fmt.Sprintf("Reservation #%d cancelled", reservationID)
+	if req.Reason = strings.TrimSpace(req.Reason); req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+
+	var bedID int
+	err = withTx(func(tx *sql.Tx) error {
+		var err error
+		bedID, err = closeReservation(tx, reservationID, "cancelled", reason)
+		return err
+	})
+	if err != nil {
+		status, message := reservationErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error cancelling bed reservation: %v", err)
+		}
+		bedSendJSONError(w, message, status)
+		return
+	}
+
+	go fillBedFromQueue(bedID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"reservationId": reservationID,
+		"message":       "Reservation cancelled",
+	})
+}
+
+// SearchAvailableBeds finds beds free for the whole of [from, to), leaving out
+// beds out of service, beds occupied with no planned discharge before from,
+// and beds with an active reservation overlapping the period. from defaults
+// to today and to to the day after from. A bed reserved from a later date is
+// listed with reservedFrom set, since a stay there must end by that date.
+func SearchAvailableBeds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	today := time.Now().Format("2006-01-02")
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = today
+	}
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		bedSendJSONError(w, "Invalid from date, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = fromDate.AddDate(0, 0, 1).Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", to); err != nil || to <= from {
+		bedSendJSONError(w, "to must be a date after from", http.StatusBadRequest)
+		return
+	}
+
+	// Beds being cleaned, still occupied or held today only count for later dates
+	statusCondition := "bi.Status = 'available'"
+	if from > today {
+		statusCondition = "bi.Status IN ('available', 'occupied', 'cleaning', 'reserved')"
+	}
+
+	query := `
+		SELECT bi.BedID, bi.HospitalID, bi.BedType, bi.Status,
+		       COALESCE(rm.RoomID, 0), ` + bedWardColumns + `,
+		       COALESCE((
+				SELECT DATE_FORMAT(MIN(br.StartDate), '%Y-%m-%d') FROM BedReservations br
+				WHERE br.BedID = bi.BedID AND br.Status = 'active' AND br.StartDate >= ?), '')
+		FROM BedInventory bi
+		` + bedWardJoins + `
+		WHERE ` + statusCondition + `
+		  AND NOT EXISTS (
+			SELECT 1 FROM BedAssignments ba
+			WHERE ba.BedID = bi.BedID AND ba.DischargeDate IS NULL
+			  AND (ba.PlannedDischargeDate IS NULL OR ba.PlannedDischargeDate > ?))
+		  AND NOT EXISTS (
+			SELECT 1 FROM BedReservations br
+			WHERE br.BedID = bi.BedID AND br.Status = 'active'
+			  AND br.StartDate < ? AND br.EndDate > ?)
+	`
+	args := []interface{}{to, from, to, from}
+
+	filters := []struct{ param, column string }{
+		{"hospitalId", "bi.HospitalID"},
+		{"bedType", "bi.BedType"},
+		{"wardId", "wd.WardID"},
+		{"floor", "wd.Floor"},
+	}
+	for _, f := range filters {
+		if v := r.URL.Query().Get(f.param); v != "" && v != "all" {
+			query += " AND " + f.column + " = ?"
+			args = append(args, v)
+		}
+	}
+	query += " ORDER BY bi.HospitalID, " + bedWardOrder + ", bi.BedID"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error searching available beds: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	beds := []models.Bed{}
+	for rows.Next() {
+		var bed models.Bed
+		err := rows.Scan(&bed.BedID, &bed.HospitalID, &bed.BedType, &bed.Status,
+			&bed.RoomID, &bed.WardID, &bed.WardName, &bed.Floor, &bed.RoomNumber, &bed.Label, &bed.ReservedFrom)
+		if err != nil {
+			log.Printf("Error scanning available bed row: %v", err)
+			continue
+		}
+		bed.Location = bedLocation(bed.BedID, bed.WardName, bed.RoomNumber, bed.Label)
+		beds = append(beds, bed)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"beds": beds,
+	})
+}
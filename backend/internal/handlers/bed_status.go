@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hospital-management/backend/internal/database"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Bed lifecycle states stored in BedInventory.Status. Only available beds can
+// be assigned. occupied is set and cleared by the occupancy service; the other
+// states are changed by staff through UpdateBedStatus.
+var bedStatuses = []string{"available", "occupied", "reserved", "cleaning", "maintenance", "blocked"}
+
+// manualBedTransitions lists the state changes staff may make by hand
+var manualBedTransitions = map[string][]string{
+	"available":   {"reserved", "cleaning", "maintenance", "blocked"},
+	"reserved":    {"available"},
+	"cleaning":    {"available", "maintenance"},
+	"maintenance": {"available", "cleaning"},
+	"blocked":     {"available", "cleaning"},
+}
+
+var errInvalidBedTransition = errors.New("bed cannot change to that status")
+
+func validBedStatus(status string) bool {
+	for _, s := range bedStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// recordBedStatus moves a bed to a new state and logs the transition. A bed
+// going for cleaning gets a housekeeping task, and one leaving cleaning has its
+// open task closed. The caller must already hold the bed row lock. changedBy
+// is the employee making the change, or 0 for changes made by the system.
+func recordBedStatus(tx *sql.Tx, bedID int, from, to, reason string, changedBy int) error {
+	if _, err := tx.Exec("UPDATE BedInventory SET Status = ? WHERE BedID = ?", to, bedID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		INSERT INTO BedStatusLog (BedID, FromStatus, ToStatus, Reason, ChangedBy)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0))
+	`, bedID, from, to, reason, changedBy)
+	if err != nil || from == to {
+		return err
+	}
+	if to == "cleaning" {
+		return openHousekeepingTask(tx, bedID, from, reason)
+	}
+	if from == "cleaning" {
+		return closeHousekeepingTask(tx, bedID, to, changedBy)
+	}
+	return nil
+}
+
+// changeBedStatus locks a bed and applies a manual state change if it is allowed
+func changeBedStatus(tx *sql.Tx, bedID int, to, reason string, changedBy int) (string, error) {
+	var from string
+	err := tx.QueryRow("SELECT Status FROM BedInventory WHERE BedID = ? FOR UPDATE", bedID).Scan(&from)
+	if err == sql.ErrNoRows {
+		return "", errBedNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	allowed := false
+	for _, s := range manualBedTransitions[from] {
+		if s == to {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return from, errInvalidBedTransition
+	}
+	return from, recordBedStatus(tx, bedID, from, to, reason, changedBy)
+}
+
+// UpdateBedStatus lets staff move a bed between lifecycle states with a reason
+func UpdateBedStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	bedID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid bed ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Status     string `json:"status"`
+		Reason     string `json:"reason"`
+		EmployeeID int    `json:"employeeId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !validBedStatus(req.Status) {
+		sendJSONError(w, "Invalid bed status", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		sendJSONError(w, "A reason is required to change bed status", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	from, err := changeBedStatus(tx, bedID, req.Status, req.Reason, req.EmployeeID)
+	if err != nil {
+		if err == errInvalidBedTransition {
+			sendJSONError(w, "A "+from+" bed cannot be changed to "+req.Status, http.StatusConflict)
+			return
+		}
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error changing bed status: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Bed %d changed from %s to %s: %s", bedID, from, req.Status, req.Reason)
+	if req.Status == "available" {
+		go fillBedFromQueue(bedID)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"bedId":          bedID,
+		"previousStatus": from,
+		"status":         req.Status,
+	})
+}
+
+// GetBedStatusLog returns the state transitions of a bed, newest first
+func GetBedStatusLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	bedID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid bed ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT l.LogID, COALESCE(l.FromStatus, ''), l.ToStatus, COALESCE(l.Reason, ''),
+		       COALESCE(l.ChangedBy, 0), COALESCE(e.FullName, ''), l.ChangedAt
+		FROM BedStatusLog l
+		LEFT JOIN Employees e ON l.ChangedBy = e.EmployeeID
+		WHERE l.BedID = ?
+		ORDER BY l.ChangedAt DESC, l.LogID DESC
+	`, bedID)
+	if err != nil {
+		log.Printf("Error fetching bed status log: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []map[string]interface{}{}
+	for rows.Next() {
+		var logID, changedBy int
+		var from, to, reason, changedByName string
+		var changedAt time.Time
+		if err := rows.Scan(&logID, &from, &to, &reason, &changedBy, &changedByName, &changedAt); err != nil {
+			log.Printf("Error scanning bed status log: %v", err)
+			continue
+		}
+		entries = append(entries, map[string]interface{}{
+			"logId":         logID,
+			"fromStatus":    from,
+			"toStatus":      to,
+			"reason":        reason,
+			"changedBy":     changedBy,
+			"changedByName": changedByName,
+			"changedAt":     changedAt.Format(time.RFC3339),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bedId":       bedID,
+		"transitions": entries,
+	})
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+)
+
+// Bed type catalogue. Each type carries the attributes used for planning and
+// billing: acuity, daily tariff, equipment, nurse-to-bed ratio and whether it
+// can be used for isolation. A type in use can't be deleted, only retired;
+// retired types are hidden from pickers and rejected for new beds.
+
+// bedTypeColumns selects a BedTypes row bt for scanBedType
+const bedTypeColumns = `bt.BedType, COALESCE(bt.Description, ''), bt.AcuityLevel, bt.DailyTariff,
+	bt.NeedsVentilator, bt.NeedsMonitor, bt.BedsPerNurse, bt.IsolationCapable, bt.RetiredAt IS NOT NULL`
+
+// scanBedType reads a row selected with bedTypeColumns
+func scanBedType(row interface{ Scan(...interface{}) error }) (models.BedType, error) {
+	var t models.BedType
+	err := row.Scan(&t.Type, &t.Description, &t.AcuityLevel, &t.DailyTariff,
+		&t.NeedsVentilator, &t.NeedsMonitor, &t.BedsPerNurse, &t.IsolationCapable, &t.Retired)
+	t.NurseToBedRatio = nurseToBedRatio(t.BedsPerNurse)
+	return t, err
+}
+
+// nurseToBedRatio formats beds per nurse as a ratio such as "1:2" or "1:1.5"
+func nurseToBedRatio(bedsPerNurse float64) string {
+	return "1:" + strconv.FormatFloat(bedsPerNurse, 'f', -1, 64)
+}
+
+// bedTypeRequest is the body of CreateBedType and UpdateBedType. Fields left
+// out of an update are unchanged.
+type bedTypeRequest struct {
+	Type             string   `json:"type"`
+	Description      *string  `json:"description"`
+	AcuityLevel      *int     `json:"acuityLevel"`
+	DailyTariff      *float64 `json:"dailyTariff"`
+	NeedsVentilator  *bool    `json:"needsVentilator"`
+	NeedsMonitor     *bool    `json:"needsMonitor"`
+	BedsPerNurse     *float64 `json:"bedsPerNurse"`
+	IsolationCapable *bool    `json:"isolationCapable"`
+	Retired          *bool    `json:"retired"`
+}
+
+// validate checks the attribute values that were given
+func (req bedTypeRequest) validate() string {
+	if req.AcuityLevel != nil && (*req.AcuityLevel < 1 || *req.AcuityLevel > 5) {
+		return "acuityLevel must be between 1 and 5"
+	}
+	if req.DailyTariff != nil && *req.DailyTariff < 0 {
+		return "dailyTariff cannot be negative"
+	}
+	// BedsPerNurse is stored as DECIMAL(4,1), so check it at that precision
+	if req.BedsPerNurse != nil {
+		if ratio := math.Round(*req.BedsPerNurse*10) / 10; ratio < 0.1 || ratio > 999.9 {
+			return "bedsPerNurse must be between 0.1 and 999.9"
+		}
+	}
+	return ""
+}
+
+// loadBedType returns a bed type by name
+func loadBedType(q queryer, bedType string) (models.BedType, error) {
+	return scanBedType(q.QueryRow("SELECT "+bedTypeColumns+" FROM BedTypes bt WHERE bt.BedType = ?", bedType))
+}
+
+// CreateBedType adds a bed type to the catalogue
+func CreateBedType(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req bedTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		bedSendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Type = strings.TrimSpace(req.Type)
+	if req.Type == "" || len(req.Type) > 50 {
+		bedSendJSONError(w, "type is required and must be at most 50 characters", http.StatusBadRequest)
+		return
+	}
+	if message := req.validate(); message != "" {
+		bedSendJSONError(w, message, http.StatusBadRequest)
+		return
+	}
+
+	t := models.BedType{Type: req.Type, AcuityLevel: 1, BedsPerNurse: 4}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.AcuityLevel != nil {
+		t.AcuityLevel = *req.AcuityLevel
+	}
+	if req.DailyTariff != nil {
+		t.DailyTariff = *req.DailyTariff
+	}
+	if req.NeedsVentilator != nil {
+		t.NeedsVentilator = *req.NeedsVentilator
+	}
+	if req.NeedsMonitor != nil {
+		t.NeedsMonitor = *req.NeedsMonitor
+	}
+	if req.BedsPerNurse != nil {
+		t.BedsPerNurse = *req.BedsPerNurse
+	}
+	if req.IsolationCapable != nil {
+		t.IsolationCapable = *req.IsolationCapable
+	}
+	t.NurseToBedRatio = nurseToBedRatio(t.BedsPerNurse)
+
+	_, err := database.DB.Exec(`
+		INSERT INTO BedTypes (BedType, Description, AcuityLevel, DailyTariff, NeedsVentilator, NeedsMonitor, BedsPerNurse, IsolationCapable)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.Type, t.Description, t.AcuityLevel, t.DailyTariff, t.NeedsVentilator, t.NeedsMonitor, t.BedsPerNurse, t.IsolationCapable)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			bedSendJSONError(w, "A bed type with that name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Error creating bed type: %v", err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Bed type %q created", t.Type)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// UpdateBedType changes a bed type's description or attributes, or retires
+// or reinstates it with retired
+func UpdateBedType(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	bedType := mux.Vars(r)["type"]
+	var req bedTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		bedSendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Type != "" && req.Type != bedType {
+		bedSendJSONError(w, "A bed type can't be renamed; create a new type and retire this one", http.StatusBadRequest)
+		return
+	}
+	if message := req.validate(); message != "" {
+		bedSendJSONError(w, message, http.StatusBadRequest)
+		return
+	}
+
+	var sets []string
+	var args []interface{}
+	for _, f := range []struct {
+		column string
+		value  interface{}
+		given  bool
+	}{
+		{"Description", req.Description, req.Description != nil},
+		{"AcuityLevel", req.AcuityLevel, req.AcuityLevel != nil},
+		{"DailyTariff", req.DailyTariff, req.DailyTariff != nil},
+		{"NeedsVentilator", req.NeedsVentilator, req.NeedsVentilator != nil},
+		{"NeedsMonitor", req.NeedsMonitor, req.NeedsMonitor != nil},
+		{"BedsPerNurse", req.BedsPerNurse, req.BedsPerNurse != nil},
+		{"IsolationCapable", req.IsolationCapable, req.IsolationCapable != nil},
+	} {
+		if f.given {
+			sets = append(sets, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if req.Retired != nil {
+		if *req.Retired {
+			sets = append(sets, "RetiredAt = COALESCE(RetiredAt, NOW())")
+		} else {
+			sets = append(sets, "RetiredAt = NULL")
+		}
+	}
+	if len(sets) == 0 {
+		bedSendJSONError(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	args = append(args, bedType)
+	if _, err := database.DB.Exec("UPDATE BedTypes SET "+strings.Join(sets, ", ")+" WHERE BedType = ?", args...); err != nil {
+		log.Printf("Error updating bed type %q: %v", bedType, err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	t, err := loadBedType(database.DB, bedType)
+	if err == sql.ErrNoRows {
+		bedSendJSONError(w, "Bed type not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching bed type %q: %v", bedType, err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+// DeleteBedType removes a bed type that no bed or count refers to, and
+// retires it otherwise
+func DeleteBedType(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	bedType := mux.Vars(r)["type"]
+
+	var exists, inUse bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM BedTypes WHERE BedType = ?),
+		       EXISTS (SELECT 1 FROM BedInventory WHERE BedType = ?)
+		       OR EXISTS (SELECT 1 FROM BedsCount WHERE BedType = ?)
+		       OR EXISTS (SELECT 1 FROM BedRequests WHERE BedType = ?)
+		       OR EXISTS (SELECT 1 FROM HospitalTransfers WHERE BedType = ?)
+	`, bedType, bedType, bedType, bedType, bedType).Scan(&exists, &inUse)
+	if err != nil {
+		log.Printf("Error checking bed type %q: %v", bedType, err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		bedSendJSONError(w, "Bed type not found", http.StatusNotFound)
+		return
+	}
+
+	if inUse {
+		_, err = database.DB.Exec("UPDATE BedTypes SET RetiredAt = COALESCE(RetiredAt, NOW()) WHERE BedType = ?", bedType)
+	} else {
+		_, err = database.DB.Exec("DELETE FROM BedTypes WHERE BedType = ?", bedType)
+	}
+	if err != nil {
+		log.Printf("Error removing bed type %q: %v", bedType, err)
+		bedSendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	message := "Bed type deleted"
+	if inUse {
+		message = "Bed type is in use and has been retired"
+	}
+	log.Printf("Bed type %q: %s", bedType, message)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+		"type":    bedType,
+		"retired": inUse,
+	})
+}
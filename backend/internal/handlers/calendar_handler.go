@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// doctorIDForEmployee resolves the doctor behind the employeeId query parameter,
+// the same way the doctor dashboard endpoints do
+func doctorIDForEmployee(r *http.Request) (int, int, error) {
+	employeeID, err := strconv.Atoi(r.URL.Query().Get("employeeId"))
+	if err != nil {
+		return 0, http.StatusBadRequest, fmt.Errorf("Valid employeeId is required")
+	}
+
+	var doctorID int
+	err = database.DB.QueryRow("SELECT DoctorID FROM doctoremployee WHERE EmployeeID = ?", employeeID).Scan(&doctorID)
+	if err == sql.ErrNoRows {
+		return 0, http.StatusNotFound, fmt.Errorf("Doctor not found for this employee ID")
+	}
+	if err != nil {
+		log.Printf("Error fetching doctor for employee %d: %v", employeeID, err)
+		return 0, http.StatusInternalServerError, fmt.Errorf("Database error")
+	}
+	return doctorID, http.StatusOK, nil
+}
+
+// patientInitials reduces a patient name to initials so calendar feeds,
+// which are synced to third-party services, don't carry patient details
+func patientInitials(name string) string {
+	var initials []string
+	for _, part := range strings.Fields(name) {
+		r := []rune(part)
+		initials = append(initials, strings.ToUpper(string(r[0]))+".")
+	}
+	return strings.Join(initials, " ")
+}
+
+func calendarFeedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/api/calendar/doctor/%s.ics", scheme, r.Host, token)
+}
+
+// CreateCalendarToken issues a new private calendar subscription URL for a doctor
+func CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	doctorID, status, err := doctorIDForEmployee(r)
+	if err != nil {
+		sendJSONError(w, err.Error(), status)
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Error generating calendar token: %v", err)
+		sendJSONError(w, "Failed to create calendar link", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	result, err := database.DB.Exec(
+		"INSERT INTO DoctorCalendarTokens (DoctorID, Token) VALUES (?, ?)", doctorID, token)
+	if err != nil {
+		log.Printf("Error saving calendar token: %v", err)
+		sendJSONError(w, "Failed to create calendar link", http.StatusInternalServerError)
+		return
+	}
+	tokenID, _ := result.LastInsertId()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"token_id": tokenID,
+		"url":      calendarFeedURL(r, token),
+	})
+}
+
+// GetCalendarTokens lists a doctor's active calendar subscription URLs
+func GetCalendarTokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	doctorID, status, err := doctorIDForEmployee(r)
+	if err != nil {
+		sendJSONError(w, err.Error(), status)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT TokenID, Token, CreatedAt FROM DoctorCalendarTokens
+		WHERE DoctorID = ? AND RevokedAt IS NULL
+		ORDER BY CreatedAt
+	`, doctorID)
+	if err != nil {
+		log.Printf("Error fetching calendar tokens: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tokens := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var token string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &token, &createdAt); err != nil {
+			log.Printf("Error scanning calendar token: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		tokens = append(tokens, map[string]interface{}{
+			"token_id":   id,
+			"url":        calendarFeedURL(r, token),
+			"created_at": createdAt.Format(time.RFC3339),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"doctor_id": doctorID,
+		"tokens":    tokens,
+	})
+}
+
+// RevokeCalendarToken disables a calendar subscription URL
+func RevokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	doctorID, status, err := doctorIDForEmployee(r)
+	if err != nil {
+		sendJSONError(w, err.Error(), status)
+		return
+	}
+
+	tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE DoctorCalendarTokens SET RevokedAt = NOW()
+		WHERE TokenID = ? AND DoctorID = ? AND RevokedAt IS NULL
+	`, tokenID, doctorID)
+	if err != nil {
+		log.Printf("Error revoking calendar token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		sendJSONError(w, "Calendar link not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Calendar link revoked",
+	})
+}
+
+// GetDoctorCalendarFeed serves a doctor's upcoming appointments as an
+// iCalendar feed. The token in the URL is the only credential, so it is
+// looked up on every request and revoked tokens stop working immediately.
+// Cancelled appointments stay in the feed with STATUS:CANCELLED so that
+// subscribed calendars remove them.
+func GetDoctorCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	var doctorID int
+	var doctorName string
+	err := database.DB.QueryRow(`
+		SELECT t.DoctorID, d.FullName
+		FROM DoctorCalendarTokens t
+		JOIN Doctors d ON t.DoctorID = d.DoctorID
+		WHERE t.Token = ? AND t.RevokedAt IS NULL
+	`, token).Scan(&doctorID, &doctorName)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error looking up calendar token: %v", err)
+		}
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT a.AppointmentID, a.AppointmentDate, a.AppointmentTime, a.Status,
+		       a.Sequence, a.AppointmentType, p.FullName, COALESCE(d.Room, '')
+		FROM Appointment a
+		JOIN Patients p ON a.PatientID = p.PatientID
+		JOIN Doctors d ON a.DoctorID = d.DoctorID
+		WHERE a.DoctorID = ? AND a.AppointmentDate >= CURDATE()
+		ORDER BY a.AppointmentDate, a.AppointmentID
+	`, doctorID)
+	if err != nil {
+		log.Printf("Error fetching calendar appointments: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var events []icalEvent
+	for rows.Next() {
+		var e icalEvent
+		var date time.Time
+		var slot, status, appointmentType, patientName string
+		if err := rows.Scan(&e.AppointmentID, &date, &slot, &status, &e.Sequence,
+			&appointmentType, &patientName, &e.Location); err != nil {
+			log.Printf("Error scanning calendar appointment: %v", err)
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		e.Start, err = slotStart(date, slot)
+		if err != nil {
+			continue
+		}
+		e.End = e.Start.Add(slotDuration)
+		e.Summary = "Consultation: " + patientInitials(patientName)
+		if appointmentType == "walk-in" {
+			e.Summary += " (walk-in)"
+		}
+		e.Cancelled = status == "cancelled"
+		events = append(events, e)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="appointments.ics"`)
+	if err := writeCalendar(w, doctorName+" - Appointments", events); err != nil {
+		log.Printf("Error writing calendar feed: %v", err)
+	}
+}
+
+// appointmentCalendar returns a single-event calendar for a patient's
+// appointment, attached to booking emails
+func appointmentCalendar(appointmentID int64) ([]byte, error) {
+	var e icalEvent
+	var date time.Time
+	var slot, status, doctorName, department string
+	err := database.DB.QueryRow(`
+		SELECT a.AppointmentID, a.AppointmentDate, a.AppointmentTime, a.Status, a.Sequence,
+		       d.FullName, d.Department, COALESCE(d.Room, '')
+		FROM Appointment a
+		JOIN Doctors d ON a.DoctorID = d.DoctorID
+		WHERE a.AppointmentID = ?
+	`, appointmentID).Scan(&e.AppointmentID, &date, &slot, &status, &e.Sequence,
+		&doctorName, &department, &e.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Start, err = slotStart(date, slot)
+	if err != nil {
+		return nil, err
+	}
+	e.End = e.Start.Add(slotDuration)
+	e.Summary = fmt.Sprintf("Appointment with %s (%s)", doctorName, department)
+	e.Description = "Please arrive 15 minutes early and check in at the reception desk."
+	e.Cancelled = status == "cancelled"
+
+	var buf bytes.Buffer
+	if err := writeCalendar(&buf, "PulsePoint Appointment", []icalEvent{e}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hospital-management/backend/internal/database"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// validDischargeType reports whether t is a recognised discharge type
+func validDischargeType(t string) bool {
+	switch t {
+	case "routine", "against-advice", "transfer-out", "deceased":
+		return true
+	}
+	return false
+}
+
+// DischargeRequest is the body accepted by DischargePatient. Either
+// AssignmentID or PatientID identifies the active assignment. When Planned is
+// set the discharge is only scheduled for DischargeDate and the bed stays
+// occupied, showing as "releasing", until it is discharged for real.
+type DischargeRequest struct {
+	AssignmentID        int    `json:"assignmentId"`
+	PatientID           int    `json:"patientId"`
+	DischargeType       string `json:"dischargeType"`
+	DischargingDoctorID int    `json:"dischargingDoctorId"`
+	DischargeDate       string `json:"dischargeDate"`
+	Notes               string `json:"notes"`
+	Planned             bool   `json:"planned"`
+	EmployeeID          int    `json:"employeeId"` // recorded in the movement ledger
+	Summary             string `json:"summary"`    // discharge summary kept on the admission
+}
+
+// DischargePatient closes a patient's active bed assignment and sends the bed
+// for cleaning, or records a planned discharge
+func DischargePatient(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req DischargeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding discharge request: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.AssignmentID == 0 && req.PatientID == 0 {
+		sendJSONError(w, "Either assignmentId or patientId is required", http.StatusBadRequest)
+		return
+	}
+	if req.DischargeType == "" {
+		req.DischargeType = "routine"
+	}
+	if !validDischargeType(req.DischargeType) {
+		sendJSONError(w, "dischargeType must be routine, against-advice, transfer-out or deceased", http.StatusBadRequest)
+		return
+	}
+	if req.DischargingDoctorID == 0 {
+		sendJSONError(w, "dischargingDoctorId is required", http.StatusBadRequest)
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	if req.DischargeDate == "" {
+		req.DischargeDate = today
+	}
+	if _, err := time.Parse("2006-01-02", req.DischargeDate); err != nil {
+		sendJSONError(w, "Invalid dischargeDate format", http.StatusBadRequest)
+		return
+	}
+	if req.Planned && req.DischargeDate < today {
+		sendJSONError(w, "A planned discharge date cannot be in the past", http.StatusBadRequest)
+		return
+	}
+	if !req.Planned && req.DischargeDate > today {
+		sendJSONError(w, "dischargeDate can't be in the future for an immediate discharge; use planned", http.StatusBadRequest)
+		return
+	}
+
+	var doctorExists bool
+	err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Doctors WHERE DoctorID = ?)", req.DischargingDoctorID).Scan(&doctorExists)
+	if err != nil {
+		log.Printf("Error checking discharging doctor: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !doctorExists {
+		sendJSONError(w, "Discharging doctor not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var a activeAssignment
+	if req.AssignmentID != 0 {
+		a, err = assignmentByID(tx, req.AssignmentID)
+	} else {
+		a, err = currentAssignment(tx, req.PatientID)
+	}
+	if err == nil && req.DischargeDate < a.AdmissionDate {
+		err = errDischargeBeforeAdmit
+	}
+	if err != nil {
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error fetching bed assignment: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	// The discharge details are recorded either way; only a real discharge frees the bed
+	plannedDate := sql.NullString{String: req.DischargeDate, Valid: req.Planned}
+	_, err = tx.Exec(`
+		UPDATE BedAssignments
+		SET PlannedDischargeDate = ?, DischargeType = ?, DischargingDoctorID = ?, DischargeNotes = ?
+		WHERE AssignmentID = ?
+	`, plannedDate, req.DischargeType, req.DischargingDoctorID, req.Notes, a.AssignmentID)
+	if err == nil && !req.Planned {
+		err = vacateBed(tx, a, req.DischargeDate, bedMove{
+			Reason:  "Patient discharged (" + req.DischargeType + ")",
+			Notes:   req.Notes,
+			ActorID: req.EmployeeID,
+		})
+	}
+	if err == nil && !req.Planned && a.AdmissionID != 0 {
+		_, err = tx.Exec(`
+			UPDATE Admissions
+			SET DischargeType = ?, DischargingDoctorID = ?, DischargeSummary = COALESCE(NULLIF(?, ''), DischargeSummary)
+			WHERE AdmissionID = ?
+		`, req.DischargeType, req.DischargingDoctorID, req.Summary, a.AdmissionID)
+	}
+	if err != nil {
+		log.Printf("Error discharging patient: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	message := "Patient discharged successfully"
+	status := "discharged"
+	if req.Planned {
+		message = "Discharge planned successfully"
+		status = "releasing"
+	}
+	log.Printf("Assignment %d (patient %d, bed %d): %s (%s) by doctor %d",
+		a.AssignmentID, a.PatientID, a.Bed.BedID, status, req.DischargeType, req.DischargingDoctorID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":             true,
+		"message":             message,
+		"assignmentId":        a.AssignmentID,
+		"admissionId":         a.AdmissionID,
+		"patientId":           a.PatientID,
+		"bedId":               a.Bed.BedID,
+		"bedType":             a.Bed.BedType,
+		"dischargeType":       req.DischargeType,
+		"dischargingDoctorId": req.DischargingDoctorID,
+		"dischargeDate":       req.DischargeDate,
+		"status":              status,
+	})
+}
+
+// MarkBedCleaned returns a bed that was being cleaned after a discharge to
+// service, completing its open housekeeping task
+func MarkBedCleaned(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	bedID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid bed ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT Status FROM BedInventory WHERE BedID = ? FOR UPDATE", bedID).Scan(&status)
+	if err == nil && status != "cleaning" {
+		sendJSONError(w, "Bed is not awaiting cleaning", http.StatusConflict)
+		return
+	}
+	if err == nil {
+		err = recordBedStatus(tx, bedID, "cleaning", "available", "Cleaning completed", 0)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Bed not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error marking bed cleaned: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	go fillBedFromQueue(bedID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Bed is available again",
+		"bedId":   bedID,
+	})
+}
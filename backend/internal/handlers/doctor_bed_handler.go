@@ -55,6 +55,14 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Fetching bed data for hospitalID: %d", hospitalID)
 
+	// Optional ward filter, applied to assignments and available beds
+	wardCondition := ""
+	args := []interface{}{hospitalID}
+	if wardID := r.URL.Query().Get("wardId"); wardID != "" {
+		wardCondition = " AND wd.WardID = ?"
+		args = append(args, wardID)
+	}
+
 	// Query to get bed assignments along with bed details and vacancy counts
 	query := `
 	SELECT 
@@ -70,22 +78,24 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 			WHEN ba.DischargeDate < CURDATE() THEN 'discharged'
 			ELSE 'scheduled'
 		END AS Status,
-		bc.VacantBeds
+		bc.VacantBeds,
+		` + bedWardColumns + `
 	FROM 
 		bedassignments ba
 	JOIN 
 		bedinventory bi ON ba.BedID = bi.BedID
+	` + bedWardJoins + `
 	JOIN 
 		bedscount bc ON bi.BedType = bc.BedType AND bi.HospitalID = bc.HospitalID
 	JOIN 
 		patients p ON ba.PatientID = p.PatientID
 	WHERE 
-		bi.HospitalID = ? AND (ba.DischargeDate IS NULL OR ba.DischargeDate >= CURDATE())
+		bi.HospitalID = ? AND (ba.DischargeDate IS NULL OR ba.DischargeDate >= CURDATE())` + wardCondition + `
 	ORDER BY 
 		ba.AdmissionDate DESC
 	`
 
-	rows, err := database.DB.Query(query, hospitalID)
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		log.Printf("Error querying bed assignments: %v", err)
 		sendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -106,6 +116,7 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 			DischargeDate sql.NullString
 			Status        string
 			VacantBeds    int
+			Placement     bedPlacement
 		}
 
 		err := rows.Scan(
@@ -118,6 +129,11 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 			&assignment.DischargeDate,
 			&assignment.Status,
 			&assignment.VacantBeds,
+			&assignment.Placement.WardID,
+			&assignment.Placement.WardName,
+			&assignment.Placement.Floor,
+			&assignment.Placement.RoomNumber,
+			&assignment.Placement.Label,
 		)
 
 		if err != nil {
@@ -141,6 +157,7 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 		if assignment.DischargeDate.Valid {
 			assignmentMap["dischargeDate"] = assignment.DischargeDate.String
 		}
+		assignment.Placement.addTo(assignmentMap, assignment.BedID)
 
 		bedAssignments = append(bedAssignments, assignmentMap)
 	}
@@ -151,9 +168,11 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 		bi.BedID,
 		bi.BedType,
 		bt.Description,
-		bc.VacantBeds
+		bc.VacantBeds,
+		` + bedWardColumns + `
 	FROM 
 		bedinventory bi
+	` + bedWardJoins + `
 	JOIN 
 		bedtypes bt ON bi.BedType = bt.BedType
 	JOIN 
@@ -161,12 +180,13 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 	LEFT JOIN 
 		(SELECT BedID FROM bedassignments WHERE DischargeDate IS NULL) ba ON bi.BedID = ba.BedID
 	WHERE 
-		bi.HospitalID = ? AND ba.BedID IS NULL
+		bi.HospitalID = ? AND ba.BedID IS NULL AND bi.Status = 'available'
+		AND ` + bedNotReserved + wardCondition + `
 	ORDER BY 
-		bi.BedType, bi.BedID
+		` + bedWardOrder + `, bi.BedType, bi.BedID
 	`
 
-	availableRows, err := database.DB.Query(availableBedsQuery, hospitalID)
+	availableRows, err := database.DB.Query(availableBedsQuery, args...)
 	if err != nil {
 		log.Printf("Error querying available beds: %v", err)
 		sendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -181,21 +201,25 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 			BedType     string
 			Description string
 			VacantBeds  int
+			Placement   bedPlacement
 		}
 
-		err := availableRows.Scan(&bed.BedID, &bed.BedType, &bed.Description, &bed.VacantBeds)
+		err := availableRows.Scan(append([]interface{}{&bed.BedID, &bed.BedType, &bed.Description, &bed.VacantBeds},
+			bed.Placement.dest()...)...)
 		if err != nil {
 			log.Printf("Error scanning available bed row: %v", err)
 			continue
 		}
 
-		availableBeds = append(availableBeds, map[string]interface{}{
+		availableBed := map[string]interface{}{
 			"bedId":       bed.BedID,
 			"bedType":     bed.BedType,
 			"description": bed.Description,
 			"vacantBeds":  bed.VacantBeds,
 			"status":      "available",
-		})
+		}
+		bed.Placement.addTo(availableBed, bed.BedID)
+		availableBeds = append(availableBeds, availableBed)
 	}
 
 	// Check if we have any available beds
@@ -208,24 +232,28 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 			bi.BedID,
 			bi.BedType,
 			bt.Description,
-			bc.VacantBeds
+			bc.VacantBeds,
+			` + bedWardColumns + `
 		FROM 
 			bedinventory bi
+		` + bedWardJoins + `
 		JOIN 
 			bedtypes bt ON bi.BedType = bt.BedType
 		JOIN 
 			bedscount bc ON bi.BedType = bc.BedType AND bi.HospitalID = bc.HospitalID
 		WHERE 
 			bi.HospitalID = ? 
+			AND bi.Status = 'available'
 			AND NOT EXISTS (
 				SELECT 1 FROM bedassignments ba 
 				WHERE ba.BedID = bi.BedID AND ba.DischargeDate IS NULL
 			)
+			AND ` + bedNotReserved + wardCondition + `
 		ORDER BY 
-			bi.BedType, bi.BedID
+			` + bedWardOrder + `, bi.BedType, bi.BedID
 		`
 
-		directRows, err := database.DB.Query(directQuery, hospitalID)
+		directRows, err := database.DB.Query(directQuery, args...)
 		if err != nil {
 			log.Printf("Error in direct query for available beds: %v", err)
 		} else {
@@ -236,21 +264,25 @@ func GetDoctorBeds(w http.ResponseWriter, r *http.Request) {
 					BedType     string
 					Description string
 					VacantBeds  int
+					Placement   bedPlacement
 				}
 
-				err := directRows.Scan(&bed.BedID, &bed.BedType, &bed.Description, &bed.VacantBeds)
+				err := directRows.Scan(append([]interface{}{&bed.BedID, &bed.BedType, &bed.Description, &bed.VacantBeds},
+					bed.Placement.dest()...)...)
 				if err != nil {
 					log.Printf("Error scanning direct bed row: %v", err)
 					continue
 				}
 
-				availableBeds = append(availableBeds, map[string]interface{}{
+				availableBed := map[string]interface{}{
 					"bedId":       bed.BedID,
 					"bedType":     bed.BedType,
 					"description": bed.Description,
 					"vacantBeds":  bed.VacantBeds,
 					"status":      "available",
-				})
+				}
+				bed.Placement.addTo(availableBed, bed.BedID)
+				availableBeds = append(availableBeds, availableBed)
 			}
 			log.Printf("Direct query found %d available beds", len(availableBeds))
 		}
@@ -289,6 +321,11 @@ func AssignBed(w http.ResponseWriter, r *http.Request) {
 		BedID         int    `json:"bedId"`
 		AdmissionDate string `json:"admissionDate"`
 		Notes         string `json:"notes"`
+		Diagnosis     string `json:"diagnosis"`
+		// Queue the patient for a bed of the same type if this one is taken
+		Queue     bool   `json:"queue"`
+		Urgency   string `json:"urgency"`
+		Isolation bool   `json:"isolation"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -308,9 +345,8 @@ func AssignBed(w http.ResponseWriter, r *http.Request) {
 		request.AdmissionDate = time.Now().Format("2006-01-02")
 	}
 
-	// Verify employee exists
-	var hospitalID int
-	err := database.DB.QueryRow("SELECT HospitalID FROM employees WHERE EmployeeID = ?", request.EmployeeID).Scan(&hospitalID)
+	// Verify employee exists; a doctor assigning the bed is the admitting doctor
+	doctorID, _, err := doctorForEmployee(request.EmployeeID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sendJSONError(w, "Employee not found", http.StatusNotFound)
@@ -321,57 +357,6 @@ func AssignBed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if patient already has an active bed assignment
-	var hasExistingAssignment bool
-	err = database.DB.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 
-			FROM bedassignments 
-			WHERE PatientID = ? 
-			AND DischargeDate IS NULL
-		)
-	`, request.PatientID).Scan(&hasExistingAssignment)
-
-	if err != nil {
-		log.Printf("Error checking existing patient assignments: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	if hasExistingAssignment {
-		sendJSONError(w, "Patient already has an active bed assignment", http.StatusConflict)
-		return
-	}
-
-	// Check if bed exists and is available
-	var bedType string
-	var isBedAvailable bool
-	err = database.DB.QueryRow(`
-		SELECT 
-			bi.BedType,
-			NOT EXISTS (
-				SELECT 1 FROM bedassignments ba 
-				WHERE ba.BedID = ? AND ba.DischargeDate IS NULL
-			) AS IsAvailable
-		FROM bedinventory bi
-		WHERE bi.BedID = ?
-	`, request.BedID, request.BedID).Scan(&bedType, &isBedAvailable)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			sendJSONError(w, "Bed not found", http.StatusNotFound)
-		} else {
-			log.Printf("Error checking bed availability: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	if !isBedAvailable {
-		sendJSONError(w, "Bed is not available", http.StatusConflict)
-		return
-	}
-
 	// Begin transaction for bed assignment and count updates
 	tx, err := database.DB.Begin()
 	if err != nil {
@@ -381,35 +366,28 @@ func AssignBed(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// Insert new assignment
-	result, err := tx.Exec(
-		"INSERT INTO bedassignments (BedID, PatientID, AdmissionDate, DischargeDate) VALUES (?, ?, ?, NULL)",
-		request.BedID, request.PatientID, request.AdmissionDate)
-	if err != nil {
-		log.Printf("Error inserting bed assignment: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	// Get the new assignment ID
-	assignmentID, err := result.LastInsertId()
-	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
+	assignmentID, bed, err := occupyBed(tx, request.BedID, request.PatientID, request.AdmissionDate,
+		bedMove{Notes: request.Notes, ActorID: request.EmployeeID},
+		admissionDetails{AdmittingDoctorID: doctorID, Diagnosis: request.Diagnosis})
+	if (err == errBedUnavailable || err == errBedReserved) && request.Queue {
+		tx.Rollback()
+		queueForBed(w, request.EmployeeID, bedRequest{
+			PatientID:  request.PatientID,
+			HospitalID: bed.HospitalID,
+			BedType:    bed.BedType,
+			Isolation:  request.Isolation,
+		}, request.Urgency, request.Notes)
 		return
 	}
-
-	// Update the BedsCount table
-	_, err = tx.Exec(
-		`UPDATE bedscount 
-		 SET OccupiedBeds = OccupiedBeds + 1, VacantBeds = VacantBeds - 1
-		 WHERE HospitalID = ? AND BedType = ?`,
-		hospitalID, bedType)
 	if err != nil {
-		log.Printf("Error updating BedsCount: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error assigning bed: %v", err)
+		}
+		sendJSONError(w, message, status)
 		return
 	}
+	bedType := bed.BedType
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
@@ -458,6 +436,7 @@ func TransferBed(w http.ResponseWriter, r *http.Request) {
 		EmployeeID int `json:"employeeId"`
 		PatientID  int `json:"patientId"`
 		NewBedID   int `json:"newBedId"`
+		Reason     string `json:"reason"`
 		Notes      string `json:"notes"`
 	}
 
@@ -486,61 +465,6 @@ func TransferBed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the current bed assignment for the patient
-	var currentAssignment struct {
-		AssignmentID int
-		BedID        int
-		BedType      string
-	}
-	err = database.DB.QueryRow(`
-		SELECT 
-			ba.AssignmentID, 
-			ba.BedID,
-			bi.BedType
-		FROM bedassignments ba
-		JOIN bedinventory bi ON ba.BedID = bi.BedID
-		WHERE ba.PatientID = ? AND ba.DischargeDate IS NULL
-	`, request.PatientID).Scan(&currentAssignment.AssignmentID, &currentAssignment.BedID, &currentAssignment.BedType)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			sendJSONError(w, "No active bed assignment found for this patient", http.StatusNotFound)
-		} else {
-			log.Printf("Error finding current assignment: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	// Check if new bed exists and is available
-	var newBedType string
-	var isBedAvailable bool
-	err = database.DB.QueryRow(`
-		SELECT 
-			bi.BedType,
-			NOT EXISTS (
-				SELECT 1 FROM bedassignments ba 
-				WHERE ba.BedID = ? AND ba.DischargeDate IS NULL
-			) AS IsAvailable
-		FROM bedinventory bi
-		WHERE bi.BedID = ?
-	`, request.NewBedID, request.NewBedID).Scan(&newBedType, &isBedAvailable)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			sendJSONError(w, "New bed not found", http.StatusNotFound)
-		} else {
-			log.Printf("Error checking bed availability: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	if !isBedAvailable {
-		sendJSONError(w, "New bed is not available", http.StatusConflict)
-		return
-	}
-
 	// Begin transaction for bed transfer
 	tx, err := database.DB.Begin()
 	if err != nil {
@@ -550,70 +474,28 @@ func TransferBed(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// Get the current admission date to preserve it
-	var admissionDate string
-	err = tx.QueryRow(
-		"SELECT DATE_FORMAT(AdmissionDate, '%Y-%m-%d') FROM bedassignments WHERE AssignmentID = ?",
-		currentAssignment.AssignmentID).Scan(&admissionDate)
-	if err != nil {
-		log.Printf("Error getting admission date: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
+	// Move the patient; the old bed goes for cleaning and both beds' counts are updated
+	current, err := currentAssignment(tx, request.PatientID)
+	if err == nil && current.Bed.HospitalID != hospitalID {
+		sendJSONError(w, "Patient is not in a bed at your hospital", http.StatusForbidden)
 		return
 	}
-
-	// Discharge from current bed
-	_, err = tx.Exec(
-		"UPDATE bedassignments SET DischargeDate = CURRENT_DATE() WHERE AssignmentID = ?",
-		currentAssignment.AssignmentID)
-	if err != nil {
-		log.Printf("Error discharging from current bed: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	// Create new assignment for the new bed
-	result, err := tx.Exec(
-		"INSERT INTO bedassignments (BedID, PatientID, AdmissionDate, DischargeDate) VALUES (?, ?, ?, NULL)",
-		request.NewBedID, request.PatientID, admissionDate)
-	if err != nil {
-		log.Printf("Error creating new bed assignment: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
+	var newAssignmentID int64
+	var newBed bedInfo
+	if err == nil {
+		newAssignmentID, newBed, err = moveBed(tx, current, request.NewBedID, time.Now().Format("2006-01-02"),
+			bedMove{Reason: request.Reason, Notes: request.Notes, ActorID: request.EmployeeID})
 	}
-
-	// Get the new assignment ID
-	newAssignmentID, err := result.LastInsertId()
 	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	// Update BedsCount if bed types differ
-	if currentAssignment.BedType != newBedType {
-		// Decrement count for old bed type
-		_, err = tx.Exec(
-			`UPDATE bedscount 
-			 SET OccupiedBeds = OccupiedBeds - 1, VacantBeds = VacantBeds + 1
-			 WHERE HospitalID = ? AND BedType = ?`,
-			hospitalID, currentAssignment.BedType)
-		if err != nil {
-			log.Printf("Error updating old bed type count: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-			return
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error transferring patient: %v", err)
 		}
-
-		// Increment count for new bed type
-		_, err = tx.Exec(
-			`UPDATE bedscount 
-			 SET OccupiedBeds = OccupiedBeds + 1, VacantBeds = VacantBeds - 1
-			 WHERE HospitalID = ? AND BedType = ?`,
-			hospitalID, newBedType)
-		if err != nil {
-			log.Printf("Error updating new bed type count: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-			return
+		if err == errNoActiveAssignment {
+			message = "No active bed assignment found for this patient"
 		}
+		sendJSONError(w, message, status)
+		return
 	}
 
 	// Commit transaction
@@ -634,10 +516,10 @@ func TransferBed(w http.ResponseWriter, r *http.Request) {
 		"assignmentId":   newAssignmentID,
 		"patientId":      request.PatientID,
 		"patientName":    patientName,
-		"oldBedId":       currentAssignment.BedID,
+		"oldBedId":       current.Bed.BedID,
 		"newBedId":       request.NewBedID,
-		"newBedType":     newBedType,
-		"admissionDate":  admissionDate,
+		"newBedType":     newBed.BedType,
+		"admissionDate":  current.AdmissionDate,
 		"transferDate":   time.Now().Format("2006-01-02"),
 	}
 
@@ -661,9 +543,11 @@ func AssignBedFromAppointment(w http.ResponseWriter, r *http.Request) {
 
 	// Parse the request body
 	var request struct {
-		EmployeeID int `json:"employeeId"`
-		PatientID  int `json:"patientId"`
-		BedID      int `json:"bedId"`
+		EmployeeID    int    `json:"employeeId"`
+		PatientID     int    `json:"patientId"`
+		BedID         int    `json:"bedId"`
+		AppointmentID int    `json:"appointmentId"` // defaults to the latest completed appointment
+		Diagnosis     string `json:"diagnosis"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -711,115 +595,53 @@ func AssignBedFromAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Verify that the patient has a completed appointment with this doctor
-	var appointmentExists bool
+	// 2. Find the patient's completed appointment with this doctor; the
+	// admission keeps it as the appointment the patient was admitted from
+	var appointmentID int
 	err = tx.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 
-			FROM appointment a
-			WHERE a.PatientID = ? 
-			AND a.DoctorID = ? 
-			AND a.Status = 'Completed'
-		)
-	`, request.PatientID, doctorID).Scan(&appointmentExists)
-
-	if err != nil {
+		SELECT a.AppointmentID
+		FROM appointment a
+		WHERE a.PatientID = ? 
+		AND a.DoctorID = ? 
+		AND a.Status = 'Completed'
+		AND (? = 0 OR a.AppointmentID = ?)
+		ORDER BY a.AppointmentDate DESC, a.AppointmentID DESC
+		LIMIT 1
+	`, request.PatientID, doctorID, request.AppointmentID, request.AppointmentID).Scan(&appointmentID)
+
+	if err != nil && err != sql.ErrNoRows {
 		log.Printf("Error checking patient appointment status: %v", err)
 		sendJSONError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	if !appointmentExists {
+	if err == sql.ErrNoRows {
 		log.Printf("No completed appointment found for Patient ID %d with Doctor ID %d", request.PatientID, doctorID)
 		sendJSONError(w, "Patient does not have a completed appointment with this doctor", http.StatusBadRequest)
 		return
 	}
 
-	// 3. Check if patient already has an active bed assignment
-	var hasExistingAssignment bool
-	err = tx.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 
-			FROM bedassignments 
-			WHERE PatientID = ? 
-			AND DischargeDate IS NULL
-		)
-	`, request.PatientID).Scan(&hasExistingAssignment)
-
-	if err != nil {
-		log.Printf("Error checking existing patient assignments: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	if hasExistingAssignment {
-		sendJSONError(w, "Patient already has an active bed assignment", http.StatusConflict)
-		return
-	}
-
-	// 4. Check if bed exists and is available
-	var bedType string
-	var isBedAvailable bool
-	err = tx.QueryRow(`
-		SELECT 
-			bi.BedType,
-			NOT EXISTS (
-				SELECT 1 FROM bedassignments ba 
-				WHERE ba.BedID = ? AND ba.DischargeDate IS NULL
-			) AS IsAvailable
-		FROM bedinventory bi
-		WHERE bi.BedID = ?
-	`, request.BedID, request.BedID).Scan(&bedType, &isBedAvailable)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			sendJSONError(w, "Bed not found", http.StatusNotFound)
-		} else {
-			log.Printf("Error checking bed availability: %v", err)
-			sendJSONError(w, "Database error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	if !isBedAvailable {
-		sendJSONError(w, "Bed is not available", http.StatusConflict)
-		return
-	}
-
-	// 5. Create the bed assignment
+	// 3. Assign the bed; this checks the patient and bed and updates BedsCount
 	currentDate := time.Now().Format("2006-01-02")
-	result, err := tx.Exec(
-		"INSERT INTO bedassignments (BedID, PatientID, AdmissionDate, DischargeDate) VALUES (?, ?, ?, NULL)",
-		request.BedID, request.PatientID, currentDate)
-	
-	if err != nil {
-		log.Printf("Error inserting bed assignment: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	// Get the new assignment ID
-	assignmentID, err := result.LastInsertId()
+	assignmentID, bed, err := occupyBed(tx, request.BedID, request.PatientID, currentDate, bedMove{
+		Reason:  "Admitted after completed appointment",
+		ActorID: request.EmployeeID,
+	}, admissionDetails{
+		AppointmentID:     appointmentID,
+		AdmittingDoctorID: doctorID,
+		Diagnosis:         request.Diagnosis,
+	})
 	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
-	// 6. Update the BedsCount table
-	_, err = tx.Exec(
-		`UPDATE bedscount 
-		 SET OccupiedBeds = OccupiedBeds + 1, VacantBeds = VacantBeds - 1
-		 WHERE HospitalID = ? AND BedType = ?`,
-		hospitalID, bedType)
-	
-	if err != nil {
-		log.Printf("Error updating BedsCount: %v", err)
-		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		status, message := bedErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error assigning bed: %v", err)
+		}
+		sendJSONError(w, message, status)
 		return
 	}
+	bedType := bed.BedType
 
-	// 7. Commit transaction
+	// 4. Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.Printf("Error committing transaction: %v", err)
 		sendJSONError(w, "Database error", http.StatusInternalServerError)
@@ -840,6 +662,7 @@ func AssignBedFromAppointment(w http.ResponseWriter, r *http.Request) {
 		"bedId":         request.BedID,
 		"bedType":       bedType,
 		"admissionDate": currentDate,
+		"appointmentId": appointmentID,
 		"status":        "current",
 	}
 
@@ -0,0 +1,936 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+)
+
+// Emergency department. A visit is registered with the least that is known
+// about the patient, triaged on a five-level acuity scale with vitals, seen
+// by a doctor and closed with a disposition. Admission goes through the bed
+// occupancy service like any other admission: straight into a free bed, or
+// into the bed request queue until one is free. Whenever a patient with an
+// open visit is given a bed, by any route, the visit is closed as admitted.
+//
+// Steps lock the patient row before the visit, the same order occupyBed
+// uses when it closes a visit.
+
+var (
+	errVisitNotFound      = errors.New("emergency visit not found")
+	errVisitState         = errors.New("emergency visit is not in a state that allows this")
+	errVisitOpen          = errors.New("patient already has an open emergency visit")
+	errVisitStaff         = errors.New("employee does not work at the visit's hospital")
+	errVisitDoctor        = errors.New("doctor does not work at the visit's hospital")
+	errVisitBedHospital   = errors.New("bed is not in the visit's hospital")
+	errPatientIdentified  = errors.New("patient is already identified")
+	errPatientEmailInUse  = errors.New("another patient already has this email")
+	errDestinationUnknown = errors.New("destination hospital not found")
+)
+
+// edInputError is a problem with the values in a step request
+type edInputError string
+
+func (e edInputError) Error() string { return string(e) }
+
+// edErrorStatus extends bedRequestErrorStatus with the emergency department errors
+func edErrorStatus(err error) (int, string) {
+	var input edInputError
+	if errors.As(err, &input) {
+		return http.StatusBadRequest, input.Error()
+	}
+	switch err {
+	case errVisitNotFound, errDestinationUnknown:
+		return http.StatusNotFound, err.Error()
+	case errVisitState, errVisitOpen, errPatientIdentified, errPatientEmailInUse:
+		return http.StatusConflict, err.Error()
+	case errVisitStaff, errVisitDoctor, errVisitBedHospital:
+		return http.StatusForbidden, err.Error()
+	}
+	return bedRequestErrorStatus(err)
+}
+
+// edOpenStatuses are the statuses of a visit still in the department
+const edOpenStatuses = "'waiting', 'triaged', 'in_treatment', 'awaiting_bed'"
+
+// edPlaceholderDomain is the email domain of patient records created in the
+// department without an email. It is reserved, so nothing is ever delivered.
+const edPlaceholderDomain = "ed.invalid"
+
+// edTriageScale names each triage level and gives the longest a patient at
+// that level should wait for a doctor
+var edTriageScale = []struct {
+	Category      string
+	TargetMinutes int
+}{
+	{"Resuscitation", 0},
+	{"Emergent", 15},
+	{"Urgent", 30},
+	{"Less urgent", 60},
+	{"Non-urgent", 120},
+}
+
+// edTriageTargetMinutes is the longest a patient should wait to be triaged
+const edTriageTargetMinutes = 10
+
+// edBedUrgency is the bed request urgency for a triage level
+func edBedUrgency(triageLevel int) string {
+	switch {
+	case triageLevel == 1 || triageLevel == 2:
+		return "emergency"
+	case triageLevel == 0 || triageLevel == 3:
+		return "urgent"
+	}
+	return "routine"
+}
+
+// validPatientGender accepts the genders the registration forms offer, stored
+// lowercase; empty leaves the gender unrecorded.
+func validPatientGender(gender string) bool {
+	switch gender {
+	case "", "male", "female", "other":
+		return true
+	}
+	return false
+}
+
+func validArrivalMode(mode string) bool {
+	switch mode {
+	case "walk-in", "ambulance", "police", "referral", "other":
+		return true
+	}
+	return false
+}
+
+const (
+	edVisitColumns = `v.VisitID, v.HospitalID, v.PatientID, p.FullName, COALESCE(p.Gender, ''), p.Unidentified,
+		v.ArrivalMode, v.ChiefComplaint, DATE_FORMAT(v.ArrivedAt, '%Y-%m-%d %H:%i'),
+		COALESCE(v.TriageLevel, 0), COALESCE(DATE_FORMAT(v.TriagedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(v.DoctorID, 0), COALESCE(d.FullName, ''), COALESCE(DATE_FORMAT(v.SeenAt, '%Y-%m-%d %H:%i'), ''),
+		v.Status, TIMESTAMPDIFF(MINUTE, v.ArrivedAt, COALESCE(v.SeenAt, v.DispositionAt, NOW())),
+		COALESCE(v.Disposition, ''), COALESCE(DATE_FORMAT(v.DispositionAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(v.DispositionNotes, ''), COALESCE(v.DestinationHospitalID, 0), COALESCE(v.DestinationName, ''),
+		COALESCE(v.BedRequestID, 0), COALESCE(v.AdmissionID, 0)`
+	edVisitJoins = `JOIN Patients p ON v.PatientID = p.PatientID
+		LEFT JOIN Doctors d ON v.DoctorID = d.DoctorID`
+
+	triageColumns = `ta.VisitID, ta.AssessmentID, ta.TriageLevel, ta.HeartRate, ta.SystolicBP, ta.DiastolicBP,
+		ta.RespiratoryRate, ta.Temperature, ta.SpO2, ta.PainScore, ta.GCS, COALESCE(ta.Notes, ''),
+		COALESCE(ta.AssessedBy, 0), DATE_FORMAT(ta.AssessedAt, '%Y-%m-%d %H:%i')`
+)
+
+// scanEDVisit reads a row selected with edVisitColumns
+func scanEDVisit(row interface{ Scan(...interface{}) error }) (models.EDVisit, error) {
+	var v models.EDVisit
+	err := row.Scan(&v.VisitID, &v.HospitalID, &v.PatientID, &v.PatientName, &v.Gender, &v.Unidentified,
+		&v.ArrivalMode, &v.ChiefComplaint, &v.ArrivedAt,
+		&v.TriageLevel, &v.TriagedAt,
+		&v.DoctorID, &v.DoctorName, &v.SeenAt,
+		&v.Status, &v.WaitMinutes,
+		&v.Disposition, &v.DispositionAt,
+		&v.DispositionNotes, &v.DestinationHospitalID, &v.DestinationName,
+		&v.BedRequestID, &v.AdmissionID)
+	if err != nil {
+		return v, err
+	}
+
+	v.TargetMinutes = edTriageTargetMinutes
+	if v.TriageLevel >= 1 && v.TriageLevel <= len(edTriageScale) {
+		v.TriageCategory = edTriageScale[v.TriageLevel-1].Category
+		v.TargetMinutes = edTriageScale[v.TriageLevel-1].TargetMinutes
+	}
+	v.Overdue = v.SeenAt == "" && v.Disposition == "" && v.WaitMinutes > v.TargetMinutes
+	return v, nil
+}
+
+// scanTriageAssessment reads a row selected with triageColumns
+func scanTriageAssessment(row interface{ Scan(...interface{}) error }) (int, models.TriageAssessment, error) {
+	var visitID int
+	var a models.TriageAssessment
+	var heartRate, systolic, diastolic, respiratory, spo2, pain, gcs sql.NullInt64
+	var temperature sql.NullFloat64
+	err := row.Scan(&visitID, &a.AssessmentID, &a.TriageLevel, &heartRate, &systolic, &diastolic,
+		&respiratory, &temperature, &spo2, &pain, &gcs, &a.Notes, &a.AssessedBy, &a.AssessedAt)
+	if err != nil {
+		return 0, a, err
+	}
+	for _, f := range []struct {
+		src *sql.NullInt64
+		dst **int
+	}{
+		{&heartRate, &a.HeartRate}, {&systolic, &a.SystolicBP}, {&diastolic, &a.DiastolicBP},
+		{&respiratory, &a.RespiratoryRate}, {&spo2, &a.SpO2}, {&pain, &a.PainScore}, {&gcs, &a.GCS},
+	} {
+		if f.src.Valid {
+			n := int(f.src.Int64)
+			*f.dst = &n
+		}
+	}
+	if temperature.Valid {
+		a.Temperature = &temperature.Float64
+	}
+	return visitID, a, nil
+}
+
+// edVisit is the locked state of a visit that a step works on
+type edVisit struct {
+	VisitID      int
+	PatientID    int
+	HospitalID   int
+	Status       string
+	TriageLevel  int
+	DoctorID     int
+	BedRequestID int
+}
+
+// isOpen reports whether the patient is still in the department
+func (v edVisit) isOpen() bool {
+	return strings.Contains(edOpenStatuses, "'"+v.Status+"'")
+}
+
+// lockEDVisit locks a visit's patient and then the visit
+func lockEDVisit(tx *sql.Tx, visitID int) (edVisit, error) {
+	v := edVisit{VisitID: visitID}
+	err := tx.QueryRow("SELECT PatientID FROM EDVisits WHERE VisitID = ?", visitID).Scan(&v.PatientID)
+	if err == sql.ErrNoRows {
+		return v, errVisitNotFound
+	}
+	if err != nil {
+		return v, err
+	}
+	var locked int
+	if err := tx.QueryRow("SELECT PatientID FROM Patients WHERE PatientID = ? FOR UPDATE", v.PatientID).Scan(&locked); err != nil {
+		return v, err
+	}
+	err = tx.QueryRow(`
+		SELECT HospitalID, Status, COALESCE(TriageLevel, 0), COALESCE(DoctorID, 0), COALESCE(BedRequestID, 0)
+		FROM EDVisits WHERE VisitID = ?
+		FOR UPDATE
+	`, visitID).Scan(&v.HospitalID, &v.Status, &v.TriageLevel, &v.DoctorID, &v.BedRequestID)
+	return v, err
+}
+
+// edStaff checks that an employee works at a hospital and returns their
+// DoctorID, 0 if they aren't a doctor
+func edStaff(employeeID, hospitalID int) (int, error) {
+	doctorID, employeeHospital, err := doctorForEmployee(employeeID)
+	if err == sql.ErrNoRows || (err == nil && employeeHospital != hospitalID) {
+		return 0, errVisitStaff
+	}
+	return doctorID, err
+}
+
+// closeEDVisitOnAdmission closes the patient's open visit, if any, as
+// admitted. occupyBed calls it with the patient lock held, so a patient given
+// a bed from the queue or straight from the bed board leaves the department.
+func closeEDVisitOnAdmission(tx *sql.Tx, patientID int, admissionID int64) error {
+	_, err := tx.Exec(`
+		UPDATE EDVisits
+		SET Status = 'admitted', AdmissionID = ?,
+		    Disposition = 'admit', DispositionAt = COALESCE(DispositionAt, NOW())
+		WHERE PatientID = ? AND Status IN (`+edOpenStatuses+`)
+	`, admissionID, patientID)
+	return err
+}
+
+// reopenEDVisitForRequest puts a visit that was waiting on a cancelled bed
+// request back under treatment, so a new disposition can be made
+func reopenEDVisitForRequest(tx *sql.Tx, requestID int) error {
+	_, err := tx.Exec(`
+		UPDATE EDVisits
+		SET Status = IF(DoctorID IS NOT NULL, 'in_treatment', IF(TriageLevel IS NOT NULL, 'triaged', 'waiting')),
+		    Disposition = NULL, DispositionAt = NULL, DispositionBy = NULL, BedRequestID = NULL
+		WHERE BedRequestID = ? AND Status = 'awaiting_bed'
+	`, requestID)
+	return err
+}
+
+// createEDPatient adds a patient record for a visit. Without an email the
+// record gets a placeholder address; without a name it is marked unidentified
+// and named after its ID until the patient is identified.
+func createEDPatient(tx *sql.Tx, p models.Patient, unidentified bool) (int64, error) {
+	if p.Email != "" && !unidentified {
+		return findOrCreatePatient(tx, p)
+	}
+	if p.FullName == "" {
+		unidentified = true
+		p.FullName = "Unidentified patient"
+	}
+	result, err := tx.Exec(`
+		INSERT INTO Patients (FullName, ContactNumber, Email, Gender, Unidentified)
+		VALUES (?, ?, CONCAT('ed-', UUID(), '@', ?), NULLIF(?, ''), ?)
+	`, p.FullName, p.ContactNumber, edPlaceholderDomain, p.Gender, unidentified)
+	if err != nil {
+		return 0, err
+	}
+	patientID, err := result.LastInsertId()
+	if err != nil || !unidentified {
+		return patientID, err
+	}
+	_, err = tx.Exec("UPDATE Patients SET FullName = CONCAT('Unidentified patient #', PatientID) WHERE PatientID = ?", patientID)
+	return patientID, err
+}
+
+// loadEDVisit reads a visit with its triage history
+func loadEDVisit(visitID int) (models.EDVisit, error) {
+	v, err := scanEDVisit(database.DB.QueryRow(`
+		SELECT `+edVisitColumns+`
+		FROM EDVisits v
+		`+edVisitJoins+`
+		WHERE v.VisitID = ?
+	`, visitID))
+	if err == sql.ErrNoRows {
+		return v, errVisitNotFound
+	}
+	if err != nil {
+		return v, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+triageColumns+`
+		FROM EDTriageAssessments ta
+		WHERE ta.VisitID = ?
+		ORDER BY ta.AssessedAt DESC, ta.AssessmentID DESC
+	`, visitID)
+	if err != nil {
+		return v, err
+	}
+	defer rows.Close()
+
+	v.Assessments = []models.TriageAssessment{}
+	for rows.Next() {
+		_, a, err := scanTriageAssessment(rows)
+		if err != nil {
+			return v, err
+		}
+		v.Assessments = append(v.Assessments, a)
+	}
+	if len(v.Assessments) > 0 {
+		v.LatestVitals = &v.Assessments[0]
+	}
+	return v, rows.Err()
+}
+
+// RegisterEDVisit registers a patient arriving at the emergency department.
+// An existing patient is given by patientId; otherwise a record is created
+// from whatever is known, and unidentified=true (or no name) registers an
+// unidentified patient.
+func RegisterEDVisit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req struct {
+		EmployeeID     int    `json:"employeeId"`
+		PatientID      int    `json:"patientId"`
+		FullName       string `json:"fullName"`
+		ContactNumber  string `json:"contactNumber"`
+		Email          string `json:"email"`
+		Gender         string `json:"gender"`
+		Unidentified   bool   `json:"unidentified"`
+		ChiefComplaint string `json:"chiefComplaint"`
+		ArrivalMode    string `json:"arrivalMode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.ChiefComplaint = strings.TrimSpace(req.ChiefComplaint)
+	if req.EmployeeID == 0 || req.ChiefComplaint == "" {
+		sendJSONError(w, "employeeId and chiefComplaint are required", http.StatusBadRequest)
+		return
+	}
+	if len(req.ChiefComplaint) > 255 {
+		sendJSONError(w, "chiefComplaint must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+	if req.ArrivalMode == "" {
+		req.ArrivalMode = "walk-in"
+	}
+	if !validArrivalMode(req.ArrivalMode) {
+		sendJSONError(w, "arrivalMode must be walk-in, ambulance, police, referral or other", http.StatusBadRequest)
+		return
+	}
+	req.Gender = strings.ToLower(strings.TrimSpace(req.Gender))
+	if !validPatientGender(req.Gender) {
+		sendJSONError(w, "gender must be male, female or other", http.StatusBadRequest)
+		return
+	}
+
+	_, hospitalID, err := doctorForEmployee(req.EmployeeID)
+	if err == sql.ErrNoRows {
+		sendJSONError(w, "Employee not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching registering employee: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var visitID int64
+	err = withTx(func(tx *sql.Tx) error {
+		patientID := int64(req.PatientID)
+		if patientID == 0 {
+			var err error
+			patientID, err = createEDPatient(tx, models.Patient{
+				FullName:      strings.TrimSpace(req.FullName),
+				ContactNumber: strings.TrimSpace(req.ContactNumber),
+				Email:         strings.TrimSpace(req.Email),
+				Gender:        req.Gender,
+			}, req.Unidentified)
+			if err != nil {
+				return err
+			}
+		} else {
+			var locked int
+			err := tx.QueryRow("SELECT PatientID FROM Patients WHERE PatientID = ? FOR UPDATE", patientID).Scan(&locked)
+			if err == sql.ErrNoRows {
+				return errPatientNotFound
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO EDVisits (HospitalID, PatientID, ArrivalMode, ChiefComplaint, ArrivedAt, RegisteredBy)
+			VALUES (?, ?, ?, ?, NOW(), ?)
+		`, hospitalID, patientID, req.ArrivalMode, req.ChiefComplaint, req.EmployeeID)
+		if err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+				return errVisitOpen
+			}
+			return err
+		}
+		visitID, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		status, message := edErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error registering emergency visit: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	v, err := loadEDVisit(int(visitID))
+	if err != nil {
+		log.Printf("Error fetching emergency visit %d: %v", visitID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Emergency visit %d registered for patient %d", visitID, v.PatientID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+// GetEDBoard returns the patients in the department for a hospitalId,
+// untriaged patients first, then by triage level and longest wait, with each
+// patient's latest vitals and the count at each level
+func GetEDBoard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	hospitalID, err := strconv.Atoi(r.URL.Query().Get("hospitalId"))
+	if err != nil {
+		sendJSONError(w, "hospitalId is required", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+edVisitColumns+`
+		FROM EDVisits v
+		`+edVisitJoins+`
+		WHERE v.HospitalID = ? AND v.Status IN (`+edOpenStatuses+`)
+		ORDER BY v.TriageLevel IS NOT NULL, v.TriageLevel, v.ArrivedAt, v.VisitID
+	`, hospitalID)
+	if err != nil {
+		log.Printf("Error querying emergency board: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	visits := []models.EDVisit{}
+	index := make(map[int]int)
+	byLevel := map[string]int{"untriaged": 0}
+	for _, level := range edTriageScale {
+		byLevel[level.Category] = 0
+	}
+	overdue := 0
+	for rows.Next() {
+		v, err := scanEDVisit(rows)
+		if err != nil {
+			log.Printf("Error scanning emergency visit row: %v", err)
+			continue
+		}
+		if v.TriageCategory == "" {
+			byLevel["untriaged"]++
+		} else {
+			byLevel[v.TriageCategory]++
+		}
+		if v.Overdue {
+			overdue++
+		}
+		index[v.VisitID] = len(visits)
+		visits = append(visits, v)
+	}
+	rows.Close()
+
+	// Latest vitals of each visit on the board
+	rows, err = database.DB.Query(`
+		SELECT `+triageColumns+`
+		FROM EDTriageAssessments ta
+		WHERE ta.AssessmentID IN (
+			SELECT MAX(a.AssessmentID)
+			FROM EDTriageAssessments a
+			JOIN EDVisits v ON a.VisitID = v.VisitID
+			WHERE v.HospitalID = ? AND v.Status IN (`+edOpenStatuses+`)
+			GROUP BY a.VisitID
+		)
+	`, hospitalID)
+	if err != nil {
+		log.Printf("Error querying emergency triage vitals: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		visitID, a, err := scanTriageAssessment(rows)
+		if err != nil {
+			log.Printf("Error scanning triage assessment row: %v", err)
+			continue
+		}
+		if i, ok := index[visitID]; ok {
+			visits[i].LatestVitals = &a
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hospitalId":  hospitalID,
+		"generatedAt": time.Now().Format("2006-01-02 15:04"),
+		"total":       len(visits),
+		"overdue":     overdue,
+		"byLevel":     byLevel,
+		"visits":      visits,
+	})
+}
+
+// GetEDVisit returns a visit with its triage history
+func GetEDVisit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	visitID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+	v, err := loadEDVisit(visitID)
+	if err != nil {
+		status, message := edErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error fetching emergency visit %d: %v", visitID, err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+	json.NewEncoder(w).Encode(v)
+}
+
+// edStepRequest is the body accepted by every visit step; each step uses the
+// fields that apply to it
+type edStepRequest struct {
+	EmployeeID int    `json:"employeeId"`
+	Notes      string `json:"notes"`
+
+	// Triage
+	TriageLevel     int      `json:"triageLevel"`
+	HeartRate       *int     `json:"heartRate"`
+	SystolicBP      *int     `json:"systolicBP"`
+	DiastolicBP     *int     `json:"diastolicBP"`
+	RespiratoryRate *int     `json:"respiratoryRate"`
+	Temperature     *float64 `json:"temperature"`
+	SpO2            *int     `json:"spo2"`
+	PainScore       *int     `json:"painScore"`
+	GCS             *int     `json:"gcs"`
+
+	// Doctor assignment; defaults to the employee when they are a doctor
+	DoctorID int `json:"doctorId"`
+
+	// Disposition
+	Disposition           string `json:"disposition"` // admit, discharge, transfer, left
+	BedID                 int    `json:"bedId"`       // admit straight into this bed
+	BedType               string `json:"bedType"`     // or queue for a bed of this type
+	Isolation             bool   `json:"isolation"`
+	Diagnosis             string `json:"diagnosis"`
+	DestinationHospitalID int    `json:"destinationHospitalId"`
+	DestinationName       string `json:"destinationName"`
+
+	// Identification
+	Patient models.Patient `json:"patient"`
+}
+
+// validateVitals checks triage values against physiological limits
+func (req edStepRequest) validateVitals() error {
+	if req.TriageLevel < 1 || req.TriageLevel > len(edTriageScale) {
+		return edInputError("triageLevel must be between 1 and 5")
+	}
+	for _, f := range []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"heartRate", req.HeartRate, 0, 300},
+		{"systolicBP", req.SystolicBP, 0, 300},
+		{"diastolicBP", req.DiastolicBP, 0, 200},
+		{"respiratoryRate", req.RespiratoryRate, 0, 80},
+		{"spo2", req.SpO2, 0, 100},
+		{"painScore", req.PainScore, 0, 10},
+		{"gcs", req.GCS, 3, 15},
+	} {
+		if f.value != nil && (*f.value < f.min || *f.value > f.max) {
+			return edInputError(fmt.Sprintf("%s must be between %d and %d", f.name, f.min, f.max))
+		}
+	}
+	if req.Temperature != nil && (*req.Temperature < 25 || *req.Temperature > 45) {
+		return edInputError("temperature must be between 25 and 45 degrees Celsius")
+	}
+	return nil
+}
+
+// edStep advances a locked visit. doctorID is the employee's DoctorID, 0 if
+// they aren't a doctor.
+type edStep func(tx *sql.Tx, v edVisit, doctorID int, req edStepRequest) error
+
+// runEDStep decodes a step request, runs the step in a transaction and
+// responds with the updated visit. A visit left waiting for a bed comes with
+// the allocator's current suggestion.
+func runEDStep(w http.ResponseWriter, r *http.Request, message string, step edStep) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	visitID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid visit ID", http.StatusBadRequest)
+		return
+	}
+	var req edStepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding emergency visit step: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EmployeeID == 0 {
+		sendJSONError(w, "employeeId is required", http.StatusBadRequest)
+		return
+	}
+
+	err = withTx(func(tx *sql.Tx) error {
+		v, err := lockEDVisit(tx, visitID)
+		if err != nil {
+			return err
+		}
+		doctorID, err := edStaff(req.EmployeeID, v.HospitalID)
+		if err != nil {
+			return err
+		}
+		return step(tx, v, doctorID, req)
+	})
+	if err != nil {
+		status, message := edErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error updating emergency visit %d: %v", visitID, err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	v, err := loadEDVisit(visitID)
+	if err != nil {
+		log.Printf("Error fetching emergency visit %d: %v", visitID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	response := map[string]interface{}{
+		"success": true,
+		"message": message,
+		"visit":   v,
+	}
+	if v.Status == "awaiting_bed" && v.BedRequestID != 0 {
+		if bedReq, _, err := loadBedRequest(database.DB, v.BedRequestID); err == nil {
+			if suggestion, err := suggestBed(database.DB, bedReq); err == nil {
+				response["suggestion"] = suggestion
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// TriageEDVisit records a triage assessment with vitals and sets the visit's
+// triage level. A patient can be re-triaged while in the department.
+func TriageEDVisit(w http.ResponseWriter, r *http.Request) {
+	runEDStep(w, r, "Patient triaged", func(tx *sql.Tx, v edVisit, doctorID int, req edStepRequest) error {
+		if !v.isOpen() {
+			return errVisitState
+		}
+		if err := req.validateVitals(); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO EDTriageAssessments
+				(VisitID, TriageLevel, HeartRate, SystolicBP, DiastolicBP, RespiratoryRate,
+				 Temperature, SpO2, PainScore, GCS, Notes, AssessedBy)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+		`, v.VisitID, req.TriageLevel, req.HeartRate, req.SystolicBP, req.DiastolicBP, req.RespiratoryRate,
+			req.Temperature, req.SpO2, req.PainScore, req.GCS, req.Notes, req.EmployeeID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE EDVisits
+			SET TriageLevel = ?, TriagedAt = COALESCE(TriagedAt, NOW()),
+			    Status = IF(Status = 'waiting', 'triaged', Status)
+			WHERE VisitID = ?
+		`, req.TriageLevel, v.VisitID)
+		if err != nil || v.BedRequestID == 0 {
+			return err
+		}
+		// Keep a queued bed request as urgent as the patient
+		_, err = tx.Exec("UPDATE BedRequests SET Urgency = ? WHERE RequestID = ? AND Status = 'pending'",
+			edBedUrgency(req.TriageLevel), v.BedRequestID)
+		return err
+	})
+}
+
+// AssignEDDoctor assigns the doctor responsible for a visit. The first
+// assignment marks the patient as seen.
+func AssignEDDoctor(w http.ResponseWriter, r *http.Request) {
+	runEDStep(w, r, "Doctor assigned", func(tx *sql.Tx, v edVisit, doctorID int, req edStepRequest) error {
+		if !v.isOpen() {
+			return errVisitState
+		}
+		if req.DoctorID == 0 {
+			req.DoctorID = doctorID
+		}
+		if req.DoctorID == 0 {
+			return edInputError("doctorId is required")
+		}
+		works, err := doctorWorksAt(tx, req.DoctorID, v.HospitalID)
+		if err != nil {
+			return err
+		}
+		if !works {
+			return errVisitDoctor
+		}
+		_, err = tx.Exec(`
+			UPDATE EDVisits
+			SET DoctorID = ?, SeenAt = COALESCE(SeenAt, NOW()),
+			    Status = IF(Status IN ('waiting', 'triaged'), 'in_treatment', Status)
+			WHERE VisitID = ?
+		`, req.DoctorID, v.VisitID)
+		return err
+	})
+}
+
+// DisposeEDVisit closes a visit: admit to bedId, or queue for a bed of
+// bedType; discharge; transfer to destinationHospitalId or destinationName;
+// or left, for a patient who left before the visit was completed. A visit
+// waiting for a bed can still be given a bed directly or another disposition,
+// which cancels its bed request.
+func DisposeEDVisit(w http.ResponseWriter, r *http.Request) {
+	runEDStep(w, r, "Disposition recorded", func(tx *sql.Tx, v edVisit, doctorID int, req edStepRequest) error {
+		if !v.isOpen() {
+			return errVisitState
+		}
+
+		status := map[string]string{
+			"admit":     "admitted",
+			"discharge": "discharged",
+			"transfer":  "transferred",
+			"left":      "left",
+		}[req.Disposition]
+		if status == "" {
+			return edInputError("disposition must be admit, discharge, transfer or left")
+		}
+		if req.Disposition == "admit" && req.BedID == 0 {
+			if req.BedType == "" {
+				return edInputError("bedId or bedType is required to admit")
+			}
+			if v.Status == "awaiting_bed" {
+				return errVisitState
+			}
+		}
+		if req.Disposition == "transfer" {
+			req.DestinationName = strings.TrimSpace(req.DestinationName)
+			if req.DestinationHospitalID == 0 && req.DestinationName == "" {
+				return edInputError("destinationHospitalId or destinationName is required for a transfer")
+			}
+			if req.DestinationHospitalID == v.HospitalID {
+				return edInputError("destination must be another hospital")
+			}
+			if req.DestinationHospitalID != 0 {
+				var exists bool
+				err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM Hospital WHERE HospitalID = ?)", req.DestinationHospitalID).Scan(&exists)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return errDestinationUnknown
+				}
+			}
+		}
+
+		// A patient waiting for a bed who is given one directly takes it
+		// through their request, which is then fulfilled
+		queued := v.BedRequestID != 0 && v.Status == "awaiting_bed"
+		if queued && req.Disposition != "admit" {
+			reason := fmt.Sprintf("Emergency visit #%d: disposition changed to %s", v.VisitID, req.Disposition)
+			if err := cancelBedRequest(tx, v.BedRequestID, reason); err != nil && err != errBedRequestClosed {
+				return err
+			}
+		}
+
+		// The admitting doctor is the doctor treating the patient, or the
+		// doctor making the disposition
+		admittingDoctorID := v.DoctorID
+		if admittingDoctorID == 0 {
+			admittingDoctorID = doctorID
+		}
+
+		if req.Disposition == "admit" && req.BedID == 0 {
+			requestID, err := createBedRequest(tx, bedRequest{
+				PatientID:  v.PatientID,
+				HospitalID: v.HospitalID,
+				BedType:    req.BedType,
+				Isolation:  req.Isolation,
+			}, edBedUrgency(v.TriageLevel), admittingDoctorID,
+				strings.TrimSpace(fmt.Sprintf("Emergency visit #%d %s", v.VisitID, req.Notes)))
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`
+				UPDATE EDVisits
+				SET Status = 'awaiting_bed', BedRequestID = ?, Disposition = 'admit', DispositionAt = NOW(),
+				    DispositionBy = ?, DispositionNotes = NULLIF(?, '')
+				WHERE VisitID = ?
+			`, requestID, req.EmployeeID, req.Notes, v.VisitID)
+			return err
+		}
+
+		if req.Disposition == "admit" {
+			var bedHospitalID int
+			err := tx.QueryRow("SELECT HospitalID FROM BedInventory WHERE BedID = ?", req.BedID).Scan(&bedHospitalID)
+			if err == sql.ErrNoRows {
+				return errBedNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if bedHospitalID != v.HospitalID {
+				return errVisitBedHospital
+			}
+			// occupyBed closes the visit as admitted
+			if queued {
+				_, err = allocateBedRequest(tx, v.BedRequestID, req.BedID, time.Now().Format("2006-01-02"), req.EmployeeID)
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec("UPDATE EDVisits SET DispositionNotes = COALESCE(NULLIF(?, ''), DispositionNotes) WHERE VisitID = ?",
+					req.Notes, v.VisitID)
+				return err
+			}
+			_, _, err = occupyBed(tx, req.BedID, v.PatientID, time.Now().Format("2006-01-02"), bedMove{
+				Reason:  fmt.Sprintf("Admitted from emergency visit #%d", v.VisitID),
+				Notes:   req.Notes,
+				ActorID: req.EmployeeID,
+			}, admissionDetails{AdmittingDoctorID: admittingDoctorID, Diagnosis: req.Diagnosis})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`
+			UPDATE EDVisits
+			SET Status = ?, Disposition = ?, DispositionAt = NOW(), DispositionBy = ?,
+			    DispositionNotes = NULLIF(?, ''), DestinationHospitalID = NULLIF(?, 0), DestinationName = NULLIF(?, '')
+			WHERE VisitID = ?
+		`, status, req.Disposition, req.EmployeeID, req.Notes, req.DestinationHospitalID, req.DestinationName, v.VisitID)
+		return err
+	})
+}
+
+// IdentifyEDPatient fills in the details of a patient registered without
+// them. The placeholder record is updated in place, so the visit and any
+// admission keep pointing at it.
+func IdentifyEDPatient(w http.ResponseWriter, r *http.Request) {
+	runEDStep(w, r, "Patient details updated", func(tx *sql.Tx, v edVisit, doctorID int, req edStepRequest) error {
+		p := req.Patient
+		p.FullName = strings.TrimSpace(p.FullName)
+		p.Email = strings.TrimSpace(p.Email)
+		if p.FullName == "" {
+			return edInputError("patient.full_name is required")
+		}
+		p.Gender = strings.ToLower(strings.TrimSpace(p.Gender))
+		if !validPatientGender(p.Gender) {
+			return edInputError("patient.gender must be male, female or other")
+		}
+
+		var unidentified bool
+		var email string
+		err := tx.QueryRow("SELECT Unidentified, Email FROM Patients WHERE PatientID = ?", v.PatientID).Scan(&unidentified, &email)
+		if err != nil {
+			return err
+		}
+		placeholder := strings.HasSuffix(email, "@"+edPlaceholderDomain)
+		if !unidentified && !placeholder {
+			return errPatientIdentified
+		}
+		if p.Email == "" {
+			p.Email = email
+		}
+
+		_, err = tx.Exec(`
+			UPDATE Patients
+			SET FullName = ?, ContactNumber = COALESCE(NULLIF(?, ''), ContactNumber), Email = ?,
+			    Gender = COALESCE(NULLIF(?, ''), Gender), Address = COALESCE(NULLIF(?, ''), Address),
+			    City = COALESCE(NULLIF(?, ''), City), State = COALESCE(NULLIF(?, ''), State),
+			    PinCode = COALESCE(NULLIF(?, ''), PinCode), Adhar = COALESCE(NULLIF(?, ''), Adhar),
+			    Unidentified = FALSE
+			WHERE PatientID = ?
+		`, p.FullName, p.ContactNumber, p.Email, p.Gender, p.Address,
+			p.City, p.State, p.PinCode, p.Adhar, v.PatientID)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return errPatientEmailInUse
+		}
+		return err
+	})
+}
@@ -0,0 +1,537 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Housekeeping for bed turnover. recordBedStatus opens a task whenever a bed
+// goes for cleaning, so every discharge, transfer and transfer-out leaves a
+// task behind, and closes it when the bed leaves cleaning by some other route.
+// Tasks are assigned to staff, started and completed; completing one returns
+// the bed to available. A second person then verifies the bed, and a failed
+// check sends it back for cleaning on the same task.
+
+var (
+	errTaskNotFound = errors.New("housekeeping task not found")
+	errTaskState    = errors.New("housekeeping task is not in a state that allows this")
+	errTaskAssignee = errors.New("task is assigned to someone else")
+	errTaskVerifier = errors.New("a task must be verified by someone other than the person who completed it")
+	errTaskStaff    = errors.New("employee does not work at the bed's hospital")
+)
+
+// housekeepingErrorStatus extends bedErrorStatus with the housekeeping errors
+func housekeepingErrorStatus(err error) (int, string) {
+	switch err {
+	case errTaskNotFound:
+		return http.StatusNotFound, err.Error()
+	case errTaskState, errTaskAssignee, errTaskVerifier:
+		return http.StatusConflict, err.Error()
+	case errTaskStaff:
+		return http.StatusForbidden, err.Error()
+	}
+	return bedErrorStatus(err)
+}
+
+// openTaskStatuses are the statuses of a task whose bed is still being cleaned
+const openTaskStatuses = "'pending', 'assigned', 'in_progress'"
+
+// openHousekeepingTask creates a task for a bed that has gone for cleaning,
+// unless it already has one. The caller holds the bed lock.
+func openHousekeepingTask(tx *sql.Tx, bedID int, from, reason string) error {
+	var open bool
+	err := tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM HousekeepingTasks WHERE BedID = ? AND Status IN ("+openTaskStatuses+"))",
+		bedID).Scan(&open)
+	if err != nil || open {
+		return err
+	}
+	source := "manual"
+	if from == "occupied" {
+		source = "turnover"
+	}
+	_, err = tx.Exec(`
+		INSERT INTO HousekeepingTasks (BedID, HospitalID, Source, Reason)
+		SELECT BedID, HospitalID, ?, NULLIF(?, '') FROM BedInventory WHERE BedID = ?
+	`, source, reason, bedID)
+	return err
+}
+
+// closeHousekeepingTask closes the open task of a bed that has left cleaning
+// without going through the task: it is completed if the bed is available
+// again and cancelled otherwise. The caller holds the bed lock.
+func closeHousekeepingTask(tx *sql.Tx, bedID int, to string, changedBy int) error {
+	if to == "available" {
+		_, err := tx.Exec(`
+			UPDATE HousekeepingTasks
+			SET Status = 'completed', CompletedAt = NOW(), CompletedBy = NULLIF(?, 0)
+			WHERE BedID = ? AND Status IN (`+openTaskStatuses+`)
+		`, changedBy, bedID)
+		return err
+	}
+	_, err := tx.Exec(`
+		UPDATE HousekeepingTasks
+		SET Status = 'cancelled', Notes = CONCAT_WS('\n', Notes, ?)
+		WHERE BedID = ? AND Status IN (`+openTaskStatuses+`)
+	`, "Bed changed to "+to, bedID)
+	return err
+}
+
+// housekeepingTask is the part of a task the workflow steps need
+type housekeepingTask struct {
+	TaskID      int
+	BedID       int
+	HospitalID  int
+	Status      string
+	AssignedTo  int
+	CompletedBy int
+	BedStatus   string
+}
+
+// lockHousekeepingTask locks a task's bed and then the task, in the same
+// order as recordBedStatus, so steps can't deadlock with bed changes
+func lockHousekeepingTask(tx *sql.Tx, taskID int) (housekeepingTask, error) {
+	t := housekeepingTask{TaskID: taskID}
+	err := tx.QueryRow("SELECT BedID FROM HousekeepingTasks WHERE TaskID = ?", taskID).Scan(&t.BedID)
+	if err == sql.ErrNoRows {
+		return t, errTaskNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+	err = tx.QueryRow("SELECT Status FROM BedInventory WHERE BedID = ? FOR UPDATE", t.BedID).Scan(&t.BedStatus)
+	if err != nil {
+		return t, err
+	}
+	err = tx.QueryRow(`
+		SELECT HospitalID, Status, COALESCE(AssignedTo, 0), COALESCE(CompletedBy, 0)
+		FROM HousekeepingTasks WHERE TaskID = ?
+		FOR UPDATE
+	`, taskID).Scan(&t.HospitalID, &t.Status, &t.AssignedTo, &t.CompletedBy)
+	return t, err
+}
+
+// checkTaskStaff checks that an employee works at the task's hospital
+func checkTaskStaff(employeeID, hospitalID int) error {
+	_, employeeHospital, err := doctorForEmployee(employeeID)
+	if err == sql.ErrNoRows || (err == nil && employeeHospital != hospitalID) {
+		return errTaskStaff
+	}
+	return err
+}
+
+const (
+	housekeepingColumns = `t.TaskID, t.BedID, t.HospitalID, ` + bedWardColumns + `, t.Source, COALESCE(t.Reason, ''), t.Status,
+		COALESCE(t.AssignedTo, 0), COALESCE(e.FullName, ''),
+		COALESCE(DATE_FORMAT(t.AssignedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(DATE_FORMAT(t.StartedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(DATE_FORMAT(t.CompletedAt, '%Y-%m-%d %H:%i'), ''), COALESCE(t.CompletedBy, 0),
+		COALESCE(DATE_FORMAT(t.VerifiedAt, '%Y-%m-%d %H:%i'), ''), COALESCE(t.VerifiedBy, 0),
+		t.FailedChecks, COALESCE(t.Notes, ''), DATE_FORMAT(t.CreatedAt, '%Y-%m-%d %H:%i')`
+	housekeepingJoins = `JOIN BedInventory bi ON t.BedID = bi.BedID
+		` + bedWardJoins + `
+		LEFT JOIN Employees e ON t.AssignedTo = e.EmployeeID`
+)
+
+// scanHousekeepingTask reads a row selected with housekeepingColumns
+func scanHousekeepingTask(row interface{ Scan(...interface{}) error }) (models.HousekeepingTask, error) {
+	var t models.HousekeepingTask
+	var p bedPlacement
+	dest := append([]interface{}{&t.TaskID, &t.BedID, &t.HospitalID}, p.dest()...)
+	dest = append(dest, &t.Source, &t.Reason, &t.Status,
+		&t.AssignedTo, &t.AssigneeName, &t.AssignedAt, &t.StartedAt,
+		&t.CompletedAt, &t.CompletedBy, &t.VerifiedAt, &t.VerifiedBy,
+		&t.FailedChecks, &t.Notes, &t.CreatedAt)
+	if err := row.Scan(dest...); err != nil {
+		return t, err
+	}
+	t.WardID, t.WardName = p.WardID, p.WardName
+	t.Location = bedLocation(t.BedID, p.WardName, p.RoomNumber, p.Label)
+	return t, nil
+}
+
+// GetHousekeepingTasks lists housekeeping tasks, oldest first. By default
+// only open tasks are listed; status=all includes closed ones. Filters:
+// hospitalId, wardId, assignedTo.
+func GetHousekeepingTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	var conditions []string
+	var args []interface{}
+	switch status := query.Get("status"); status {
+	case "":
+		conditions = append(conditions, "t.Status IN ("+openTaskStatuses+")")
+	case "all":
+	default:
+		conditions = append(conditions, "t.Status = ?")
+		args = append(args, status)
+	}
+	for name, column := range map[string]string{
+		"hospitalId": "t.HospitalID",
+		"wardId":     "wd.WardID",
+		"assignedTo": "t.AssignedTo",
+	} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				sendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			conditions = append(conditions, column+" = ?")
+			args = append(args, id)
+		}
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+housekeepingColumns+`
+		FROM HousekeepingTasks t
+		`+housekeepingJoins+`
+		`+where+`
+		ORDER BY t.CreatedAt, t.TaskID
+	`, args...)
+	if err != nil {
+		log.Printf("Error querying housekeeping tasks: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.HousekeepingTask{}
+	for rows.Next() {
+		t, err := scanHousekeepingTask(rows)
+		if err != nil {
+			log.Printf("Error scanning housekeeping task row: %v", err)
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+
+	json.NewEncoder(w).Encode(tasks)
+}
+
+// housekeepingStepRequest is the body accepted by every task step; each step
+// uses the fields that apply to it
+type housekeepingStepRequest struct {
+	EmployeeID int    `json:"employeeId"`
+	AssigneeID int    `json:"assigneeId"`
+	Passed     *bool  `json:"passed"`
+	Notes      string `json:"notes"`
+}
+
+// housekeepingStep advances a locked task. It returns the ID of a bed it made
+// available, if any, so the bed can be offered to the queue after commit.
+type housekeepingStep func(tx *sql.Tx, t housekeepingTask, req housekeepingStepRequest) (int, error)
+
+// runHousekeepingStep decodes a step request, runs the step in a transaction
+// and responds with the updated task
+func runHousekeepingStep(w http.ResponseWriter, r *http.Request, message string, step housekeepingStep) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	taskID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	var req housekeepingStepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding housekeeping step: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EmployeeID == 0 {
+		sendJSONError(w, "employeeId is required", http.StatusBadRequest)
+		return
+	}
+
+	var freedBedID int
+	err = withTx(func(tx *sql.Tx) error {
+		t, err := lockHousekeepingTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkTaskStaff(req.EmployeeID, t.HospitalID); err != nil {
+			return err
+		}
+		freedBedID, err = step(tx, t, req)
+		return err
+	})
+	if err != nil {
+		status, message := housekeepingErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error updating housekeeping task %d: %v", taskID, err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+	if freedBedID != 0 {
+		go fillBedFromQueue(freedBedID)
+	}
+
+	t, err := scanHousekeepingTask(database.DB.QueryRow(`
+		SELECT `+housekeepingColumns+`
+		FROM HousekeepingTasks t
+		`+housekeepingJoins+`
+		WHERE t.TaskID = ?
+	`, taskID))
+	if err != nil {
+		log.Printf("Error fetching housekeeping task %d: %v", taskID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+		"task":    t,
+	})
+}
+
+// AssignHousekeepingTask assigns or reassigns an open task to a member of staff
+func AssignHousekeepingTask(w http.ResponseWriter, r *http.Request) {
+	runHousekeepingStep(w, r, "Task assigned", func(tx *sql.Tx, t housekeepingTask, req housekeepingStepRequest) (int, error) {
+		if t.Status != "pending" && t.Status != "assigned" && t.Status != "in_progress" {
+			return 0, errTaskState
+		}
+		if req.AssigneeID == 0 {
+			req.AssigneeID = req.EmployeeID
+		}
+		if err := checkTaskStaff(req.AssigneeID, t.HospitalID); err != nil {
+			return 0, err
+		}
+		_, err := tx.Exec(`
+			UPDATE HousekeepingTasks
+			SET AssignedTo = ?, AssignedAt = NOW(), Status = IF(Status = 'pending', 'assigned', Status)
+			WHERE TaskID = ?
+		`, req.AssigneeID, t.TaskID)
+		return 0, err
+	})
+}
+
+// StartHousekeepingTask records that cleaning has begun. An unassigned task
+// is taken by the employee starting it.
+func StartHousekeepingTask(w http.ResponseWriter, r *http.Request) {
+	runHousekeepingStep(w, r, "Cleaning started", func(tx *sql.Tx, t housekeepingTask, req housekeepingStepRequest) (int, error) {
+		if t.Status != "pending" && t.Status != "assigned" {
+			return 0, errTaskState
+		}
+		if t.AssignedTo != 0 && t.AssignedTo != req.EmployeeID {
+			return 0, errTaskAssignee
+		}
+		_, err := tx.Exec(`
+			UPDATE HousekeepingTasks
+			SET Status = 'in_progress', StartedAt = NOW(),
+			    AssignedTo = ?, AssignedAt = COALESCE(AssignedAt, NOW())
+			WHERE TaskID = ?
+		`, req.EmployeeID, t.TaskID)
+		return 0, err
+	})
+}
+
+// CompleteHousekeepingTask records that the bed is clean and returns it to
+// available
+func CompleteHousekeepingTask(w http.ResponseWriter, r *http.Request) {
+	runHousekeepingStep(w, r, "Cleaning completed; the bed is available again", func(tx *sql.Tx, t housekeepingTask, req housekeepingStepRequest) (int, error) {
+		if t.Status != "pending" && t.Status != "assigned" && t.Status != "in_progress" {
+			return 0, errTaskState
+		}
+		if t.AssignedTo != 0 && t.AssignedTo != req.EmployeeID {
+			return 0, errTaskAssignee
+		}
+		if t.BedStatus != "cleaning" {
+			return 0, errTaskState
+		}
+		_, err := tx.Exec(`
+			UPDATE HousekeepingTasks
+			SET Status = 'completed', CompletedAt = NOW(), CompletedBy = ?,
+			    StartedAt = COALESCE(StartedAt, NOW()), AssignedTo = ?, AssignedAt = COALESCE(AssignedAt, NOW()),
+			    Notes = CONCAT_WS('\n', Notes, NULLIF(?, ''))
+			WHERE TaskID = ?
+		`, req.EmployeeID, req.EmployeeID, req.Notes, t.TaskID)
+		if err != nil {
+			return 0, err
+		}
+		err = recordBedStatus(tx, t.BedID, "cleaning", "available",
+			fmt.Sprintf("Cleaning completed (task #%d)", t.TaskID), req.EmployeeID)
+		return t.BedID, err
+	})
+}
+
+// VerifyHousekeepingTask records a check of a completed task by someone other
+// than the cleaner. A failed check sends the bed back for cleaning on the same
+// task, as long as no patient has taken it yet.
+func VerifyHousekeepingTask(w http.ResponseWriter, r *http.Request) {
+	runHousekeepingStep(w, r, "Task verified", func(tx *sql.Tx, t housekeepingTask, req housekeepingStepRequest) (int, error) {
+		if t.Status != "completed" {
+			return 0, errTaskState
+		}
+		if req.EmployeeID == t.CompletedBy {
+			return 0, errTaskVerifier
+		}
+		if req.Passed == nil || *req.Passed {
+			_, err := tx.Exec(`
+				UPDATE HousekeepingTasks
+				SET Status = 'verified', VerifiedAt = NOW(), VerifiedBy = ?, Notes = CONCAT_WS('\n', Notes, NULLIF(?, ''))
+				WHERE TaskID = ?
+			`, req.EmployeeID, req.Notes, t.TaskID)
+			return 0, err
+		}
+
+		if t.BedStatus != "available" {
+			return 0, errBedUnavailable
+		}
+		_, err := tx.Exec(`
+			UPDATE HousekeepingTasks
+			SET Status = IF(AssignedTo IS NULL, 'pending', 'assigned'), FailedChecks = FailedChecks + 1,
+			    StartedAt = NULL, CompletedAt = NULL, CompletedBy = NULL,
+			    Notes = CONCAT_WS('\n', Notes, ?)
+			WHERE TaskID = ?
+		`, strings.TrimSpace("Failed check: "+req.Notes), t.TaskID)
+		if err != nil {
+			return 0, err
+		}
+		// The task is open again, so no second task is created for the bed
+		return 0, recordBedStatus(tx, t.BedID, "available", "cleaning",
+			fmt.Sprintf("Failed cleaning check (task #%d)", t.TaskID), req.EmployeeID)
+	})
+}
+
+// GetHousekeepingReport returns bed turnaround per ward for tasks completed
+// between from and to (inclusive, default the last 30 days), optionally for
+// one hospitalId. Turnaround runs from the bed going for cleaning to the task
+// being completed; response is the wait until cleaning started.
+func GetHousekeepingReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	to := time.Now().Format("2006-01-02")
+	from := time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+	for name, dst := range map[string]*string{"from": &from, "to": &to} {
+		if value := query.Get(name); value != "" {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				sendJSONError(w, "Invalid "+name+" date, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*dst = value
+		}
+	}
+	hospitalID := 0
+	if value := query.Get("hospitalId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			sendJSONError(w, "Invalid hospitalId", http.StatusBadRequest)
+			return
+		}
+		hospitalID = id
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT COALESCE(wd.WardID, 0), COALESCE(wd.Name, 'Unassigned'), COUNT(*),
+		       AVG(TIMESTAMPDIFF(MINUTE, t.CreatedAt, t.CompletedAt)),
+		       MAX(TIMESTAMPDIFF(MINUTE, t.CreatedAt, t.CompletedAt)),
+		       AVG(TIMESTAMPDIFF(MINUTE, t.CreatedAt, t.StartedAt)),
+		       AVG(TIMESTAMPDIFF(MINUTE, t.StartedAt, t.CompletedAt)),
+		       SUM(t.Status = 'verified'), SUM(t.FailedChecks)
+		FROM HousekeepingTasks t
+		JOIN BedInventory bi ON t.BedID = bi.BedID
+		`+bedWardJoins+`
+		WHERE t.Status IN ('completed', 'verified')
+		  AND t.CompletedAt >= ? AND t.CompletedAt < DATE_ADD(?, INTERVAL 1 DAY)
+		  AND (? = 0 OR t.HospitalID = ?)
+		GROUP BY COALESCE(wd.WardID, 0), COALESCE(wd.Name, 'Unassigned')
+		ORDER BY COALESCE(wd.WardID, 0) = 0, COALESCE(wd.Name, 'Unassigned')
+	`, from, to, hospitalID, hospitalID)
+	if err != nil {
+		log.Printf("Error querying housekeeping report: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type wardTurnaround struct {
+		WardID              int     `json:"wardId"`
+		WardName            string  `json:"wardName"`
+		Completed           int     `json:"completed"`
+		AvgTurnaroundMins   float64 `json:"avgTurnaroundMinutes"`
+		MaxTurnaroundMins   int     `json:"maxTurnaroundMinutes"`
+		AvgResponseMins     float64 `json:"avgResponseMinutes"`
+		AvgCleaningMins     float64 `json:"avgCleaningMinutes"`
+		Verified            int     `json:"verified"`
+		FailedVerifications int     `json:"failedVerifications"`
+	}
+	wards := []wardTurnaround{}
+	var total wardTurnaround
+	var weighted float64
+	for rows.Next() {
+		var wt wardTurnaround
+		var avgTurnaround, avgResponse, avgCleaning sql.NullFloat64
+		var maxTurnaround sql.NullInt64
+		err := rows.Scan(&wt.WardID, &wt.WardName, &wt.Completed, &avgTurnaround, &maxTurnaround,
+			&avgResponse, &avgCleaning, &wt.Verified, &wt.FailedVerifications)
+		if err != nil {
+			log.Printf("Error scanning housekeeping report row: %v", err)
+			continue
+		}
+		wt.AvgTurnaroundMins = math.Round(avgTurnaround.Float64*10) / 10
+		wt.MaxTurnaroundMins = int(maxTurnaround.Int64)
+		wt.AvgResponseMins = math.Round(avgResponse.Float64*10) / 10
+		wt.AvgCleaningMins = math.Round(avgCleaning.Float64*10) / 10
+		wards = append(wards, wt)
+
+		total.Completed += wt.Completed
+		total.Verified += wt.Verified
+		total.FailedVerifications += wt.FailedVerifications
+		weighted += avgTurnaround.Float64 * float64(wt.Completed)
+		if wt.MaxTurnaroundMins > total.MaxTurnaroundMins {
+			total.MaxTurnaroundMins = wt.MaxTurnaroundMins
+		}
+	}
+	if total.Completed > 0 {
+		total.AvgTurnaroundMins = math.Round(weighted/float64(total.Completed)*10) / 10
+	}
+
+	var open int
+	err = database.DB.QueryRow(
+		"SELECT COUNT(*) FROM HousekeepingTasks WHERE Status IN ("+openTaskStatuses+") AND (? = 0 OR HospitalID = ?)",
+		hospitalID, hospitalID).Scan(&open)
+	if err != nil {
+		log.Printf("Error counting open housekeeping tasks: %v", err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":                 from,
+		"to":                   to,
+		"wards":                wards,
+		"completed":            total.Completed,
+		"verified":             total.Verified,
+		"failedVerifications":  total.FailedVerifications,
+		"avgTurnaroundMinutes": total.AvgTurnaroundMins,
+		"maxTurnaroundMinutes": total.MaxTurnaroundMins,
+		"openTasks":            open,
+	})
+}
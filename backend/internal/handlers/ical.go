@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalDomain is the right-hand side of event UIDs. UIDs must stay the same
+// for the life of an appointment so calendar clients update events in place.
+const icalDomain = "pulsepoint.hospital"
+
+// icalEvent is one VEVENT in an iCalendar feed
+type icalEvent struct {
+	AppointmentID int
+	Sequence      int
+	Start         time.Time
+	End           time.Time
+	Summary       string
+	Description   string
+	Location      string
+	Cancelled     bool
+}
+
+func appointmentUID(appointmentID int) string {
+	return fmt.Sprintf("appointment-%d@%s", appointmentID, icalDomain)
+}
+
+// icalEscape escapes a TEXT value (RFC 5545 section 3.3.11)
+func icalEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// icalFold splits a content line into lines of at most 75 octets, continuing
+// each one with CRLF and a space, without splitting a UTF-8 character
+// (RFC 5545 section 3.1)
+func icalFold(line string) string {
+	const limit = 75
+	var b strings.Builder
+	width := limit
+	for len(line) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		width = limit - 1 // the leading space counts towards the limit
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func icalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// writeCalendar writes a VCALENDAR containing events
+func writeCalendar(w io.Writer, name string, events []icalEvent) error {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		b.WriteString(icalFold(fmt.Sprintf(format, args...)))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//PulsePoint Hospital//Appointments//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", icalEscape(name))
+
+	stamp := icalTime(time.Now())
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:%s", appointmentUID(e.AppointmentID))
+		line("DTSTAMP:%s", stamp)
+		line("SEQUENCE:%d", e.Sequence)
+		line("DTSTART:%s", icalTime(e.Start))
+		line("DTEND:%s", icalTime(e.End))
+		line("SUMMARY:%s", icalEscape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:%s", icalEscape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION:%s", icalEscape(e.Location))
+		}
+		if e.Cancelled {
+			line("STATUS:CANCELLED")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/notify"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// reminderInterval is how often the reminder worker looks for upcoming appointments
+const reminderInterval = 5 * time.Minute
+
+// reminderLeadTime is how long before an appointment its reminder is sent.
+// It can be overridden with the REMINDER_HOURS environment variable.
+func reminderLeadTime() time.Duration {
+	if h, err := strconv.Atoi(os.Getenv("REMINDER_HOURS")); err == nil && h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// appointmentNotice loads the details needed to notify a patient about an appointment
+func appointmentNotice(appointmentID int64) (patientID int, email, phone string, data notify.TemplateData, err error) {
+	var date time.Time
+	err = database.DB.QueryRow(`
+		SELECT p.PatientID, p.Email, p.ContactNumber, p.FullName,
+		       d.FullName, d.Department, a.AppointmentDate, a.AppointmentTime
+		FROM Appointment a
+		JOIN Patients p ON a.PatientID = p.PatientID
+		JOIN Doctors d ON a.DoctorID = d.DoctorID
+		WHERE a.AppointmentID = ?
+	`, appointmentID).Scan(&patientID, &email, &phone, &data.PatientName,
+		&data.DoctorName, &data.Department, &date, &data.Time)
+	data.Date = date.Format("2006-01-02")
+	return
+}
+
+// notifyAppointment sends an appointment notification through every configured
+// channel the patient has not opted out of, and records each delivery in
+// NotificationLog. data only needs fields the appointment row can't supply,
+// such as the previous date and time of a rescheduled appointment. It blocks
+// while retrying, so handlers call it in a goroutine after committing.
+func notifyAppointment(appointmentID int64, event string, data notify.TemplateData) {
+	patientID, email, phone, details, err := appointmentNotice(appointmentID)
+	if err != nil {
+		log.Printf("Error loading appointment %d for notification: %v", appointmentID, err)
+		return
+	}
+	details.OldDate, details.OldTime = data.OldDate, data.OldTime
+
+	optedOut, err := notificationOptOuts(patientID)
+	if err != nil {
+		log.Printf("Error fetching notification preferences for patient %d: %v", patientID, err)
+		return
+	}
+
+	// Calendar clients update the event from the attachment using its UID and SEQUENCE
+	var attachments []notify.Attachment
+	if event != notify.EventReminder {
+		ics, err := appointmentCalendar(appointmentID)
+		if err != nil {
+			log.Printf("Error building calendar attachment for appointment %d: %v", appointmentID, err)
+		} else {
+			attachments = []notify.Attachment{{
+				Filename:    "appointment.ics",
+				ContentType: "text/calendar; charset=utf-8; method=PUBLISH",
+				Data:        ics,
+			}}
+		}
+	}
+
+	for _, ch := range notify.Channels() {
+		to := email
+		if ch.Kind() == "sms" {
+			to = phone
+		}
+
+		if optedOut[ch.Kind()] {
+			logNotification(appointmentID, patientID, ch.Name(), event, to, "skipped", 0, nil)
+			continue
+		}
+
+		subject, body, err := notify.Render(event, ch.Kind(), details)
+		if err != nil {
+			log.Printf("Error rendering %s notification: %v", event, err)
+			continue
+		}
+
+		msg := notify.Message{To: to, Subject: subject, Body: body}
+		if ch.Kind() == "email" {
+			msg.Attachments = attachments
+		}
+
+		attempts, err := notify.Deliver(ch, msg)
+		status := "sent"
+		if err != nil {
+			status = "failed"
+		}
+		logNotification(appointmentID, patientID, ch.Name(), event, to, status, attempts, err)
+	}
+}
+
+func logNotification(appointmentID int64, patientID int, channel, event, recipient, status string, attempts int, sendErr error) {
+	var errMsg sql.NullString
+	if sendErr != nil {
+		errMsg = sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+	_, err := database.DB.Exec(`
+		INSERT INTO NotificationLog (AppointmentID, PatientID, Channel, Event, Recipient, Status, Attempts, Error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, appointmentID, patientID, channel, event, recipient, status, attempts, errMsg)
+	if err != nil {
+		log.Printf("Error writing notification log: %v", err)
+	}
+}
+
+// notificationOptOuts returns the channel kinds a patient has opted out of
+func notificationOptOuts(patientID int) (map[string]bool, error) {
+	rows, err := database.DB.Query(
+		"SELECT Channel FROM PatientNotificationOptOut WHERE PatientID = ?", patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	optedOut := map[string]bool{}
+	for rows.Next() {
+		var channel string
+		if err := rows.Scan(&channel); err != nil {
+			return nil, err
+		}
+		optedOut[channel] = true
+	}
+	return optedOut, rows.Err()
+}
+
+// sendDueReminders sends reminders for scheduled appointments starting within
+// the reminder lead time that haven't had a reminder sent yet
+func sendDueReminders() error {
+	now := time.Now()
+	lead := reminderLeadTime()
+
+	rows, err := database.DB.Query(`
+		SELECT a.AppointmentID, a.AppointmentDate, a.AppointmentTime
+		FROM Appointment a
+		WHERE a.Status = 'scheduled'
+		AND a.AppointmentDate BETWEEN CURDATE() AND ?
+		AND NOT EXISTS (
+			SELECT 1 FROM NotificationLog n
+			WHERE n.AppointmentID = a.AppointmentID AND n.Event = 'reminder'
+		)
+	`, now.Add(lead).Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	var due []int64
+	for rows.Next() {
+		var id int64
+		var date time.Time
+		var slot string
+		if err := rows.Scan(&id, &date, &slot); err != nil {
+			rows.Close()
+			return err
+		}
+		start, err := slotStart(date, slot)
+		if err != nil {
+			continue
+		}
+		if start.After(now) && start.Sub(now) <= lead {
+			due = append(due, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range due {
+		notifyAppointment(id, notify.EventReminder, notify.TemplateData{})
+	}
+	return nil
+}
+
+// StartNotifications configures notification channels from the environment
+// and starts the appointment reminder worker
+func StartNotifications() {
+	notify.Configure(notify.FromEnv()...)
+
+	go func() {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := sendDueReminders(); err != nil {
+				log.Printf("Error sending appointment reminders: %v", err)
+			}
+		}
+	}()
+	log.Printf("Appointment reminders started (%s before appointments)", reminderLeadTime())
+}
+
+// GetAppointmentNotifications returns the delivery log for an appointment
+func GetAppointmentNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	appointmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT NotificationID, Channel, Event, Recipient, Status, Attempts, Error, CreatedAt
+		FROM NotificationLog
+		WHERE AppointmentID = ?
+		ORDER BY CreatedAt, NotificationID
+	`, appointmentID)
+	if err != nil {
+		log.Printf("Error fetching notification log: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	notifications := []map[string]interface{}{}
+	for rows.Next() {
+		var id, attempts int
+		var channel, event, recipient, status string
+		var errMsg sql.NullString
+		var createdAt time.Time
+		if err := rows.Scan(&id, &channel, &event, &recipient, &status, &attempts, &errMsg, &createdAt); err != nil {
+			log.Printf("Error scanning notification log: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		notifications = append(notifications, map[string]interface{}{
+			"notification_id": id,
+			"channel":         channel,
+			"event":           event,
+			"recipient":       recipient,
+			"status":          status,
+			"attempts":        attempts,
+			"error":           errMsg.String,
+			"created_at":      createdAt.Format(time.RFC3339),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"appointment_id": appointmentID,
+		"notifications":  notifications,
+	})
+}
+
+// GetNotificationPreferences returns which channels a patient receives notifications on
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	patientID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid patient ID", http.StatusBadRequest)
+		return
+	}
+
+	optedOut, err := notificationOptOuts(patientID)
+	if err != nil {
+		log.Printf("Error fetching notification preferences: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"patient_id": patientID,
+		"email":      !optedOut["email"],
+		"sms":        !optedOut["sms"],
+	})
+}
+
+// UpdateNotificationPreferences opts a patient in or out of email and SMS notifications
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	patientID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid patient ID", http.StatusBadRequest)
+		return
+	}
+
+	var req map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	for channel, enabled := range req {
+		if channel != "email" && channel != "sms" {
+			sendJSONError(w, "Preferences can only be set for email and sms", http.StatusBadRequest)
+			return
+		}
+
+		if enabled {
+			_, err = database.DB.Exec(
+				"DELETE FROM PatientNotificationOptOut WHERE PatientID = ? AND Channel = ?",
+				patientID, channel)
+		} else {
+			_, err = database.DB.Exec(
+				"INSERT IGNORE INTO PatientNotificationOptOut (PatientID, Channel) VALUES (?, ?)",
+				patientID, channel)
+		}
+		if err != nil {
+			log.Printf("Error updating notification preferences: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Notification preferences updated",
+	})
+}
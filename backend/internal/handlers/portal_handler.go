@@ -0,0 +1,689 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/notify"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Patient portal sign-in settings
+const (
+	otpDigits          = 6
+	otpLifetime        = 10 * time.Minute
+	otpMaxAttempts     = 5
+	portalSessionHours = 12
+)
+
+// otpRequestLimiter caps how many codes can be sent to one email or phone number
+var otpRequestLimiter = newRateLimiter(5, 15*time.Minute)
+
+// hashSecret returns the hex SHA-256 of a code or session token. Only hashes
+// are stored so that a database leak doesn't expose live credentials.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateOTP() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n), nil
+}
+
+func portalHeaders(w http.ResponseWriter, methods string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", methods+", OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+}
+
+// portalIdentity is the body of the sign-in requests. Patients identify
+// themselves by the email or contact number they gave when booking.
+type portalIdentity struct {
+	Email         string `json:"email"`
+	ContactNumber string `json:"contact_number"`
+	Code          string `json:"code"`
+}
+
+// channel returns the channel kind the code goes to and the address used to
+// find the patient. The email wins when both are given.
+func (id portalIdentity) channel() (kind, address string) {
+	if id.Email != "" {
+		return "email", strings.TrimSpace(id.Email)
+	}
+	return "sms", strings.TrimSpace(id.ContactNumber)
+}
+
+// lookup finds the patient and which channel kind the code goes to
+func (id portalIdentity) lookup() (patientID int, kind, address string, err error) {
+	kind, address = id.channel()
+	if kind == "email" {
+		err = database.DB.QueryRow("SELECT PatientID FROM Patients WHERE Email = ?", address).Scan(&patientID)
+		return
+	}
+
+	// Contact numbers aren't unique, so only sign in by phone when exactly one patient matches
+	var matches int
+	err = database.DB.QueryRow(
+		"SELECT COUNT(*), COALESCE(MIN(PatientID), 0) FROM Patients WHERE ContactNumber = ?",
+		address).Scan(&matches, &patientID)
+	if err == nil && matches != 1 {
+		err = sql.ErrNoRows
+	}
+	return
+}
+
+// errNoSession is returned by portalPatientID when the request carries no token
+var errNoSession = errors.New("missing session token")
+
+// portalPatientID returns the patient signed in with the request's bearer token
+func portalPatientID(r *http.Request) (int, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return 0, errNoSession
+	}
+
+	var patientID int
+	err := database.DB.QueryRow(`
+		SELECT PatientID FROM PatientSessions
+		WHERE TokenHash = ? AND RevokedAt IS NULL AND ExpiresAt > NOW()
+	`, hashSecret(token)).Scan(&patientID)
+	return patientID, err
+}
+
+// requirePatient writes a 401 and returns false if the request has no valid session
+func requirePatient(w http.ResponseWriter, r *http.Request) (int, bool) {
+	patientID, err := portalPatientID(r)
+	if err != nil {
+		if err != sql.ErrNoRows && err != errNoSession {
+			log.Printf("Error checking portal session: %v", err)
+		}
+		sendJSONError(w, "Please sign in again", http.StatusUnauthorized)
+		return 0, false
+	}
+	return patientID, true
+}
+
+// RequestPortalOTP sends a one-time sign-in code to a patient's registered
+// email or phone. The response is the same whether or not the patient exists,
+// so the endpoint can't be used to discover who is registered.
+func RequestPortalOTP(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "POST")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req portalIdentity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" && req.ContactNumber == "" {
+		sendJSONError(w, "email or contact_number is required", http.StatusBadRequest)
+		return
+	}
+
+	// Limit on the address the patient is looked up by, so variations of the
+	// request that reach the same patient share one budget. Unknown addresses
+	// are limited the same way, so a 429 doesn't reveal who is registered.
+	kind, address := req.channel()
+	if ok, retryAfter := otpRequestLimiter.allow(kind + "|" + strings.ToLower(address)); !ok {
+		rateLimitExceeded(w, retryAfter)
+		return
+	}
+
+	response := map[string]interface{}{
+		"status":  "success",
+		"message": "If the details match a registered patient, a verification code has been sent",
+	}
+
+	patientID, kind, address, err := req.lookup()
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error looking up patient for portal sign-in: %v", err)
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		log.Printf("Error generating sign-in code: %v", err)
+		sendJSONError(w, "Could not send verification code", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO PatientOTPs (PatientID, CodeHash, Channel, ExpiresAt)
+		VALUES (?, ?, ?, ?)
+	`, patientID, hashSecret(code), kind, time.Now().Add(otpLifetime))
+	if err != nil {
+		log.Printf("Error saving sign-in code: %v", err)
+		sendJSONError(w, "Could not send verification code", http.StatusInternalServerError)
+		return
+	}
+
+	subject, body, err := notify.Render(notify.EventOTP, kind, notify.TemplateData{Code: code})
+	if err != nil {
+		log.Printf("Error rendering sign-in code: %v", err)
+		sendJSONError(w, "Could not send verification code", http.StatusInternalServerError)
+		return
+	}
+	for _, ch := range notify.Channels() {
+		if ch.Kind() != kind {
+			continue
+		}
+		go func(ch notify.Channel) {
+			if _, err := notify.Deliver(ch, notify.Message{To: address, Subject: subject, Body: body}); err != nil {
+				log.Printf("Error sending sign-in code to patient %d: %v", patientID, err)
+			}
+		}(ch)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// VerifyPortalOTP exchanges a valid sign-in code for a portal session token
+func VerifyPortalOTP(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "POST")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req portalIdentity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if (req.Email == "" && req.ContactNumber == "") || req.Code == "" {
+		sendJSONError(w, "email or contact_number, and code are required", http.StatusBadRequest)
+		return
+	}
+
+	invalid := func() { sendJSONError(w, "Invalid or expired code", http.StatusUnauthorized) }
+
+	patientID, kind, _, err := req.lookup()
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error looking up patient for portal sign-in: %v", err)
+		}
+		invalid()
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the latest code counts, and it is locked so parallel guesses are counted
+	var otpID, attempts int
+	var codeHash string
+	err = tx.QueryRow(`
+		SELECT OTPID, CodeHash, Attempts FROM PatientOTPs
+		WHERE PatientID = ? AND Channel = ? AND UsedAt IS NULL AND ExpiresAt > NOW()
+		ORDER BY OTPID DESC LIMIT 1
+		FOR UPDATE
+	`, patientID, kind).Scan(&otpID, &codeHash, &attempts)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error fetching sign-in code: %v", err)
+		}
+		invalid()
+		return
+	}
+
+	if attempts >= otpMaxAttempts {
+		invalid()
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(strings.TrimSpace(req.Code))), []byte(codeHash)) != 1 {
+		if _, err := tx.Exec("UPDATE PatientOTPs SET Attempts = Attempts + 1 WHERE OTPID = ?", otpID); err != nil {
+			log.Printf("Error recording sign-in attempt: %v", err)
+		} else if err := tx.Commit(); err != nil {
+			log.Printf("Error committing transaction: %v", err)
+		}
+		invalid()
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Error generating session token: %v", err)
+		sendJSONError(w, "Could not sign in", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(portalSessionHours * time.Hour)
+
+	_, err = tx.Exec("UPDATE PatientOTPs SET UsedAt = NOW() WHERE OTPID = ?", otpID)
+	if err == nil {
+		_, err = tx.Exec(`
+			INSERT INTO PatientSessions (PatientID, TokenHash, ExpiresAt)
+			VALUES (?, ?, ?)
+		`, patientID, hashSecret(token), expiresAt)
+	}
+	if err != nil {
+		log.Printf("Error creating portal session: %v", err)
+		sendJSONError(w, "Could not sign in", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Could not sign in", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"token":      token,
+		"patient_id": patientID,
+		"expires_at": expiresAt.Format(time.RFC3339),
+	})
+}
+
+// PortalLogout revokes the current portal session
+func PortalLogout(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "POST")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	_, err := database.DB.Exec(
+		"UPDATE PatientSessions SET RevokedAt = NOW() WHERE TokenHash = ? AND RevokedAt IS NULL",
+		hashSecret(token))
+	if err != nil {
+		log.Printf("Error revoking portal session: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Signed out",
+	})
+}
+
+// GetPortalAppointments lists the signed-in patient's appointments
+func GetPortalAppointments(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "GET, POST")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	patientID, ok := requirePatient(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT a.AppointmentID, a.DoctorID, d.FullName, d.Department,
+		       DATE_FORMAT(a.AppointmentDate, '%Y-%m-%d'), a.AppointmentTime,
+		       a.Status, COALESCE(a.Description, '')
+		FROM Appointment a
+		JOIN Doctors d ON a.DoctorID = d.DoctorID
+		WHERE a.PatientID = ?
+		ORDER BY a.AppointmentDate DESC, a.AppointmentID DESC
+	`, patientID)
+	if err != nil {
+		log.Printf("Error fetching portal appointments: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	appointments := []map[string]interface{}{}
+	for rows.Next() {
+		var id, doctorID int
+		var doctorName, department, date, slot, status, description string
+		if err := rows.Scan(&id, &doctorID, &doctorName, &department, &date, &slot, &status, &description); err != nil {
+			log.Printf("Error scanning portal appointment: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		appointments = append(appointments, map[string]interface{}{
+			"appointment_id":   id,
+			"doctor_id":        doctorID,
+			"doctor_name":      doctorName,
+			"department":       department,
+			"appointment_date": date,
+			"appointment_time": slot,
+			"status":           status,
+			"description":      description,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"patient_id":   patientID,
+		"appointments": appointments,
+	})
+}
+
+// BookPortalAppointment books an appointment for the signed-in patient
+func BookPortalAppointment(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "POST")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	patientID, ok := requirePatient(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DoctorID        int    `json:"doctor_id"`
+		AppointmentDate string `json:"appointment_date"`
+		AppointmentTime string `json:"appointment_time"`
+		Description     string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.DoctorID == 0 {
+		sendJSONError(w, "doctor_id is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", req.AppointmentDate); err != nil {
+		sendJSONError(w, "Invalid date format", http.StatusBadRequest)
+		return
+	}
+	if req.AppointmentDate < time.Now().Format("2006-01-02") {
+		sendJSONError(w, "Cannot book an appointment in the past", http.StatusBadRequest)
+		return
+	}
+	if !isAppointmentSlot(req.AppointmentTime) {
+		sendJSONError(w, "appointment_time must be one of the bookable slots", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the doctor row so the slot can't be taken concurrently
+	var locked int
+	err = tx.QueryRow("SELECT 1 FROM Doctors WHERE DoctorID = ? FOR UPDATE", req.DoctorID).Scan(&locked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Doctor not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error locking doctor: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	taken, err := bookedSlots(tx, req.DoctorID, req.AppointmentDate)
+	if err != nil {
+		log.Printf("Error fetching booked slots: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if taken[req.AppointmentTime] {
+		sendJSONError(w, "The requested slot is not available", http.StatusConflict)
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO Appointment (PatientID, DoctorID, AppointmentDate, AppointmentTime, Description, Status)
+		VALUES (?, ?, ?, ?, ?, 'scheduled')
+	`, patientID, req.DoctorID, req.AppointmentDate, req.AppointmentTime, req.Description)
+	if err != nil {
+		log.Printf("Error inserting portal appointment: %v", err)
+		sendJSONError(w, "Error creating appointment record", http.StatusInternalServerError)
+		return
+	}
+	appointmentID, _ := result.LastInsertId()
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go notifyAppointment(appointmentID, notify.EventConfirmation, notify.TemplateData{})
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "success",
+		"message":        "Appointment booked successfully",
+		"appointment_id": appointmentID,
+	})
+}
+
+// ReschedulePortalAppointment moves one of the signed-in patient's appointments
+func ReschedulePortalAppointment(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "PUT")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	patientID, ok := requirePatient(w, r)
+	if !ok {
+		return
+	}
+
+	appointmentID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		sendJSONError(w, "Invalid appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AppointmentDate string `json:"appointment_date"`
+		AppointmentTime string `json:"appointment_time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := rescheduleAppointment(appointmentID, patientID, req.AppointmentDate, req.AppointmentTime); err != nil {
+		sendJSONError(w, err.Error(), status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "success",
+		"message":          "Appointment rescheduled successfully",
+		"appointment_date": req.AppointmentDate,
+		"appointment_time": req.AppointmentTime,
+	})
+}
+
+// CancelPortalAppointment cancels one of the signed-in patient's upcoming appointments
+func CancelPortalAppointment(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "POST")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	patientID, ok := requirePatient(w, r)
+	if !ok {
+		return
+	}
+
+	appointmentID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		sendJSONError(w, "Invalid appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var doctorID int
+	var date, slot, status string
+	err = tx.QueryRow(`
+		SELECT DoctorID, DATE_FORMAT(AppointmentDate, '%Y-%m-%d'), AppointmentTime, Status
+		FROM Appointment WHERE AppointmentID = ? AND PatientID = ? FOR UPDATE
+	`, appointmentID, patientID).Scan(&doctorID, &date, &slot, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Appointment not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching appointment: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if status != "scheduled" {
+		sendJSONError(w, "Only scheduled appointments can be cancelled", http.StatusConflict)
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE Appointment SET Status = 'cancelled', Sequence = Sequence + 1 WHERE AppointmentID = ?",
+		appointmentID)
+	if err != nil {
+		log.Printf("Error cancelling appointment: %v", err)
+		sendJSONError(w, "Failed to cancel appointment", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := offerFreedSlot(tx, doctorID, date, slot); err != nil {
+		log.Printf("Error offering freed slot: %v", err)
+		sendJSONError(w, "Failed to cancel appointment", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go notifyAppointment(appointmentID, notify.EventCancellation, notify.TemplateData{})
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Appointment cancelled",
+	})
+}
+
+// GetPortalAdmission returns whether the signed-in patient is currently
+// admitted, and their recent admissions with the diagnosis, doctors and every
+// bed of the stay
+func GetPortalAdmission(w http.ResponseWriter, r *http.Request) {
+	portalHeaders(w, "GET")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	patientID, ok := requirePatient(w, r)
+	if !ok {
+		return
+	}
+
+	admissions, err := patientAdmissions(database.DB, patientID, 10)
+	if err != nil {
+		log.Printf("Error fetching portal admissions: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	type hospitalAddress struct{ address, city string }
+	hospitals := map[int]hospitalAddress{}
+	var current map[string]interface{}
+	history := []map[string]interface{}{}
+	for _, a := range admissions {
+		h, ok := hospitals[a.HospitalID]
+		if !ok {
+			err := database.DB.QueryRow("SELECT Address, City FROM Hospital WHERE HospitalID = ?", a.HospitalID).
+				Scan(&h.address, &h.city)
+			if err != nil {
+				log.Printf("Error fetching hospital %d for portal admission: %v", a.HospitalID, err)
+				sendJSONError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			hospitals[a.HospitalID] = h
+		}
+
+		// The stay's beds, oldest first, so transfers show as one admission
+		beds := []map[string]interface{}{}
+		for _, ba := range a.Assignments {
+			beds = append(beds, map[string]interface{}{
+				"bed_type": ba.BedType,
+				"from":     ba.AdmissionDate,
+				"to":       ba.DischargeDate,
+			})
+		}
+		bedType := a.BedType
+		if bedType == "" && len(a.Assignments) > 0 {
+			bedType = a.Assignments[len(a.Assignments)-1].BedType
+		}
+
+		admission := map[string]interface{}{
+			"admission_id":     a.AdmissionID,
+			"hospital":         h.address,
+			"city":             h.city,
+			"bed_type":         bedType,
+			"location":         a.Location,
+			"admission_date":   a.AdmittedAt,
+			"discharge_date":   a.DischargedAt,
+			"diagnosis":        a.Diagnosis,
+			"admitting_doctor": a.AdmittingDoctor,
+			"attending_doctor": a.AttendingDoctor,
+			"appointment_id":   a.AppointmentID,
+			"beds":             beds,
+		}
+		if a.Status == "admitted" && current == nil {
+			current = admission
+		}
+		history = append(history, admission)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"patient_id": patientID,
+		"admitted":   current != nil,
+		"current":    current,
+		"admissions": history,
+	})
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// queueOrder sorts waiting tokens so that emergency and elderly patients are
+// called ahead of normal tokens, and by token number within each priority
+const queueOrder = "FIELD(q.Priority, 'emergency', 'elderly', 'normal'), q.TokenNumber"
+
+// validQueuePriority reports whether p is a known queue priority
+func validQueuePriority(p string) bool {
+	switch p {
+	case "normal", "elderly", "emergency":
+		return true
+	}
+	return false
+}
+
+// issueQueueToken adds a patient to a doctor's queue for today and returns the
+// token ID and the per-doctor, per-day token number
+func issueQueueToken(tx *sql.Tx, doctorID, patientID, appointmentID int, priority string) (int64, int, error) {
+	if priority == "" {
+		priority = "normal"
+	}
+	queueDate := time.Now().Format("2006-01-02")
+
+	// Lock the doctor's tokens for the day so two check-ins can't get the same number
+	var tokenNumber int
+	err := tx.QueryRow(`
+		SELECT COALESCE(MAX(TokenNumber), 0) + 1
+		FROM QueueTokens
+		WHERE DoctorID = ? AND QueueDate = ?
+		FOR UPDATE
+	`, doctorID, queueDate).Scan(&tokenNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var appointment interface{}
+	if appointmentID > 0 {
+		appointment = appointmentID
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO QueueTokens (DoctorID, PatientID, AppointmentID, QueueDate, TokenNumber, Priority, Status)
+		VALUES (?, ?, ?, ?, ?, ?, 'waiting')
+	`, doctorID, patientID, appointment, queueDate, tokenNumber, priority)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tokenID, err := result.LastInsertId()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tokenID, tokenNumber, nil
+}
+
+// GetDoctorQueue returns the ordered queue for a doctor on a given day
+func GetDoctorQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	doctorID, err := strconv.Atoi(r.URL.Query().Get("doctorId"))
+	if err != nil || doctorID <= 0 {
+		sendJSONError(w, "Valid doctorId is required", http.StatusBadRequest)
+		return
+	}
+
+	queueDate := r.URL.Query().Get("date")
+	if queueDate == "" {
+		queueDate = time.Now().Format("2006-01-02")
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT
+			q.TokenID,
+			q.DoctorID,
+			d.FullName,
+			COALESCE(d.Room, ''),
+			q.PatientID,
+			p.FullName,
+			COALESCE(q.AppointmentID, 0),
+			DATE_FORMAT(q.QueueDate, '%Y-%m-%d'),
+			q.TokenNumber,
+			q.Priority,
+			q.Status,
+			DATE_FORMAT(q.CheckedInAt, '%Y-%m-%d %H:%i:%s'),
+			COALESCE(DATE_FORMAT(q.CalledAt, '%Y-%m-%d %H:%i:%s'), '')
+		FROM QueueTokens q
+		JOIN Doctors d ON q.DoctorID = d.DoctorID
+		JOIN Patients p ON q.PatientID = p.PatientID
+		WHERE q.DoctorID = ? AND q.QueueDate = ?
+		ORDER BY
+			FIELD(q.Status, 'called', 'waiting', 'skipped', 'completed'),
+			`+queueOrder, doctorID, queueDate)
+	if err != nil {
+		log.Printf("Error querying queue: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tokens := []models.QueueToken{}
+	for rows.Next() {
+		var t models.QueueToken
+		err := rows.Scan(&t.TokenID, &t.DoctorID, &t.DoctorName, &t.Room, &t.PatientID, &t.PatientName,
+			&t.AppointmentID, &t.QueueDate, &t.TokenNumber, &t.Priority, &t.Status, &t.CheckedInAt, &t.CalledAt)
+		if err != nil {
+			log.Printf("Error scanning queue row: %v", err)
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CallNextToken completes the doctor's current token and calls the next waiting one
+func CallNextToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var request struct {
+		DoctorID int `json:"doctorId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.DoctorID <= 0 {
+		sendJSONError(w, "Valid doctorId is required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	queueDate := time.Now().Format("2006-01-02")
+
+	// Finish whoever is currently with the doctor
+	_, err = tx.Exec(`
+		UPDATE QueueTokens SET Status = 'completed'
+		WHERE DoctorID = ? AND QueueDate = ? AND Status = 'called'
+	`, request.DoctorID, queueDate)
+	if err != nil {
+		log.Printf("Error completing current token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var tokenID, tokenNumber int
+	err = tx.QueryRow(`
+		SELECT q.TokenID, q.TokenNumber
+		FROM QueueTokens q
+		WHERE q.DoctorID = ? AND q.QueueDate = ? AND q.Status = 'waiting'
+		ORDER BY `+queueOrder+`
+		LIMIT 1
+		FOR UPDATE
+	`, request.DoctorID, queueDate).Scan(&tokenID, &tokenNumber)
+	if err == sql.ErrNoRows {
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "No patients waiting",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Error finding next token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("UPDATE QueueTokens SET Status = 'called', CalledAt = NOW() WHERE TokenID = ?", tokenID)
+	if err != nil {
+		log.Printf("Error calling token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Doctor %d called token %d", request.DoctorID, tokenNumber)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"message":     "Next patient called",
+		"tokenId":     tokenID,
+		"tokenNumber": tokenNumber,
+	})
+}
+
+// SkipToken marks a waiting or called token as skipped, e.g. when the patient does not answer
+func SkipToken(w http.ResponseWriter, r *http.Request) {
+	updateTokenStatus(w, r, "skip")
+}
+
+// RecallToken calls a previously skipped token again
+func RecallToken(w http.ResponseWriter, r *http.Request) {
+	updateTokenStatus(w, r, "recall")
+}
+
+// updateTokenStatus applies a skip or recall action to a single queue token
+func updateTokenStatus(w http.ResponseWriter, r *http.Request, action string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var doctorID, tokenNumber int
+	var status, queueDate string
+	err = tx.QueryRow(`
+		SELECT DoctorID, TokenNumber, Status, DATE_FORMAT(QueueDate, '%Y-%m-%d')
+		FROM QueueTokens WHERE TokenID = ? FOR UPDATE
+	`, tokenID).Scan(&doctorID, &tokenNumber, &status, &queueDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Token not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching token: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch action {
+	case "skip":
+		if status != "waiting" && status != "called" {
+			sendJSONError(w, "Only waiting or called tokens can be skipped", http.StatusConflict)
+			return
+		}
+		_, err = tx.Exec("UPDATE QueueTokens SET Status = 'skipped' WHERE TokenID = ?", tokenID)
+	case "recall":
+		if status != "skipped" {
+			sendJSONError(w, "Only skipped tokens can be recalled", http.StatusConflict)
+			return
+		}
+		// The recalled patient goes straight in, so finish whoever is currently called
+		_, err = tx.Exec(`
+			UPDATE QueueTokens SET Status = 'completed'
+			WHERE DoctorID = ? AND QueueDate = ? AND Status = 'called'
+		`, doctorID, queueDate)
+		if err == nil {
+			_, err = tx.Exec("UPDATE QueueTokens SET Status = 'called', CalledAt = NOW() WHERE TokenID = ?", tokenID)
+		}
+	}
+	if err != nil {
+		log.Printf("Error applying %s to token %d: %v", action, tokenID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Token %d for doctor %d: %s", tokenNumber, doctorID, action)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"tokenId":     tokenID,
+		"tokenNumber": tokenNumber,
+		"action":      action,
+	})
+}
+
+// GetQueueDisplay returns the read-only waiting-room feed with the current
+// and next tokens for every doctor's room today
+func GetQueueDisplay(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	department := r.URL.Query().Get("department")
+	nextCount := 3
+	if n, err := strconv.Atoi(r.URL.Query().Get("next")); err == nil && n > 0 && n <= 10 {
+		nextCount = n
+	}
+
+	query := `
+		SELECT q.DoctorID, d.FullName, COALESCE(d.Room, ''), q.TokenNumber, q.Status
+		FROM QueueTokens q
+		JOIN Doctors d ON q.DoctorID = d.DoctorID
+		WHERE q.QueueDate = CURDATE() AND q.Status IN ('called', 'waiting')
+	`
+	var args []interface{}
+	if department != "" && department != "all" {
+		query += " AND d.Department = ?"
+		args = append(args, department)
+	}
+	query += " ORDER BY d.FullName, q.DoctorID, FIELD(q.Status, 'called', 'waiting'), " + queueOrder
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying queue display: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rooms := []*models.QueueDisplayRoom{}
+	byDoctor := map[int]*models.QueueDisplayRoom{}
+	for rows.Next() {
+		var doctorID, tokenNumber int
+		var doctorName, room, status string
+		if err := rows.Scan(&doctorID, &doctorName, &room, &tokenNumber, &status); err != nil {
+			log.Printf("Error scanning queue display row: %v", err)
+			continue
+		}
+
+		entry, ok := byDoctor[doctorID]
+		if !ok {
+			entry = &models.QueueDisplayRoom{
+				DoctorID:   doctorID,
+				DoctorName: doctorName,
+				Room:       room,
+				NextTokens: []int{},
+			}
+			byDoctor[doctorID] = entry
+			rooms = append(rooms, entry)
+		}
+
+		if status == "called" {
+			entry.CurrentToken = tokenNumber
+			continue
+		}
+		entry.WaitingCount++
+		if len(entry.NextTokens) < nextCount {
+			entry.NextTokens = append(entry.NextTokens, tokenNumber)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"generatedAt": time.Now().Format(time.RFC3339),
+		"rooms":       rooms,
+	})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter allows at most limit events per key within a sliding window.
+// State is kept in memory, so limits are per server process.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, hits: map[string][]time.Time{}}
+}
+
+// allow records an event for key and reports whether it is within the limit.
+// When it isn't, it also returns how long until the oldest event leaves the window.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	// Drop keys that have gone quiet so the map doesn't grow without bound
+	if now.Sub(l.lastSweep) > l.window {
+		for k, times := range l.hits {
+			if len(times) == 0 || times[len(times)-1].Before(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false, recent[0].Add(l.window).Sub(now)
+	}
+	l.hits[key] = append(recent, now)
+	return true, 0
+}
+
+// clientIP returns the remote address of a request without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimitExceeded writes a 429 response with a Retry-After header
+func rateLimitExceeded(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(retryAfter.Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	sendJSONError(w, "Too many requests, please try again later", http.StatusTooManyRequests)
+}
+
+// portalLimiter caps requests to the patient portal per client address
+var portalLimiter = newRateLimiter(60, time.Minute)
+
+// PortalRateLimit wraps a patient portal handler with the per-client rate limit
+func PortalRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			if ok, retryAfter := portalLimiter.allow(clientIP(r)); !ok {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+				rateLimitExceeded(w, retryAfter)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
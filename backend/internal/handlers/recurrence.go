@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSeriesOccurrences caps how many appointments a single series may create
+const maxSeriesOccurrences = 104
+
+// recurrenceRule is the subset of RFC 5545 RRULE used for appointment series,
+// e.g. "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,TH;COUNT=12" or "FREQ=DAILY;INTERVAL=3;COUNT=10"
+type recurrenceRule struct {
+	Freq     string // DAILY or WEEKLY
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRecurrenceRule parses an RRULE string. COUNT or UNTIL is required so
+// that a series is always finite.
+func parseRecurrenceRule(rule string) (recurrenceRule, error) {
+	rr := recurrenceRule{Interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return rr, fmt.Errorf("recurrence rule is required")
+	}
+
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return rr, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+
+		switch key {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" {
+				return rr, fmt.Errorf("FREQ must be DAILY or WEEKLY")
+			}
+			rr.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rr, fmt.Errorf("INTERVAL must be a positive number")
+			}
+			rr.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rr, fmt.Errorf("COUNT must be a positive number")
+			}
+			rr.Count = n
+		case "UNTIL":
+			t, err := time.ParseInLocation("20060102", value, time.Local)
+			if err != nil {
+				return rr, fmt.Errorf("UNTIL must be a date in YYYYMMDD format")
+			}
+			rr.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[d]
+				if !ok {
+					return rr, fmt.Errorf("invalid BYDAY value %q", d)
+				}
+				rr.ByDay = append(rr.ByDay, wd)
+			}
+		default:
+			return rr, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rr.Freq == "" {
+		return rr, fmt.Errorf("FREQ is required")
+	}
+	if rr.Count == 0 && rr.Until.IsZero() {
+		return rr, fmt.Errorf("COUNT or UNTIL is required")
+	}
+	if rr.Count > maxSeriesOccurrences {
+		return rr, fmt.Errorf("COUNT cannot exceed %d", maxSeriesOccurrences)
+	}
+	if len(rr.ByDay) > 0 && rr.Freq != "WEEKLY" {
+		return rr, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return rr, nil
+}
+
+// occurrences expands the rule into dates from start onwards. start is the
+// first occurrence, except for WEEKLY with BYDAY, where days before start are
+// skipped and start is included only if it falls on one of the listed days.
+func (rr recurrenceRule) occurrences(start time.Time) []time.Time {
+	var dates []time.Time
+	done := func(d time.Time) bool {
+		if rr.Count > 0 && len(dates) >= rr.Count {
+			return true
+		}
+		if !rr.Until.IsZero() && d.After(rr.Until) {
+			return true
+		}
+		return len(dates) >= maxSeriesOccurrences
+	}
+
+	if rr.Freq == "DAILY" || len(rr.ByDay) == 0 {
+		step := rr.Interval
+		if rr.Freq == "WEEKLY" {
+			step *= 7
+		}
+		for d := start; !done(d); d = d.AddDate(0, 0, step) {
+			dates = append(dates, d)
+		}
+		return dates
+	}
+
+	// WEEKLY with BYDAY: walk the start week and every INTERVAL-th week after it
+	weekStart := start.AddDate(0, 0, -int(start.Weekday()))
+	for {
+		for i := 0; i < 7; i++ {
+			d := weekStart.AddDate(0, 0, i)
+			if d.Before(start) || !containsWeekday(rr.ByDay, d.Weekday()) {
+				continue
+			}
+			if done(d) {
+				return dates
+			}
+			dates = append(dates, d)
+		}
+		weekStart = weekStart.AddDate(0, 0, 7*rr.Interval)
+	}
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// slotTimeLayout is the format used for Appointment.AppointmentTime
+const slotTimeLayout = "03:04 PM"
+
+// appointmentSlot is one bookable consultation slot
+type appointmentSlot struct {
+	Time    string
+	Session string
+}
+
+// appointmentSlots mirrors the time slots offered by appointment.js
+var appointmentSlots = []appointmentSlot{
+	{"09:00 AM", "morning"}, {"09:30 AM", "morning"}, {"10:00 AM", "morning"},
+	{"10:30 AM", "morning"}, {"11:00 AM", "morning"}, {"11:30 AM", "morning"},
+	{"02:00 PM", "afternoon"}, {"02:30 PM", "afternoon"}, {"03:00 PM", "afternoon"},
+	{"03:30 PM", "afternoon"}, {"04:00 PM", "afternoon"}, {"04:30 PM", "afternoon"},
+}
+
+// slotDuration is the length of one consultation slot
+const slotDuration = 30 * time.Minute
+
+// slotStart combines a date and a slot time into a local timestamp
+func slotStart(date time.Time, slot string) (time.Time, error) {
+	t, err := time.ParseInLocation(slotTimeLayout, slot, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, time.Local), nil
+}
+
+// currentSession returns the session that is running at now, if any. A
+// session runs from its first slot until the end of its last slot.
+func currentSession(now time.Time) string {
+	for _, session := range []string{"morning", "afternoon"} {
+		var first, last time.Time
+		for _, s := range appointmentSlots {
+			if s.Session != session {
+				continue
+			}
+			start, _ := slotStart(now, s.Time)
+			if first.IsZero() {
+				first = start
+			}
+			last = start
+		}
+		if !now.Before(first) && now.Before(last.Add(slotDuration)) {
+			return session
+		}
+	}
+	return ""
+}
+
+// bookedSlots returns the slot times already taken for a doctor on a date,
+// ignoring cancelled and overbooked appointments. Slots held for a
+// waitlisted patient count as taken until the hold expires.
+func bookedSlots(q queryer, doctorID int, date string) (map[string]bool, error) {
+	rows, err := q.Query(`
+		SELECT AppointmentTime
+		FROM Appointment
+		WHERE DoctorID = ? AND AppointmentDate = ?
+		AND Status <> 'cancelled' AND Overbooked = FALSE
+		UNION ALL
+		SELECT OfferTime
+		FROM SlotOffers
+		WHERE DoctorID = ? AND OfferDate = ?
+		AND Status = 'held' AND ExpiresAt > NOW()
+	`, doctorID, date, doctorID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var slot string
+		if err := rows.Scan(&slot); err != nil {
+			return nil, err
+		}
+		taken[slot] = true
+	}
+	return taken, rows.Err()
+}
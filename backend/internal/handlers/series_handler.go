@@ -0,0 +1,617 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"hospital-management/backend/internal/notify"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SeriesRequest is the body accepted by CreateAppointmentSeries. Either
+// PatientID or Patient must be provided.
+type SeriesRequest struct {
+	PatientID       int            `json:"patient_id"`
+	Patient         models.Patient `json:"patient"`
+	DoctorID        int            `json:"doctor_id"`
+	StartDate       string         `json:"start_date"`
+	AppointmentTime string         `json:"appointment_time"`
+	Description     string         `json:"description"`
+	RRule           string         `json:"rrule"`
+	ConflictPolicy  string         `json:"conflict_policy"` // next-slot (default), skip, fail
+	DryRun          bool           `json:"dry_run"`
+}
+
+// isAppointmentSlot reports whether t is one of the bookable slot times
+func isAppointmentSlot(t string) bool {
+	for _, s := range appointmentSlots {
+		if s.Time == t {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestFreeSlot returns the first free slot after the requested one on the
+// same day, falling back to the latest free slot before it
+func nearestFreeSlot(taken map[string]bool, requested string) string {
+	idx := -1
+	for i, s := range appointmentSlots {
+		if s.Time == requested {
+			idx = i
+			break
+		}
+	}
+	for i := idx + 1; i < len(appointmentSlots); i++ {
+		if !taken[appointmentSlots[i].Time] {
+			return appointmentSlots[i].Time
+		}
+	}
+	for i := idx - 1; i >= 0; i-- {
+		if !taken[appointmentSlots[i].Time] {
+			return appointmentSlots[i].Time
+		}
+	}
+	return ""
+}
+
+// CreateAppointmentSeries books a recurring series of appointments, e.g. weekly
+// physiotherapy. Occurrences that hit a booked slot are resolved according to
+// the conflict policy.
+func CreateAppointmentSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req SeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding series request: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.DoctorID == 0 || req.StartDate == "" || req.AppointmentTime == "" {
+		sendJSONError(w, "doctor_id, start_date and appointment_time are required", http.StatusBadRequest)
+		return
+	}
+	if req.PatientID == 0 && req.Patient.Email == "" {
+		sendJSONError(w, "Either patient_id or patient details are required", http.StatusBadRequest)
+		return
+	}
+	if !isAppointmentSlot(req.AppointmentTime) {
+		sendJSONError(w, "appointment_time must be one of the bookable slots", http.StatusBadRequest)
+		return
+	}
+	if req.ConflictPolicy == "" {
+		req.ConflictPolicy = "next-slot"
+	}
+	if req.ConflictPolicy != "next-slot" && req.ConflictPolicy != "skip" && req.ConflictPolicy != "fail" {
+		sendJSONError(w, "conflict_policy must be next-slot, skip or fail", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		sendJSONError(w, "Invalid start_date format", http.StatusBadRequest)
+		return
+	}
+	if req.StartDate < time.Now().Format("2006-01-02") {
+		sendJSONError(w, "start_date cannot be in the past", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := parseRecurrenceRule(req.RRule)
+	if err != nil {
+		sendJSONError(w, "Invalid rrule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	dates := rule.occurrences(startDate)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the doctor row so concurrent bookings can't take the slots we plan to use
+	var doctorExists int
+	err = tx.QueryRow("SELECT 1 FROM Doctors WHERE DoctorID = ? FOR UPDATE", req.DoctorID).Scan(&doctorExists)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Doctor not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching doctor: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Plan every occurrence before writing anything
+	var plan []models.SeriesOccurrence
+	conflicts := 0
+	for i, d := range dates {
+		date := d.Format("2006-01-02")
+		occurrence := models.SeriesOccurrence{
+			SeriesIndex:     i + 1,
+			AppointmentDate: date,
+			AppointmentTime: req.AppointmentTime,
+			Status:          "scheduled",
+			Resolution:      "booked",
+		}
+
+		taken, err := bookedSlots(tx, req.DoctorID, date)
+		if err != nil {
+			log.Printf("Error fetching booked slots: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if taken[req.AppointmentTime] {
+			switch req.ConflictPolicy {
+			case "next-slot":
+				if slot := nearestFreeSlot(taken, req.AppointmentTime); slot != "" {
+					occurrence.AppointmentTime = slot
+					occurrence.Resolution = "moved"
+				} else {
+					occurrence.Status = ""
+					occurrence.Resolution = "skipped"
+				}
+			case "skip":
+				occurrence.Status = ""
+				occurrence.Resolution = "skipped"
+			case "fail":
+				occurrence.Status = ""
+				occurrence.Resolution = "conflict"
+				conflicts++
+			}
+		}
+		plan = append(plan, occurrence)
+	}
+
+	if conflicts > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       fmt.Sprintf("%d occurrences conflict with existing bookings", conflicts),
+			"occurrences": plan,
+		})
+		return
+	}
+
+	if req.DryRun {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":      "preview",
+			"occurrences": plan,
+		})
+		return
+	}
+
+	patientID := int64(req.PatientID)
+	if patientID == 0 {
+		patientID, err = findOrCreatePatient(tx, req.Patient)
+		if err != nil {
+			log.Printf("Error creating series patient: %v", err)
+			sendJSONError(w, "Error creating patient record", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO AppointmentSeries (PatientID, DoctorID, RRule, StartDate, AppointmentTime, Description, Status)
+		VALUES (?, ?, ?, ?, ?, ?, 'active')
+	`, patientID, req.DoctorID, req.RRule, req.StartDate, req.AppointmentTime, req.Description)
+	if err != nil {
+		log.Printf("Error inserting appointment series: %v", err)
+		sendJSONError(w, "Error creating appointment series", http.StatusInternalServerError)
+		return
+	}
+
+	seriesID, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Error getting series ID: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range plan {
+		if plan[i].Status == "" {
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO Appointment (PatientID, DoctorID, AppointmentDate, AppointmentTime, Description, Status, SeriesID, SeriesIndex)
+			VALUES (?, ?, ?, ?, ?, 'scheduled', ?, ?)
+		`, patientID, req.DoctorID, plan[i].AppointmentDate, plan[i].AppointmentTime, req.Description, seriesID, plan[i].SeriesIndex)
+		if err != nil {
+			log.Printf("Error inserting series occurrence: %v", err)
+			sendJSONError(w, "Error creating appointment record", http.StatusInternalServerError)
+			return
+		}
+		appointmentID, _ := result.LastInsertId()
+		plan[i].AppointmentID = int(appointmentID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created appointment series %d with %d occurrences", seriesID, len(plan))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "success",
+		"series_id":   seriesID,
+		"patient_id":  patientID,
+		"occurrences": plan,
+	})
+}
+
+// loadSeries reads a series and its occurrences
+func loadSeries(q queryer, seriesID int) (models.AppointmentSeries, error) {
+	var series models.AppointmentSeries
+	err := q.QueryRow(`
+		SELECT SeriesID, PatientID, DoctorID, RRule, DATE_FORMAT(StartDate, '%Y-%m-%d'),
+			AppointmentTime, COALESCE(Description, ''), Status
+		FROM AppointmentSeries WHERE SeriesID = ?
+	`, seriesID).Scan(&series.SeriesID, &series.PatientID, &series.DoctorID, &series.RRule,
+		&series.StartDate, &series.AppointmentTime, &series.Description, &series.Status)
+	if err != nil {
+		return series, err
+	}
+
+	rows, err := q.Query(`
+		SELECT AppointmentID, SeriesIndex, DATE_FORMAT(AppointmentDate, '%Y-%m-%d'), AppointmentTime, Status
+		FROM Appointment WHERE SeriesID = ?
+		ORDER BY SeriesIndex
+	`, seriesID)
+	if err != nil {
+		return series, err
+	}
+	defer rows.Close()
+
+	series.Occurrences = []models.SeriesOccurrence{}
+	for rows.Next() {
+		var o models.SeriesOccurrence
+		if err := rows.Scan(&o.AppointmentID, &o.SeriesIndex, &o.AppointmentDate, &o.AppointmentTime, &o.Status); err != nil {
+			return series, err
+		}
+		series.Occurrences = append(series.Occurrences, o)
+	}
+	return series, rows.Err()
+}
+
+// GetAppointmentSeries returns a series with all of its occurrences
+func GetAppointmentSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	seriesID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	series, err := loadSeries(database.DB, seriesID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Series not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error loading series: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(series)
+}
+
+// seriesScope resolves which upcoming occurrences an edit or cancel applies to:
+// "this" occurrence only, "following" occurrences from this one on, or "all"
+func seriesScope(tx *sql.Tx, seriesID, appointmentID int, scope string) ([]models.SeriesOccurrence, error) {
+	query := `
+		SELECT AppointmentID, SeriesIndex, DATE_FORMAT(AppointmentDate, '%Y-%m-%d'), AppointmentTime, Status
+		FROM Appointment
+		WHERE SeriesID = ? AND Status = 'scheduled' AND AppointmentDate >= CURDATE()
+	`
+	args := []interface{}{seriesID}
+
+	switch scope {
+	case "all":
+	case "this", "following":
+		var index int
+		err := tx.QueryRow("SELECT SeriesIndex FROM Appointment WHERE AppointmentID = ? AND SeriesID = ?",
+			appointmentID, seriesID).Scan(&index)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("appointment %d is not part of series %d", appointmentID, seriesID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if scope == "this" {
+			query += " AND SeriesIndex = ?"
+		} else {
+			query += " AND SeriesIndex >= ?"
+		}
+		args = append(args, index)
+	default:
+		return nil, fmt.Errorf("scope must be this, following or all")
+	}
+	query += " ORDER BY SeriesIndex FOR UPDATE"
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var occurrences []models.SeriesOccurrence
+	for rows.Next() {
+		var o models.SeriesOccurrence
+		if err := rows.Scan(&o.AppointmentID, &o.SeriesIndex, &o.AppointmentDate, &o.AppointmentTime, &o.Status); err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, o)
+	}
+	return occurrences, rows.Err()
+}
+
+// UpdateAppointmentSeries edits one occurrence, this and following
+// occurrences, or the whole series. Only upcoming scheduled occurrences change.
+func UpdateAppointmentSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	seriesID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AppointmentID   int    `json:"appointment_id"`
+		Scope           string `json:"scope"`
+		AppointmentTime string `json:"appointment_time"`
+		Description     string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.AppointmentTime == "" && req.Description == "" {
+		sendJSONError(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+	if req.AppointmentTime != "" && !isAppointmentSlot(req.AppointmentTime) {
+		sendJSONError(w, "appointment_time must be one of the bookable slots", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var doctorID int
+	var seriesStatus string
+	err = tx.QueryRow("SELECT DoctorID, Status FROM AppointmentSeries WHERE SeriesID = ? FOR UPDATE", seriesID).Scan(&doctorID, &seriesStatus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Series not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error loading series: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if seriesStatus == "cancelled" {
+		sendJSONError(w, "Series has been cancelled", http.StatusConflict)
+		return
+	}
+
+	occurrences, err := seriesScope(tx, seriesID, req.AppointmentID, req.Scope)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Moving occurrences must not double-book the doctor
+	if req.AppointmentTime != "" {
+		var conflicts []string
+		for _, o := range occurrences {
+			if o.AppointmentTime == req.AppointmentTime {
+				continue
+			}
+			taken, err := bookedSlots(tx, doctorID, o.AppointmentDate)
+			if err != nil {
+				log.Printf("Error fetching booked slots: %v", err)
+				sendJSONError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if taken[req.AppointmentTime] {
+				conflicts = append(conflicts, o.AppointmentDate)
+			}
+		}
+		if len(conflicts) > 0 {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":          "The new time is already booked on some dates",
+				"conflict_dates": conflicts,
+			})
+			return
+		}
+	}
+
+	for _, o := range occurrences {
+		_, err = tx.Exec(`
+			UPDATE Appointment
+			SET AppointmentTime = COALESCE(NULLIF(?, ''), AppointmentTime),
+				Description = COALESCE(NULLIF(?, ''), Description),
+				Sequence = Sequence + 1
+			WHERE AppointmentID = ?
+		`, req.AppointmentTime, req.Description, o.AppointmentID)
+		if err != nil {
+			log.Printf("Error updating occurrence %d: %v", o.AppointmentID, err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if req.AppointmentTime != "" && req.AppointmentTime != o.AppointmentTime {
+			if _, err := offerFreedSlot(tx, doctorID, o.AppointmentDate, o.AppointmentTime); err != nil {
+				log.Printf("Error offering freed slot: %v", err)
+				sendJSONError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// Editing the whole series also changes the template it was created from
+	if req.Scope == "all" {
+		_, err = tx.Exec(`
+			UPDATE AppointmentSeries
+			SET AppointmentTime = COALESCE(NULLIF(?, ''), AppointmentTime),
+				Description = COALESCE(NULLIF(?, ''), Description)
+			WHERE SeriesID = ?
+		`, req.AppointmentTime, req.Description, seriesID)
+		if err != nil {
+			log.Printf("Error updating series: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, o := range occurrences {
+		if req.AppointmentTime != "" && req.AppointmentTime != o.AppointmentTime {
+			go notifyAppointment(int64(o.AppointmentID), notify.EventReschedule,
+				notify.TemplateData{OldDate: o.AppointmentDate, OldTime: o.AppointmentTime})
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Updated %d occurrences", len(occurrences)),
+		"updated": len(occurrences),
+	})
+}
+
+// CancelAppointmentSeries cancels one occurrence, this and following
+// occurrences, or the whole series
+func CancelAppointmentSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	seriesID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		AppointmentID int    `json:"appointment_id"`
+		Scope         string `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var doctorID int
+	err = tx.QueryRow("SELECT DoctorID FROM AppointmentSeries WHERE SeriesID = ? FOR UPDATE", seriesID).Scan(&doctorID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Series not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error loading series: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	occurrences, err := seriesScope(tx, seriesID, req.AppointmentID, req.Scope)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, o := range occurrences {
+		_, err = tx.Exec("UPDATE Appointment SET Status = 'cancelled', Sequence = Sequence + 1 WHERE AppointmentID = ?", o.AppointmentID)
+		if err != nil {
+			log.Printf("Error cancelling occurrence %d: %v", o.AppointmentID, err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := offerFreedSlot(tx, doctorID, o.AppointmentDate, o.AppointmentTime); err != nil {
+			log.Printf("Error offering freed slot: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.Scope == "all" {
+		_, err = tx.Exec("UPDATE AppointmentSeries SET Status = 'cancelled' WHERE SeriesID = ?", seriesID)
+		if err != nil {
+			log.Printf("Error cancelling series: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, o := range occurrences {
+		go notifyAppointment(int64(o.AppointmentID), notify.EventCancellation, notify.TemplateData{})
+	}
+
+	log.Printf("Cancelled %d occurrences of series %d (scope %s)", len(occurrences), seriesID, req.Scope)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"message":   fmt.Sprintf("Cancelled %d occurrences", len(occurrences)),
+		"cancelled": len(occurrences),
+	})
+}
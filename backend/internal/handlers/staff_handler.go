@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
 	"log"
 	"net/http"
 	"strconv"
@@ -287,18 +288,29 @@ func UpdateStaffProfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetStaffPatients returns the list of patients for staff view
+// GetStaffPatients returns the list of patients for staff view, one row per
+// patient with their latest appointment and current admission
 func GetStaffPatients(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Use the exact query specified in requirements
+	admissions, err := currentAdmissions(database.DB, admissionFilter{})
+	if err != nil {
+		log.Printf("Error querying current admissions: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	admitted := make(map[int]models.Admission, len(admissions))
+	for _, a := range admissions {
+		admitted[a.PatientID] = a
+	}
+
 	rows, err := database.DB.Query(`
-		SELECT p.PatientID, p.FullName, p.Email, p.ContactNumber, a.AppointmentID, a.Status, ba.BedID
+		SELECT p.PatientID, p.FullName, p.Email, p.ContactNumber, a.AppointmentID, a.Status
 		FROM patients p
-		LEFT JOIN appointment a ON p.PatientID = a.PatientID
-		LEFT JOIN bedassignments ba ON p.PatientID = ba.PatientID
-		WHERE (ba.DischargeDate IS NULL OR ba.DischargeDate IS NOT NULL)
+		LEFT JOIN appointment a ON a.AppointmentID = (
+			SELECT MAX(la.AppointmentID) FROM appointment la WHERE la.PatientID = p.PatientID
+		)
 		ORDER BY p.PatientID DESC
 	`)
 
@@ -309,11 +321,11 @@ func GetStaffPatients(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var patients []map[string]interface{}
+	patients := []map[string]interface{}{}
 	for rows.Next() {
 		var patientID int
 		var fullName, email, contactNumber string
-		var appointmentID, bedID sql.NullInt64
+		var appointmentID sql.NullInt64
 		var status sql.NullString
 
 		err := rows.Scan(
@@ -323,7 +335,6 @@ func GetStaffPatients(w http.ResponseWriter, r *http.Request) {
 			&contactNumber,
 			&appointmentID,
 			&status,
-			&bedID,
 		)
 
 		if err != nil {
@@ -332,13 +343,14 @@ func GetStaffPatients(w http.ResponseWriter, r *http.Request) {
 		}
 
 		patient := map[string]interface{}{
-			"id":           patientID,
-			"name":         fullName,
-			"email":        email,
-			"contact":      contactNumber,
+			"id":            patientID,
+			"name":          fullName,
+			"email":         email,
+			"contact":       contactNumber,
 			"appointmentID": nil,
-			"status":       "new",
-			"bedID":        nil,
+			"status":        "new",
+			"bedID":         nil,
+			"admissionID":   nil,
 		}
 
 		// Set appointment info if exists
@@ -351,11 +363,13 @@ func GetStaffPatients(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		// Set bed info if exists
-		if bedID.Valid {
-			patient["bedID"] = bedID.Int64
-			// If patient has a bed, they're admitted
+		// Set admission info if the patient is currently admitted
+		if a, ok := admitted[patientID]; ok {
+			patient["admissionID"] = a.AdmissionID
 			patient["status"] = "admitted"
+			if a.BedID != 0 {
+				patient["bedID"] = a.BedID
+			}
 		}
 
 		patients = append(patients, patient)
@@ -442,7 +456,7 @@ func RegisterPatient(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// CheckInPatient handles patient appointment check-in
+// CheckInPatient handles patient appointment check-in and issues a queue token
 func CheckInPatient(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -459,7 +473,8 @@ func CheckInPatient(w http.ResponseWriter, r *http.Request) {
 
 	// Parse the request body
 	var checkIn struct {
-		AppointmentID int `json:"appointmentId"`
+		AppointmentID int    `json:"appointmentId"`
+		Priority      string `json:"priority"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&checkIn); err != nil {
@@ -475,10 +490,63 @@ func CheckInPatient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if checkIn.Priority == "" {
+		checkIn.Priority = "normal"
+	}
+	if !validQueuePriority(checkIn.Priority) {
+		sendJSONError(w, "Priority must be normal, elderly or emergency", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Look up the appointment to find the doctor and patient
+	var doctorID, patientID int
+	var appointmentDate string
+	err = tx.QueryRow(`
+		SELECT DoctorID, PatientID, DATE_FORMAT(AppointmentDate, '%Y-%m-%d')
+		FROM Appointment
+		WHERE AppointmentID = ?
+		FOR UPDATE
+	`, checkIn.AppointmentID).Scan(&doctorID, &patientID, &appointmentDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Appointment not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching appointment: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if appointmentDate != time.Now().Format("2006-01-02") {
+		sendJSONError(w, "Only today's appointments can be checked in", http.StatusBadRequest)
+		return
+	}
+
+	// Refuse a second token for the same appointment
+	var alreadyQueued bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM QueueTokens WHERE AppointmentID = ?)", checkIn.AppointmentID).Scan(&alreadyQueued)
+	if err != nil {
+		log.Printf("Error checking existing token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if alreadyQueued {
+		sendJSONError(w, "Patient is already checked in for this appointment", http.StatusConflict)
+		return
+	}
+
 	// Update appointment status to 'Checked-In'
-	_, err := database.DB.Exec(`
-		UPDATE Appointment 
-		SET Status = 'checked-in' 
+	_, err = tx.Exec(`
+		UPDATE Appointment
+		SET Status = 'checked-in'
 		WHERE AppointmentID = ?
 	`, checkIn.AppointmentID)
 
@@ -488,12 +556,29 @@ func CheckInPatient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tokenID, tokenNumber, err := issueQueueToken(tx, doctorID, patientID, checkIn.AppointmentID, checkIn.Priority)
+	if err != nil {
+		log.Printf("Error issuing queue token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
 	response := map[string]interface{}{
-		"success": true,
-		"message": "Patient checked in successfully",
+		"success":     true,
+		"message":     "Patient checked in successfully",
+		"tokenId":     tokenID,
+		"tokenNumber": tokenNumber,
+		"doctorId":    doctorID,
+		"priority":    checkIn.Priority,
 	}
 
-	log.Printf("Patient checked in successfully for appointment ID: %d", checkIn.AppointmentID)
+	log.Printf("Patient checked in successfully for appointment ID: %d, token %d", checkIn.AppointmentID, tokenNumber)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -503,6 +588,7 @@ func GetBedStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	wardFilter := r.URL.Query().Get("ward")
+	floorFilter := r.URL.Query().Get("floor")
 	statusFilter := r.URL.Query().Get("status")
 
 	// Build query with optional filters
@@ -511,16 +597,19 @@ func GetBedStatus(w http.ResponseWriter, r *http.Request) {
 			bi.BedID,
 			bi.BedType,
 			CASE 
-				WHEN ba.PatientID IS NULL THEN 'available'
-				ELSE 'occupied'
+				WHEN ba.PatientID IS NOT NULL AND ba.PlannedDischargeDate IS NOT NULL THEN 'releasing'
+				ELSE bi.Status
 			END as Status,
 			IFNULL(p.PatientID, 0) as PatientID,
 			IFNULL(p.FullName, '') as PatientName,
-			IFNULL(ba.AdmissionDate, '') as AdmissionDate
+			IFNULL(ba.AdmissionDate, '') as AdmissionDate,
+			IFNULL(DATE_FORMAT(ba.PlannedDischargeDate, '%Y-%m-%d'), '') as PlannedDischargeDate,
+			` + bedWardColumns + `
 		FROM 
 			BedInventory bi
+		` + bedWardJoins + `
 		LEFT JOIN 
-			(SELECT BedID, PatientID, AdmissionDate 
+			(SELECT BedID, PatientID, AdmissionDate, PlannedDischargeDate 
 			 FROM BedAssignments 
 			 WHERE DischargeDate IS NULL) ba 
 			ON bi.BedID = ba.BedID
@@ -531,16 +620,29 @@ func GetBedStatus(w http.ResponseWriter, r *http.Request) {
 	var args []interface{}
 	var whereConditions []string
 
+	// A numeric ward is a WardID; otherwise match the ward name, or the bed
+	// type as older clients used it in place of a ward
 	if wardFilter != "" && wardFilter != "all" {
-		whereConditions = append(whereConditions, "bi.BedType = ?")
-		args = append(args, wardFilter)
+		if _, err := strconv.Atoi(wardFilter); err == nil {
+			whereConditions = append(whereConditions, "wd.WardID = ?")
+			args = append(args, wardFilter)
+		} else {
+			whereConditions = append(whereConditions, "(wd.Name = ? OR bi.BedType = ?)")
+			args = append(args, wardFilter, wardFilter)
+		}
+	}
+
+	if floorFilter != "" && floorFilter != "all" {
+		whereConditions = append(whereConditions, "wd.Floor = ?")
+		args = append(args, floorFilter)
 	}
 
 	if statusFilter != "" && statusFilter != "all" {
-		if statusFilter == "available" {
-			whereConditions = append(whereConditions, "ba.PatientID IS NULL")
-		} else if statusFilter == "occupied" {
-			whereConditions = append(whereConditions, "ba.PatientID IS NOT NULL")
+		if statusFilter == "releasing" {
+			whereConditions = append(whereConditions, "ba.PatientID IS NOT NULL AND ba.PlannedDischargeDate IS NOT NULL")
+		} else {
+			whereConditions = append(whereConditions, "bi.Status = ?")
+			args = append(args, statusFilter)
 		}
 	}
 
@@ -549,7 +651,7 @@ func GetBedStatus(w http.ResponseWriter, r *http.Request) {
 		query += " WHERE " + strings.Join(whereConditions, " AND ")
 	}
 
-	query += " ORDER BY bi.BedType, bi.BedID"
+	query += " ORDER BY " + bedWardOrder + ", bi.BedType, bi.BedID"
 
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
@@ -560,19 +662,23 @@ func GetBedStatus(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	var beds []map[string]interface{}
+	groups := []wardGroup{}
 	for rows.Next() {
 		var bedID int
-		var bedType, status, patientName, admissionDate string
+		var bedType, status, patientName, admissionDate, plannedDischargeDate string
 		var patientID int
+		var placement bedPlacement
 
-		err := rows.Scan(
+		dest := []interface{}{
 			&bedID,
 			&bedType,
 			&status,
 			&patientID,
 			&patientName,
 			&admissionDate,
-		)
+			&plannedDischargeDate,
+		}
+		err := rows.Scan(append(dest, placement.dest()...)...)
 
 		if err != nil {
 			log.Printf("Error scanning bed row: %v", err)
@@ -590,8 +696,18 @@ func GetBedStatus(w http.ResponseWriter, r *http.Request) {
 		if admissionDate != "" {
 			bed["admissionDate"] = admissionDate
 		}
+		if plannedDischargeDate != "" {
+			bed["plannedDischargeDate"] = plannedDischargeDate
+		}
+		placement.addTo(bed, bedID)
 
 		beds = append(beds, bed)
+		groups = addToWardGroup(groups, placement.WardID, placement.WardName, placement.Floor, bed)
+	}
+
+	if r.URL.Query().Get("group") == "ward" {
+		json.NewEncoder(w).Encode(groups)
+		return
 	}
 
 	json.NewEncoder(w).Encode(beds)
@@ -605,6 +721,7 @@ func GetStaffAppointments(w http.ResponseWriter, r *http.Request) {
 	doctorFilter := r.URL.Query().Get("doctor")
 	departmentFilter := r.URL.Query().Get("department")
 	dateFilter := r.URL.Query().Get("date")
+	typeFilter := r.URL.Query().Get("type")
 
 	query := `
 		SELECT 
@@ -616,7 +733,9 @@ func GetStaffAppointments(w http.ResponseWriter, r *http.Request) {
 			DATE_FORMAT(a.AppointmentDate, '%Y-%m-%d') as AppointmentDate,
 			a.AppointmentTime,
 			a.Status,
-			a.Description
+			a.Description,
+			a.AppointmentType,
+			a.Overbooked
 		FROM 
 			Appointment a
 		JOIN 
@@ -638,6 +757,11 @@ func GetStaffAppointments(w http.ResponseWriter, r *http.Request) {
 		args = append(args, departmentFilter)
 	}
 
+	if typeFilter != "" && typeFilter != "all" {
+		query += " AND a.AppointmentType = ?"
+		args = append(args, typeFilter)
+	}
+
 	if dateFilter != "" {
 		query += " AND a.AppointmentDate = ?"
 		args = append(args, dateFilter)
@@ -664,6 +788,8 @@ func GetStaffAppointments(w http.ResponseWriter, r *http.Request) {
 		var appointmentID int
 		var doctorName, department, patientName, contactNumber string
 		var appointmentDate, appointmentTime, status, description string
+		var appointmentType string
+		var overbooked bool
 
 		err := rows.Scan(
 			&appointmentID,
@@ -675,6 +801,8 @@ func GetStaffAppointments(w http.ResponseWriter, r *http.Request) {
 			&appointmentTime,
 			&status,
 			&description,
+			&appointmentType,
+			&overbooked,
 		)
 
 		if err != nil {
@@ -692,6 +820,8 @@ func GetStaffAppointments(w http.ResponseWriter, r *http.Request) {
 			"time":        appointmentTime,
 			"status":      status,
 			"description": description,
+			"type":        appointmentType,
+			"overbooked":  overbooked,
 		}
 
 		appointments = append(appointments, appointment)
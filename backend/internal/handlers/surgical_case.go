@@ -0,0 +1,895 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Surgical cases. A case books a patient, surgeon and (by the time it starts)
+// an anaesthetist into a theatre for an estimated duration. Bookings must sit
+// within one of the theatre's sessions, unless they are emergencies, and must
+// not overlap another case in the same theatre (allowing for its turnover
+// time), another case or clinic appointment of either doctor, or another case
+// or appointment of the patient.
+//
+// Booking locks the patient, then the doctors in ID order, then the theatre,
+// then the case being rescheduled, so that two bookings can't both pass the
+// conflict check for the same person or room.
+
+var (
+	errCaseNotFound          = errors.New("surgical case not found")
+	errCaseState             = errors.New("surgical case is not in a state that allows this")
+	errCaseStaff             = errors.New("employee does not work at the theatre's hospital")
+	errCaseSurgeon           = errors.New("surgeon does not work at the theatre's hospital")
+	errCaseAnaesthetist      = errors.New("anaesthetist does not work at the theatre's hospital")
+	errCaseSameDoctor        = errors.New("surgeon and anaesthetist must be different doctors")
+	errCaseOutsideSession    = errors.New("case does not fit within one of the theatre's sessions")
+	errCaseSessionDepartment = errors.New("the theatre session is allocated to another department")
+	errCaseNoAnaesthetist    = errors.New("an anaesthetist must be assigned before the case starts")
+	errCaseReasonLength      = errors.New("reason must be at most 255 characters")
+	errTheatreNotFound       = errors.New("theatre not found")
+	errTheatreInactive       = errors.New("theatre is not in use")
+	errTheatreBusy           = errors.New("another case is already in progress in this theatre")
+	errTheatreInUse          = errors.New("theatre has cases still to come; move or cancel them first")
+	errTheatreHospital       = errors.New("a theatre can't be moved to another hospital")
+)
+
+// caseConflict is a booking that overlaps a proposed case
+type caseConflict struct {
+	Type          string `json:"type"` // theatre, surgeon, anaesthetist, patient
+	With          string `json:"with"` // case, appointment
+	CaseID        int    `json:"caseID,omitempty"`
+	AppointmentID int    `json:"appointmentID,omitempty"`
+	TheatreName   string `json:"theatreName,omitempty"`
+	Description   string `json:"description"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+}
+
+// caseConflictError carries the bookings that clash with a case
+type caseConflictError []caseConflict
+
+func (e caseConflictError) Error() string {
+	return fmt.Sprintf("case conflicts with %d other booking(s)", len(e))
+}
+
+// theatreErrorStatus extends bedErrorStatus with the theatre errors
+func theatreErrorStatus(err error) (int, string) {
+	switch err {
+	case errCaseNotFound, errTheatreNotFound:
+		return http.StatusNotFound, err.Error()
+	case errCaseState, errCaseOutsideSession, errCaseSessionDepartment, errCaseNoAnaesthetist,
+		errTheatreInactive, errTheatreBusy, errTheatreInUse:
+		return http.StatusConflict, err.Error()
+	case errCaseStaff, errCaseSurgeon, errCaseAnaesthetist:
+		return http.StatusForbidden, err.Error()
+	case errCaseSameDoctor, errCaseReasonLength, errTheatreHospital:
+		return http.StatusBadRequest, err.Error()
+	}
+	return bedErrorStatus(err)
+}
+
+// sendTheatreError responds with the status for err, listing the conflicting
+// bookings for a conflict
+func sendTheatreError(w http.ResponseWriter, err error, logMessage string) {
+	var conflicts caseConflictError
+	if errors.As(err, &conflicts) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":     conflicts.Error(),
+			"conflicts": []caseConflict(conflicts),
+		})
+		return
+	}
+	status, message := theatreErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s: %v", logMessage, err)
+	}
+	sendJSONError(w, message, status)
+}
+
+// caseTimeLayout is the format of case start times in requests and responses
+const caseTimeLayout = "2006-01-02 15:04"
+
+const (
+	surgicalCaseColumns = `c.CaseID, c.TheatreID, t.Name, t.HospitalID, c.PatientID, p.FullName, c.ProcedureName,
+		c.SurgeonID, s.FullName, COALESCE(c.AnaesthetistID, 0), COALESCE(a.FullName, ''),
+		DATE_FORMAT(c.ScheduledStart, '%Y-%m-%d %H:%i'), DATE_FORMAT(c.ScheduledEnd, '%Y-%m-%d %H:%i'),
+		c.EstimatedMinutes, c.Emergency, c.Status,
+		COALESCE(DATE_FORMAT(c.StartedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(DATE_FORMAT(c.SurgeryEndedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(DATE_FORMAT(c.CompletedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(c.Notes, ''), COALESCE(c.CancelReason, ''), COALESCE(c.BookedBy, 0),
+		NOT EXISTS (
+			SELECT 1 FROM TheatreSessions ts
+			WHERE ts.TheatreID = c.TheatreID AND ts.Weekday = DAYOFWEEK(c.ScheduledStart) - 1
+			AND ts.StartTime <= TIME(c.ScheduledStart) AND ts.EndTime >= TIME(c.ScheduledEnd)
+			AND DATE(c.ScheduledEnd) = DATE(c.ScheduledStart)
+		),
+		c.Status = 'in_progress' AND c.ScheduledEnd < NOW()`
+	surgicalCaseJoins = `JOIN Theatres t ON c.TheatreID = t.TheatreID
+		JOIN Patients p ON c.PatientID = p.PatientID
+		JOIN Doctors s ON c.SurgeonID = s.DoctorID
+		LEFT JOIN Doctors a ON c.AnaesthetistID = a.DoctorID`
+)
+
+// scanSurgicalCase reads a row selected with surgicalCaseColumns
+func scanSurgicalCase(row interface{ Scan(...interface{}) error }) (models.SurgicalCase, error) {
+	var c models.SurgicalCase
+	err := row.Scan(&c.CaseID, &c.TheatreID, &c.TheatreName, &c.HospitalID, &c.PatientID, &c.PatientName, &c.Procedure,
+		&c.SurgeonID, &c.SurgeonName, &c.AnaesthetistID, &c.AnaesthetistName,
+		&c.ScheduledStart, &c.ScheduledEnd,
+		&c.EstimatedMinutes, &c.Emergency, &c.Status,
+		&c.StartedAt, &c.SurgeryEndedAt, &c.CompletedAt,
+		&c.Notes, &c.CancelReason, &c.BookedBy,
+		&c.OutsideSession, &c.Overrunning)
+	return c, err
+}
+
+// loadSurgicalCase reads one case
+func loadSurgicalCase(caseID int) (models.SurgicalCase, error) {
+	c, err := scanSurgicalCase(database.DB.QueryRow(`
+		SELECT `+surgicalCaseColumns+`
+		FROM SurgicalCases c
+		`+surgicalCaseJoins+`
+		WHERE c.CaseID = ?
+	`, caseID))
+	if err == sql.ErrNoRows {
+		return c, errCaseNotFound
+	}
+	return c, err
+}
+
+// caseBooking is a case to be booked or rescheduled
+type caseBooking struct {
+	EmployeeID       int    `json:"employeeId"`
+	TheatreID        int    `json:"theatreId"`
+	PatientID        int    `json:"patientId"`
+	Procedure        string `json:"procedure"`
+	SurgeonID        int    `json:"surgeonId"`
+	AnaesthetistID   int    `json:"anaesthetistId"`
+	ScheduledStart   string `json:"scheduledStart"` // YYYY-MM-DD HH:MM
+	EstimatedMinutes int    `json:"estimatedMinutes"`
+	Emergency        bool   `json:"emergency"`
+	Notes            string `json:"notes"`
+
+	start, end time.Time
+}
+
+// validate checks the booking's fields and works out its start and end. A
+// start in the past is only accepted when it is bookedStart, the start the
+// case already has, so an overdue case can still get an anaesthetist or notes.
+func (b *caseBooking) validate(bookedStart string) error {
+	b.Procedure = strings.TrimSpace(b.Procedure)
+	if b.EmployeeID == 0 || b.TheatreID == 0 || b.PatientID == 0 || b.SurgeonID == 0 ||
+		b.Procedure == "" || b.ScheduledStart == "" {
+		return fmt.Errorf("employeeId, theatreId, patientId, surgeonId, procedure and scheduledStart are required")
+	}
+	if len(b.Procedure) > 255 {
+		return fmt.Errorf("procedure must be at most 255 characters")
+	}
+	if b.EstimatedMinutes < 1 || b.EstimatedMinutes > 1440 {
+		return fmt.Errorf("estimatedMinutes must be between 1 and 1440")
+	}
+	if b.SurgeonID == b.AnaesthetistID {
+		return errCaseSameDoctor
+	}
+	start, err := time.ParseInLocation(caseTimeLayout, b.ScheduledStart, time.Local)
+	if err != nil {
+		return fmt.Errorf("scheduledStart must be formatted YYYY-MM-DD HH:MM")
+	}
+	if start.Before(time.Now().Truncate(time.Minute)) && start.Format(caseTimeLayout) != bookedStart {
+		return fmt.Errorf("scheduledStart must not be in the past")
+	}
+	b.start = start
+	b.end = start.Add(time.Duration(b.EstimatedMinutes) * time.Minute)
+	return nil
+}
+
+// doctors returns the booking's doctors in the order they are locked
+func (b caseBooking) doctors() []int {
+	ids := []int{b.SurgeonID}
+	if b.AnaesthetistID != 0 {
+		ids = append(ids, b.AnaesthetistID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// bookCase checks a booking against the theatre's sessions and every other
+// booking of the theatre, doctors and patient, then saves it as a new case,
+// or over caseID when rescheduling. It returns the case's ID.
+func bookCase(tx *sql.Tx, caseID int, b caseBooking) (int, error) {
+	var locked int
+	err := tx.QueryRow("SELECT PatientID FROM Patients WHERE PatientID = ? FOR UPDATE", b.PatientID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return 0, errPatientNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	departments := make(map[int]string)
+	for _, doctorID := range b.doctors() {
+		var department string
+		err := tx.QueryRow("SELECT Department FROM Doctors WHERE DoctorID = ? FOR UPDATE", doctorID).Scan(&department)
+		if err == sql.ErrNoRows {
+			continue // reported as not working at the hospital below
+		}
+		if err != nil {
+			return 0, err
+		}
+		departments[doctorID] = department
+	}
+
+	var hospitalID, turnover int
+	var active bool
+	err = tx.QueryRow(
+		"SELECT HospitalID, TurnoverMinutes, Active FROM Theatres WHERE TheatreID = ? FOR UPDATE",
+		b.TheatreID).Scan(&hospitalID, &turnover, &active)
+	if err == sql.ErrNoRows {
+		return 0, errTheatreNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !active {
+		return 0, errTheatreInactive
+	}
+
+	if caseID != 0 {
+		var status string
+		err := tx.QueryRow("SELECT Status FROM SurgicalCases WHERE CaseID = ? FOR UPDATE", caseID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return 0, errCaseNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		if status != "scheduled" {
+			return 0, errCaseState
+		}
+	}
+
+	if _, staffHospital, err := doctorForEmployee(b.EmployeeID); err == sql.ErrNoRows || (err == nil && staffHospital != hospitalID) {
+		return 0, errCaseStaff
+	} else if err != nil {
+		return 0, err
+	}
+	for _, doctor := range []struct {
+		id         int
+		errNotHere error
+	}{{b.SurgeonID, errCaseSurgeon}, {b.AnaesthetistID, errCaseAnaesthetist}} {
+		if doctor.id == 0 {
+			continue
+		}
+		works, err := doctorWorksAt(tx, doctor.id, hospitalID)
+		if err != nil {
+			return 0, err
+		}
+		if !works {
+			return 0, doctor.errNotHere
+		}
+	}
+
+	if !b.Emergency {
+		if err := checkCaseSession(tx, b, departments[b.SurgeonID]); err != nil {
+			return 0, err
+		}
+	}
+
+	conflicts, err := findCaseConflicts(tx, caseID, b, turnover)
+	if err != nil {
+		return 0, err
+	}
+	if len(conflicts) > 0 {
+		return 0, caseConflictError(conflicts)
+	}
+
+	start := b.start.Format("2006-01-02 15:04:05")
+	if caseID != 0 {
+		_, err := tx.Exec(`
+			UPDATE SurgicalCases
+			SET TheatreID = ?, ProcedureName = ?, SurgeonID = ?, AnaesthetistID = NULLIF(?, 0),
+			    ScheduledStart = ?, EstimatedMinutes = ?, Emergency = ?, Notes = NULLIF(?, '')
+			WHERE CaseID = ?
+		`, b.TheatreID, b.Procedure, b.SurgeonID, b.AnaesthetistID, start, b.EstimatedMinutes, b.Emergency, b.Notes, caseID)
+		return caseID, err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO SurgicalCases
+			(TheatreID, PatientID, ProcedureName, SurgeonID, AnaesthetistID, ScheduledStart, EstimatedMinutes,
+			 Emergency, Notes, BookedBy)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0), ?, ?, ?, NULLIF(?, ''), ?)
+	`, b.TheatreID, b.PatientID, b.Procedure, b.SurgeonID, b.AnaesthetistID, start, b.EstimatedMinutes,
+		b.Emergency, b.Notes, b.EmployeeID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// checkCaseSession checks that a booking fits within one of the theatre's
+// sessions, and that a session allocated to a department is used by a
+// surgeon from it
+func checkCaseSession(q queryer, b caseBooking, surgeonDepartment string) error {
+	if b.end.YearDay() != b.start.YearDay() || b.end.Year() != b.start.Year() {
+		return errCaseOutsideSession
+	}
+	var department string
+	err := q.QueryRow(`
+		SELECT COALESCE(Department, '')
+		FROM TheatreSessions
+		WHERE TheatreID = ? AND Weekday = ? AND StartTime <= ? AND EndTime >= ?
+		ORDER BY Department IS NOT NULL
+		LIMIT 1
+	`, b.TheatreID, int(b.start.Weekday()), b.start.Format("15:04:05"), b.end.Format("15:04:05")).Scan(&department)
+	if err == sql.ErrNoRows {
+		return errCaseOutsideSession
+	}
+	if err != nil {
+		return err
+	}
+	if department != "" && !strings.EqualFold(department, surgeonDepartment) {
+		return errCaseSessionDepartment
+	}
+	return nil
+}
+
+// findCaseConflicts returns the bookings that clash with b, other than the
+// case being rescheduled. Cases in progress or in recovery are taken to last
+// at least until now. A case in recovery has left the theatre and its doctors,
+// so it only conflicts with its patient's bookings.
+func findCaseConflicts(q queryer, caseID int, b caseBooking, turnover int) ([]caseConflict, error) {
+	buffer := time.Duration(turnover) * time.Minute
+	rows, err := q.Query(`
+		SELECT c.CaseID, c.TheatreID, t.Name, c.PatientID, c.SurgeonID, COALESCE(c.AnaesthetistID, 0),
+		       c.ProcedureName, c.Status, c.ScheduledStart,
+		       IF(c.Status IN ('in_progress', 'in_recovery'), GREATEST(c.ScheduledEnd, NOW()), c.ScheduledEnd)
+		FROM SurgicalCases c
+		JOIN Theatres t ON c.TheatreID = t.TheatreID
+		WHERE c.CaseID <> ? AND c.Status IN ('scheduled', 'in_progress', 'in_recovery')
+		AND c.ScheduledStart < ?
+		AND IF(c.Status IN ('in_progress', 'in_recovery'), GREATEST(c.ScheduledEnd, NOW()), c.ScheduledEnd) > ?
+		AND (c.TheatreID = ? OR c.PatientID = ? OR c.SurgeonID IN (?, ?) OR c.AnaesthetistID IN (?, ?))
+		ORDER BY c.ScheduledStart, c.CaseID
+	`, caseID, b.end.Add(buffer).Format("2006-01-02 15:04:05"), b.start.Add(-buffer).Format("2006-01-02 15:04:05"),
+		b.TheatreID, b.PatientID, b.SurgeonID, b.AnaesthetistID, b.SurgeonID, b.AnaesthetistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conflicts := []caseConflict{}
+	for rows.Next() {
+		var id, theatreID, patientID, surgeonID, anaesthetistID int
+		var theatreName, procedure, status string
+		var start, end time.Time
+		err := rows.Scan(&id, &theatreID, &theatreName, &patientID, &surgeonID, &anaesthetistID,
+			&procedure, &status, &start, &end)
+		if err != nil {
+			return nil, err
+		}
+		overlaps := start.Before(b.end) && end.After(b.start)
+		conflict := caseConflict{
+			With:        "case",
+			CaseID:      id,
+			TheatreName: theatreName,
+			Description: procedure,
+			Start:       start.Format(caseTimeLayout),
+			End:         end.Format(caseTimeLayout),
+		}
+		add := func(kind string) {
+			conflict.Type = kind
+			conflicts = append(conflicts, conflict)
+		}
+		if theatreID == b.TheatreID && status != "in_recovery" && start.Before(b.end.Add(buffer)) && end.Add(buffer).After(b.start) {
+			add("theatre")
+		}
+		if !overlaps {
+			continue
+		}
+		if patientID == b.PatientID {
+			add("patient")
+		}
+		if status == "in_recovery" {
+			continue
+		}
+		if b.SurgeonID == surgeonID || b.SurgeonID == anaesthetistID {
+			add("surgeon")
+		}
+		if b.AnaesthetistID != 0 && (b.AnaesthetistID == surgeonID || b.AnaesthetistID == anaesthetistID) {
+			add("anaesthetist")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	appointments, err := findAppointmentConflicts(q, b)
+	if err != nil {
+		return nil, err
+	}
+	return append(conflicts, appointments...), nil
+}
+
+// findAppointmentConflicts returns the scheduled clinic appointments of the
+// booking's doctors and patient that overlap it
+func findAppointmentConflicts(q queryer, b caseBooking) ([]caseConflict, error) {
+	rows, err := q.Query(`
+		SELECT a.AppointmentID, a.DoctorID, a.PatientID, a.AppointmentDate, a.AppointmentTime,
+		       d.FullName, COALESCE(a.Description, '')
+		FROM Appointment a
+		JOIN Doctors d ON a.DoctorID = d.DoctorID
+		WHERE a.Status = 'scheduled' AND a.AppointmentDate BETWEEN ? AND ?
+		AND (a.DoctorID IN (?, ?) OR a.PatientID = ?)
+		ORDER BY a.AppointmentDate, a.AppointmentID
+	`, b.start.Format("2006-01-02"), b.end.Format("2006-01-02"), b.SurgeonID, b.AnaesthetistID, b.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conflicts := []caseConflict{}
+	for rows.Next() {
+		var id, doctorID, patientID int
+		var date time.Time
+		var slot, doctorName, description string
+		if err := rows.Scan(&id, &doctorID, &patientID, &date, &slot, &doctorName, &description); err != nil {
+			return nil, err
+		}
+		start, err := slotStart(date, slot)
+		if err != nil {
+			continue // not a bookable slot time
+		}
+		end := start.Add(slotDuration)
+		if !start.Before(b.end) || !end.After(b.start) {
+			continue
+		}
+		conflict := caseConflict{
+			With:          "appointment",
+			AppointmentID: id,
+			Description:   strings.TrimSpace("Appointment with " + doctorName + " " + description),
+			Start:         start.Format(caseTimeLayout),
+			End:           end.Format(caseTimeLayout),
+		}
+		for _, role := range []struct {
+			kind    string
+			matches bool
+		}{
+			{"patient", patientID == b.PatientID},
+			{"surgeon", doctorID == b.SurgeonID},
+			{"anaesthetist", b.AnaesthetistID != 0 && doctorID == b.AnaesthetistID},
+		} {
+			if role.matches {
+				conflict.Type = role.kind
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	return conflicts, rows.Err()
+}
+
+// BookSurgicalCase books a case into a theatre. A clash with another booking
+// is refused with 409 and the list of conflicting bookings.
+func BookSurgicalCase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var b caseBooking
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := b.validate(""); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var caseID int
+	err := withTx(func(tx *sql.Tx) error {
+		var err error
+		caseID, err = bookCase(tx, 0, b)
+		return err
+	})
+	if err != nil {
+		sendTheatreError(w, err, "Error booking surgical case")
+		return
+	}
+
+	c, err := loadSurgicalCase(caseID)
+	if err != nil {
+		log.Printf("Error fetching surgical case %d: %v", caseID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Surgical case %d booked in theatre %d at %s", caseID, c.TheatreID, c.ScheduledStart)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// UpdateSurgicalCase reschedules a case that hasn't started: its theatre,
+// start, duration, procedure or doctors. Fields left out keep their values;
+// anaesthetistId 0 removes the anaesthetist, and the patient can't be changed.
+// A case whose start has passed can still be updated as long as the start
+// stays the same.
+func UpdateSurgicalCase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	caseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid case ID", http.StatusBadRequest)
+		return
+	}
+	current, err := loadSurgicalCase(caseID)
+	if err != nil {
+		sendTheatreError(w, err, "Error fetching surgical case")
+		return
+	}
+
+	var req struct {
+		caseBooking
+		AnaesthetistID *int    `json:"anaesthetistId"` // 0 removes the anaesthetist
+		Emergency      *bool   `json:"emergency"`
+		Notes          *string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	b := req.caseBooking
+	if b.PatientID != 0 && b.PatientID != current.PatientID {
+		sendJSONError(w, "The patient of a case can't be changed; cancel it and book a new one", http.StatusBadRequest)
+		return
+	}
+	b.PatientID = current.PatientID
+	if b.TheatreID == 0 {
+		b.TheatreID = current.TheatreID
+	}
+	if b.Procedure == "" {
+		b.Procedure = current.Procedure
+	}
+	if b.SurgeonID == 0 {
+		b.SurgeonID = current.SurgeonID
+	}
+	b.AnaesthetistID = current.AnaesthetistID
+	if req.AnaesthetistID != nil {
+		b.AnaesthetistID = *req.AnaesthetistID
+	}
+	if b.ScheduledStart == "" {
+		b.ScheduledStart = current.ScheduledStart
+	}
+	if b.EstimatedMinutes == 0 {
+		b.EstimatedMinutes = current.EstimatedMinutes
+	}
+	b.Emergency = current.Emergency
+	if req.Emergency != nil {
+		b.Emergency = *req.Emergency
+	}
+	b.Notes = current.Notes
+	if req.Notes != nil {
+		b.Notes = *req.Notes
+	}
+	if err := b.validate(current.ScheduledStart); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = withTx(func(tx *sql.Tx) error {
+		_, err := bookCase(tx, caseID, b)
+		return err
+	})
+	if err != nil {
+		sendTheatreError(w, err, "Error rescheduling surgical case")
+		return
+	}
+
+	c, err := loadSurgicalCase(caseID)
+	if err != nil {
+		log.Printf("Error fetching surgical case %d: %v", caseID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// GetSurgicalCase returns one case
+func GetSurgicalCase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	caseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid case ID", http.StatusBadRequest)
+		return
+	}
+	c, err := loadSurgicalCase(caseID)
+	if err != nil {
+		sendTheatreError(w, err, "Error fetching surgical case")
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// GetSurgicalCases lists cases from a date to a date (inclusive, default
+// today onwards), optionally by hospitalId, theatreId, patientId, surgeonId,
+// anaesthetistId and status. Cancelled cases are only listed when asked for
+// by status.
+func GetSurgicalCases(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	var conditions []string
+	var args []interface{}
+	switch status := query.Get("status"); status {
+	case "":
+		conditions = append(conditions, "c.Status <> 'cancelled'")
+	case "all":
+	default:
+		conditions = append(conditions, "c.Status = ?")
+		args = append(args, status)
+	}
+	for name, column := range map[string]string{
+		"hospitalId":     "t.HospitalID",
+		"theatreId":      "c.TheatreID",
+		"patientId":      "c.PatientID",
+		"surgeonId":      "c.SurgeonID",
+		"anaesthetistId": "c.AnaesthetistID",
+	} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				sendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			conditions = append(conditions, column+" = ?")
+			args = append(args, id)
+		}
+	}
+
+	from := query.Get("from")
+	if from == "" {
+		from = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", from); err != nil {
+		sendJSONError(w, "from must be formatted YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	conditions = append(conditions, "c.ScheduledStart >= ?")
+	args = append(args, from)
+	if to := query.Get("to"); to != "" {
+		if _, err := time.Parse("2006-01-02", to); err != nil {
+			sendJSONError(w, "to must be formatted YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		conditions = append(conditions, "c.ScheduledStart < ? + INTERVAL 1 DAY")
+		args = append(args, to)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+surgicalCaseColumns+`
+		FROM SurgicalCases c
+		`+surgicalCaseJoins+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY c.ScheduledStart, t.Name, c.CaseID
+	`, args...)
+	if err != nil {
+		log.Printf("Error querying surgical cases: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	cases := []models.SurgicalCase{}
+	for rows.Next() {
+		c, err := scanSurgicalCase(rows)
+		if err != nil {
+			log.Printf("Error scanning surgical case row: %v", err)
+			continue
+		}
+		cases = append(cases, c)
+	}
+
+	json.NewEncoder(w).Encode(cases)
+}
+
+// caseStepRequest is the body accepted by every case step
+type caseStepRequest struct {
+	EmployeeID int    `json:"employeeId"`
+	Reason     string `json:"reason"`
+	Notes      string `json:"notes"`
+}
+
+// lockedCase is the part of a case the status steps need
+type lockedCase struct {
+	CaseID         int
+	TheatreID      int
+	HospitalID     int
+	Status         string
+	AnaesthetistID int
+}
+
+// caseStep advances a locked case
+type caseStep func(tx *sql.Tx, c lockedCase, req caseStepRequest) error
+
+// runCaseStep decodes a step request, runs the step in a transaction and
+// responds with the updated case
+func runCaseStep(w http.ResponseWriter, r *http.Request, message string, step caseStep) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	caseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid case ID", http.StatusBadRequest)
+		return
+	}
+	var req caseStepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding surgical case step: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EmployeeID == 0 {
+		sendJSONError(w, "employeeId is required", http.StatusBadRequest)
+		return
+	}
+
+	err = withTx(func(tx *sql.Tx) error {
+		c := lockedCase{CaseID: caseID}
+		err := tx.QueryRow(`
+			SELECT c.TheatreID, t.HospitalID, c.Status, COALESCE(c.AnaesthetistID, 0)
+			FROM SurgicalCases c
+			JOIN Theatres t ON c.TheatreID = t.TheatreID
+			WHERE c.CaseID = ?
+			FOR UPDATE
+		`, caseID).Scan(&c.TheatreID, &c.HospitalID, &c.Status, &c.AnaesthetistID)
+		if err == sql.ErrNoRows {
+			return errCaseNotFound
+		}
+		if err != nil {
+			return err
+		}
+		_, staffHospital, err := doctorForEmployee(req.EmployeeID)
+		if err == sql.ErrNoRows || (err == nil && staffHospital != c.HospitalID) {
+			return errCaseStaff
+		}
+		if err != nil {
+			return err
+		}
+		return step(tx, c, req)
+	})
+	if err != nil {
+		sendTheatreError(w, err, fmt.Sprintf("Error updating surgical case %d", caseID))
+		return
+	}
+
+	c, err := loadSurgicalCase(caseID)
+	if err != nil {
+		log.Printf("Error fetching surgical case %d: %v", caseID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+		"case":    c,
+	})
+}
+
+// StartSurgicalCase records that the patient is in theatre and the case has begun
+func StartSurgicalCase(w http.ResponseWriter, r *http.Request) {
+	runCaseStep(w, r, "Case started", func(tx *sql.Tx, c lockedCase, req caseStepRequest) error {
+		if c.Status != "scheduled" {
+			return errCaseState
+		}
+		if c.AnaesthetistID == 0 {
+			return errCaseNoAnaesthetist
+		}
+		var busy bool
+		err := tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM SurgicalCases WHERE TheatreID = ? AND Status = 'in_progress' AND CaseID <> ?)",
+			c.TheatreID, c.CaseID).Scan(&busy)
+		if err != nil {
+			return err
+		}
+		if busy {
+			return errTheatreBusy
+		}
+		_, err = tx.Exec(`
+			UPDATE SurgicalCases
+			SET Status = 'in_progress', StartedAt = NOW(), Notes = CONCAT_WS('\n', Notes, NULLIF(?, ''))
+			WHERE CaseID = ?
+		`, req.Notes, c.CaseID)
+		return err
+	})
+}
+
+// MoveSurgicalCaseToRecovery records that surgery is over and the patient
+// has left theatre for recovery, freeing the theatre
+func MoveSurgicalCaseToRecovery(w http.ResponseWriter, r *http.Request) {
+	runCaseStep(w, r, "Patient moved to recovery", func(tx *sql.Tx, c lockedCase, req caseStepRequest) error {
+		if c.Status != "in_progress" {
+			return errCaseState
+		}
+		_, err := tx.Exec(`
+			UPDATE SurgicalCases
+			SET Status = 'in_recovery', SurgeryEndedAt = NOW(), Notes = CONCAT_WS('\n', Notes, NULLIF(?, ''))
+			WHERE CaseID = ?
+		`, req.Notes, c.CaseID)
+		return err
+	})
+}
+
+// CompleteSurgicalCase records that the patient has been discharged from
+// recovery. A case that needed no recovery can be completed straight from
+// theatre.
+func CompleteSurgicalCase(w http.ResponseWriter, r *http.Request) {
+	runCaseStep(w, r, "Case completed", func(tx *sql.Tx, c lockedCase, req caseStepRequest) error {
+		if c.Status != "in_progress" && c.Status != "in_recovery" {
+			return errCaseState
+		}
+		_, err := tx.Exec(`
+			UPDATE SurgicalCases
+			SET Status = 'done', SurgeryEndedAt = COALESCE(SurgeryEndedAt, NOW()), CompletedAt = NOW(),
+			    Notes = CONCAT_WS('\n', Notes, NULLIF(?, ''))
+			WHERE CaseID = ?
+		`, req.Notes, c.CaseID)
+		return err
+	})
+}
+
+// CancelSurgicalCase cancels a case that hasn't started, freeing its slot
+func CancelSurgicalCase(w http.ResponseWriter, r *http.Request) {
+	runCaseStep(w, r, "Case cancelled", func(tx *sql.Tx, c lockedCase, req caseStepRequest) error {
+		if c.Status != "scheduled" {
+			return errCaseState
+		}
+		if len(strings.TrimSpace(req.Reason)) > 255 {
+			return errCaseReasonLength
+		}
+		_, err := tx.Exec(`
+			UPDATE SurgicalCases
+			SET Status = 'cancelled', CancelReason = NULLIF(?, ''), Notes = CONCAT_WS('\n', Notes, NULLIF(?, ''))
+			WHERE CaseID = ?
+		`, strings.TrimSpace(req.Reason), req.Notes, c.CaseID)
+		return err
+	})
+}
@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+)
+
+// sessionTimeLayout is the format of theatre session times
+const sessionTimeLayout = "15:04"
+
+// theatreSessionsByID returns the sessions of the given theatres, optionally
+// only those on one weekday, keyed by theatre
+func theatreSessionsByID(q queryer, theatreIDs []int, weekday int) (map[int][]models.TheatreSession, error) {
+	sessions := make(map[int][]models.TheatreSession)
+	if len(theatreIDs) == 0 {
+		return sessions, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(theatreIDs)), ", ")
+	args := make([]interface{}, 0, len(theatreIDs)+1)
+	for _, id := range theatreIDs {
+		args = append(args, id)
+	}
+	query := `
+		SELECT TheatreID, SessionID, Weekday, TIME_FORMAT(StartTime, '%H:%i'), TIME_FORMAT(EndTime, '%H:%i'),
+		       COALESCE(Department, '')
+		FROM TheatreSessions
+		WHERE TheatreID IN (` + placeholders + `)`
+	if weekday >= 0 {
+		query += " AND Weekday = ?"
+		args = append(args, weekday)
+	}
+	query += " ORDER BY TheatreID, Weekday, StartTime"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var theatreID int
+		var s models.TheatreSession
+		if err := rows.Scan(&theatreID, &s.SessionID, &s.Weekday, &s.StartTime, &s.EndTime, &s.Department); err != nil {
+			return nil, err
+		}
+		s.WeekdayName = time.Weekday(s.Weekday).String()
+		sessions[theatreID] = append(sessions[theatreID], s)
+	}
+	return sessions, rows.Err()
+}
+
+// sessionMinutes is the length of a session
+func sessionMinutes(s models.TheatreSession) int {
+	start, _ := time.Parse(sessionTimeLayout, s.StartTime)
+	end, _ := time.Parse(sessionTimeLayout, s.EndTime)
+	return int(end.Sub(start).Minutes())
+}
+
+// theatreRequest is the body of a theatre create or update. Sessions left out
+// of an update are kept; an empty list removes them all.
+type theatreRequest struct {
+	HospitalID      int                     `json:"hospitalID"`
+	Name            string                  `json:"name"`
+	Specialty       string                  `json:"specialty"`
+	TurnoverMinutes *int                    `json:"turnoverMinutes"`
+	Active          *bool                   `json:"active"`
+	Sessions        []models.TheatreSession `json:"sessions"`
+}
+
+// validate checks the theatre's fields and its sessions, which must not
+// overlap on the same weekday
+func (t *theatreRequest) validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Specialty = strings.TrimSpace(t.Specialty)
+	if t.Name == "" || t.HospitalID == 0 {
+		return fmt.Errorf("hospitalID and name are required")
+	}
+	if t.TurnoverMinutes != nil && (*t.TurnoverMinutes < 0 || *t.TurnoverMinutes > 240) {
+		return fmt.Errorf("turnoverMinutes must be between 0 and 240")
+	}
+
+	for i := range t.Sessions {
+		s := &t.Sessions[i]
+		if s.Weekday < 0 || s.Weekday > 6 {
+			return fmt.Errorf("session weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, err := time.Parse(sessionTimeLayout, s.StartTime)
+		if err != nil {
+			return fmt.Errorf("session startTime must be formatted HH:MM")
+		}
+		end, err := time.Parse(sessionTimeLayout, s.EndTime)
+		if err != nil {
+			return fmt.Errorf("session endTime must be formatted HH:MM")
+		}
+		if !start.Before(end) {
+			return fmt.Errorf("session startTime must be before its endTime")
+		}
+		s.StartTime, s.EndTime = start.Format(sessionTimeLayout), end.Format(sessionTimeLayout)
+		s.Department = strings.TrimSpace(s.Department)
+	}
+
+	sorted := append([]models.TheatreSession(nil), t.Sessions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Weekday != sorted[j].Weekday {
+			return sorted[i].Weekday < sorted[j].Weekday
+		}
+		return sorted[i].StartTime < sorted[j].StartTime
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Weekday == sorted[i-1].Weekday && sorted[i].StartTime < sorted[i-1].EndTime {
+			return fmt.Errorf("sessions on %s overlap", time.Weekday(sorted[i].Weekday))
+		}
+	}
+	return nil
+}
+
+// replaceTheatreSessions replaces a theatre's weekly sessions
+func replaceTheatreSessions(tx *sql.Tx, theatreID int, sessions []models.TheatreSession) error {
+	if _, err := tx.Exec("DELETE FROM TheatreSessions WHERE TheatreID = ?", theatreID); err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		_, err := tx.Exec(`
+			INSERT INTO TheatreSessions (TheatreID, Weekday, StartTime, EndTime, Department)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''))
+		`, theatreID, s.Weekday, s.StartTime, s.EndTime, s.Department)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTheatre reads a theatre with its sessions
+func loadTheatre(q queryer, theatreID int) (models.Theatre, error) {
+	var t models.Theatre
+	err := q.QueryRow(`
+		SELECT TheatreID, HospitalID, Name, COALESCE(Specialty, ''), TurnoverMinutes, Active
+		FROM Theatres WHERE TheatreID = ?
+	`, theatreID).Scan(&t.TheatreID, &t.HospitalID, &t.Name, &t.Specialty, &t.TurnoverMinutes, &t.Active)
+	if err == sql.ErrNoRows {
+		return t, errTheatreNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+	sessions, err := theatreSessionsByID(q, []int{theatreID}, -1)
+	t.Sessions = sessions[theatreID]
+	if t.Sessions == nil {
+		t.Sessions = []models.TheatreSession{}
+	}
+	return t, err
+}
+
+// GetTheatres lists theatres with their weekly sessions, optionally for one
+// hospitalId. Theatres no longer in use are left out unless
+// includeInactive=true.
+func GetTheatres(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := `
+		SELECT TheatreID, HospitalID, Name, COALESCE(Specialty, ''), TurnoverMinutes, Active
+		FROM Theatres
+	`
+	var conditions []string
+	var args []interface{}
+	if hospitalID := r.URL.Query().Get("hospitalId"); hospitalID != "" {
+		conditions = append(conditions, "HospitalID = ?")
+		args = append(args, hospitalID)
+	}
+	if r.URL.Query().Get("includeInactive") != "true" {
+		conditions = append(conditions, "Active = TRUE")
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY HospitalID, Name"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying theatres: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	theatres := []models.Theatre{}
+	var ids []int
+	for rows.Next() {
+		var t models.Theatre
+		if err := rows.Scan(&t.TheatreID, &t.HospitalID, &t.Name, &t.Specialty, &t.TurnoverMinutes, &t.Active); err != nil {
+			log.Printf("Error scanning theatre row: %v", err)
+			continue
+		}
+		theatres = append(theatres, t)
+		ids = append(ids, t.TheatreID)
+	}
+	rows.Close()
+
+	sessions, err := theatreSessionsByID(database.DB, ids, -1)
+	if err != nil {
+		log.Printf("Error querying theatre sessions: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for i := range theatres {
+		theatres[i].Sessions = sessions[theatres[i].TheatreID]
+		if theatres[i].Sessions == nil {
+			theatres[i].Sessions = []models.TheatreSession{}
+		}
+	}
+
+	json.NewEncoder(w).Encode(theatres)
+}
+
+// CreateTheatre adds a theatre to a hospital with its weekly sessions
+func CreateTheatre(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req theatreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	turnover := 15
+	if req.TurnoverMinutes != nil {
+		turnover = *req.TurnoverMinutes
+	}
+
+	var theatreID int
+	err := withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			INSERT INTO Theatres (HospitalID, Name, Specialty, TurnoverMinutes)
+			VALUES (?, ?, NULLIF(?, ''), ?)
+		`, req.HospitalID, req.Name, req.Specialty, turnover)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		theatreID = int(id)
+		return replaceTheatreSessions(tx, theatreID, req.Sessions)
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			sendJSONError(w, "A theatre with this name already exists in the hospital", http.StatusConflict)
+			return
+		}
+		log.Printf("Error creating theatre: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	t, err := loadTheatre(database.DB, theatreID)
+	if err != nil {
+		log.Printf("Error fetching theatre %d: %v", theatreID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// UpdateTheatre changes a theatre's details and, when sessions are given,
+// replaces its weekly sessions. A theatre with cases still to come can't be
+// taken out of use.
+func UpdateTheatre(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	theatreID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid theatre ID", http.StatusBadRequest)
+		return
+	}
+	var req theatreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = withTx(func(tx *sql.Tx) error {
+		var hospitalID int
+		err := tx.QueryRow("SELECT HospitalID FROM Theatres WHERE TheatreID = ? FOR UPDATE", theatreID).Scan(&hospitalID)
+		if err == sql.ErrNoRows {
+			return errTheatreNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if req.HospitalID != hospitalID {
+			return errTheatreHospital
+		}
+		if req.Active != nil && !*req.Active {
+			var upcoming int
+			err := tx.QueryRow(
+				"SELECT COUNT(*) FROM SurgicalCases WHERE TheatreID = ? AND Status IN ('scheduled', 'in_progress')",
+				theatreID).Scan(&upcoming)
+			if err != nil {
+				return err
+			}
+			if upcoming > 0 {
+				return errTheatreInUse
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE Theatres
+			SET Name = ?, Specialty = NULLIF(?, ''), TurnoverMinutes = COALESCE(?, TurnoverMinutes),
+			    Active = COALESCE(?, Active)
+			WHERE TheatreID = ?
+		`, req.Name, req.Specialty, req.TurnoverMinutes, req.Active, theatreID)
+		if err != nil || req.Sessions == nil {
+			return err
+		}
+		return replaceTheatreSessions(tx, theatreID, req.Sessions)
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			sendJSONError(w, "A theatre with this name already exists in the hospital", http.StatusConflict)
+			return
+		}
+		sendTheatreError(w, err, "Error updating theatre")
+		return
+	}
+
+	t, err := loadTheatre(database.DB, theatreID)
+	if err != nil {
+		log.Printf("Error fetching theatre %d: %v", theatreID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+// theatreDay is one theatre's list for a day
+type theatreDay struct {
+	models.Theatre
+	Cases          []models.SurgicalCase `json:"cases"`
+	SessionMinutes int                   `json:"sessionMinutes"`
+	BookedMinutes  int                   `json:"bookedMinutes"`
+	FreeMinutes    int                   `json:"freeMinutes"`
+	Utilisation    float64               `json:"utilisation"` // booked minutes as a percentage of session minutes
+}
+
+// GetTheatreList returns the day's theatre list for a hospitalId: each
+// theatre's sessions on that date (default today) and its cases in order,
+// with booked and free session time. Theatres not in use are only listed if
+// they have cases that day.
+func GetTheatreList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	hospitalID, err := strconv.Atoi(r.URL.Query().Get("hospitalId"))
+	if err != nil {
+		sendJSONError(w, "hospitalId is required", http.StatusBadRequest)
+		return
+	}
+	date := time.Now()
+	if value := r.URL.Query().Get("date"); value != "" {
+		date, err = time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			sendJSONError(w, "date must be formatted YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	day := date.Format("2006-01-02")
+
+	rows, err := database.DB.Query(`
+		SELECT TheatreID, HospitalID, Name, COALESCE(Specialty, ''), TurnoverMinutes, Active
+		FROM Theatres t
+		WHERE HospitalID = ? AND (Active = TRUE OR EXISTS (
+			SELECT 1 FROM SurgicalCases c
+			WHERE c.TheatreID = t.TheatreID AND c.Status <> 'cancelled'
+			AND c.ScheduledStart >= ? AND c.ScheduledStart < ? + INTERVAL 1 DAY
+		))
+		ORDER BY Name
+	`, hospitalID, day, day)
+	if err != nil {
+		log.Printf("Error querying theatres: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	theatres := []theatreDay{}
+	index := make(map[int]int)
+	var ids []int
+	for rows.Next() {
+		var t theatreDay
+		if err := rows.Scan(&t.TheatreID, &t.HospitalID, &t.Name, &t.Specialty, &t.TurnoverMinutes, &t.Active); err != nil {
+			log.Printf("Error scanning theatre row: %v", err)
+			continue
+		}
+		t.Sessions = []models.TheatreSession{}
+		t.Cases = []models.SurgicalCase{}
+		index[t.TheatreID] = len(theatres)
+		theatres = append(theatres, t)
+		ids = append(ids, t.TheatreID)
+	}
+	rows.Close()
+
+	sessions, err := theatreSessionsByID(database.DB, ids, int(date.Weekday()))
+	if err != nil {
+		log.Printf("Error querying theatre sessions: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for theatreID, list := range sessions {
+		t := &theatres[index[theatreID]]
+		t.Sessions = list
+		for _, s := range list {
+			t.SessionMinutes += sessionMinutes(s)
+		}
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT `+surgicalCaseColumns+`
+		FROM SurgicalCases c
+		`+surgicalCaseJoins+`
+		WHERE t.HospitalID = ? AND c.Status <> 'cancelled'
+		AND c.ScheduledStart >= ? AND c.ScheduledStart < ? + INTERVAL 1 DAY
+		ORDER BY c.ScheduledStart, c.CaseID
+	`, hospitalID, day, day)
+	if err != nil {
+		log.Printf("Error querying theatre cases: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	byStatus := map[string]int{"scheduled": 0, "in_progress": 0, "in_recovery": 0, "done": 0}
+	for rows.Next() {
+		c, err := scanSurgicalCase(rows)
+		if err != nil {
+			log.Printf("Error scanning surgical case row: %v", err)
+			continue
+		}
+		i, ok := index[c.TheatreID]
+		if !ok {
+			continue
+		}
+		theatres[i].Cases = append(theatres[i].Cases, c)
+		if !c.OutsideSession {
+			theatres[i].BookedMinutes += c.EstimatedMinutes
+		}
+		byStatus[c.Status]++
+		total++
+	}
+
+	for i := range theatres {
+		t := &theatres[i]
+		if t.SessionMinutes > t.BookedMinutes {
+			t.FreeMinutes = t.SessionMinutes - t.BookedMinutes
+		}
+		t.Utilisation = round2(calculatePercentage(t.BookedMinutes, t.SessionMinutes))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hospitalId": hospitalID,
+		"date":       day,
+		"weekday":    date.Weekday().String(),
+		"totalCases": total,
+		"byStatus":   byStatus,
+		"theatres":   theatres,
+	})
+}
@@ -0,0 +1,770 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+)
+
+// Inter-hospital transfers. The sending hospital requests a transfer for one
+// of its admitted patients. The receiving hospital checks its availability and
+// accepts with a bed, which is held for the patient by a reservation, or
+// rejects. When the patient leaves, the sending hospital discharges them as
+// transfer-out and the transfer is in transit; on arrival the receiving
+// hospital admits them to the held bed. Each step is checked against the
+// acting employee's hospital and recorded in HospitalTransferEvents.
+
+// transferBedHold is how long an accepted transfer's bed is held for the
+// patient to arrive before the reservation expires
+const transferBedHold = 24 * time.Hour
+
+// transferStayDays is the default stay reserved at the receiving hospital
+const transferStayDays = 3
+
+var (
+	errTransferNotFound  = errors.New("transfer not found")
+	errTransferState     = errors.New("transfer is not in a state that allows this")
+	errTransferOpen      = errors.New("patient already has an open transfer")
+	errTransferHospital  = errors.New("employee does not work at the hospital responsible for this step")
+	errTransferWrongSide = errors.New("bed is not at the receiving hospital")
+	errTransferSameSite  = errors.New("patient is already at the receiving hospital; use a bed transfer instead")
+)
+
+// transferErrorStatus extends reservationErrorStatus with the transfer errors
+func transferErrorStatus(err error) (int, string) {
+	switch err {
+	case errTransferNotFound:
+		return http.StatusNotFound, err.Error()
+	case errTransferState, errTransferOpen, errTransferWrongSide, errNoBedSuggestion:
+		return http.StatusConflict, err.Error()
+	case errTransferHospital:
+		return http.StatusForbidden, err.Error()
+	case errTransferSameSite:
+		return http.StatusBadRequest, err.Error()
+	}
+	return reservationErrorStatus(err)
+}
+
+// transfer is the part of a HospitalTransfers row the workflow steps need
+type transfer struct {
+	TransferID      int
+	PatientID       int
+	FromHospitalID  int
+	ToHospitalID    int
+	BedType         string
+	Status          string
+	ReservationID   int
+	ToBedID         int
+	FromAdmissionID int
+}
+
+// lockTransfer reads and locks a transfer. It is locked before any patient or
+// bed so that steps on the same transfer are serialised.
+func lockTransfer(tx *sql.Tx, transferID int) (transfer, error) {
+	t := transfer{TransferID: transferID}
+	err := tx.QueryRow(`
+		SELECT PatientID, FromHospitalID, ToHospitalID, BedType, Status,
+		       COALESCE(ReservationID, 0), COALESCE(ToBedID, 0), COALESCE(FromAdmissionID, 0)
+		FROM HospitalTransfers WHERE TransferID = ?
+		FOR UPDATE
+	`, transferID).Scan(&t.PatientID, &t.FromHospitalID, &t.ToHospitalID, &t.BedType, &t.Status,
+		&t.ReservationID, &t.ToBedID, &t.FromAdmissionID)
+	if err == sql.ErrNoRows {
+		return t, errTransferNotFound
+	}
+	return t, err
+}
+
+// setTransferStatus moves a transfer to a new status and records the step in
+// its audit trail on behalf of hospitalID
+func setTransferStatus(tx *sql.Tx, t *transfer, status string, hospitalID, actorID int, notes string) error {
+	_, err := tx.Exec("UPDATE HospitalTransfers SET Status = ? WHERE TransferID = ?", status, t.TransferID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO HospitalTransferEvents (TransferID, HospitalID, FromStatus, ToStatus, ActorID, Notes)
+		VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, 0), NULLIF(?, ''))
+	`, t.TransferID, hospitalID, t.Status, status, actorID, notes)
+	t.Status = status
+	return err
+}
+
+// transferActor checks that an employee works at hospitalID and returns
+// their DoctorID, or 0 if they aren't a doctor
+func transferActor(employeeID, hospitalID int) (int, error) {
+	doctorID, employeeHospital, err := doctorForEmployee(employeeID)
+	if err == sql.ErrNoRows || (err == nil && employeeHospital != hospitalID) {
+		return 0, errTransferHospital
+	}
+	return doctorID, err
+}
+
+// transferAvailability reports how many beds of a type are free at a
+// hospital and which one the allocator would pick for the patient
+func transferAvailability(q queryer, hospitalID int, bedType string, patientID int) (map[string]interface{}, error) {
+	var free int
+	err := q.QueryRow(`
+		SELECT COUNT(*)
+		FROM BedInventory bi
+		WHERE bi.HospitalID = ? AND bi.BedType = ? AND bi.Status = 'available'
+		  AND `+bedNotReserved, hospitalID, bedType).Scan(&free)
+	if err != nil {
+		return nil, err
+	}
+	availability := map[string]interface{}{
+		"hospitalId":    hospitalID,
+		"bedType":       bedType,
+		"availableBeds": free,
+		"suggestion":    nil,
+	}
+
+	var gender sql.NullString
+	if err := q.QueryRow("SELECT Gender FROM Patients WHERE PatientID = ?", patientID).Scan(&gender); err != nil {
+		return nil, err
+	}
+	s, err := suggestBed(q, bedRequest{
+		PatientID:  patientID,
+		HospitalID: hospitalID,
+		BedType:    bedType,
+		Gender:     strings.ToLower(strings.TrimSpace(gender.String)),
+	})
+	if err == nil {
+		availability["suggestion"] = s
+	} else if err != errNoBedSuggestion {
+		return nil, err
+	}
+	return availability, nil
+}
+
+// loadTransfer returns a transfer with its audit trail
+func loadTransfer(transferID int) (models.HospitalTransfer, error) {
+	t, err := scanTransfer(database.DB.QueryRow(`
+		SELECT `+transferColumns+`
+		FROM HospitalTransfers ht
+		`+transferJoins+`
+		WHERE ht.TransferID = ?
+	`, transferID))
+	if err == sql.ErrNoRows {
+		return t, errTransferNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT ev.EventID, ev.HospitalID, COALESCE(ev.FromStatus, ''), ev.ToStatus,
+		       COALESCE(e.FullName, ''), COALESCE(ev.Notes, ''), DATE_FORMAT(ev.CreatedAt, '%Y-%m-%d %H:%i')
+		FROM HospitalTransferEvents ev
+		LEFT JOIN Employees e ON ev.ActorID = e.EmployeeID
+		WHERE ev.TransferID = ?
+		ORDER BY ev.CreatedAt, ev.EventID
+	`, transferID)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ev models.TransferEvent
+		if err := rows.Scan(&ev.EventID, &ev.HospitalID, &ev.FromStatus, &ev.ToStatus,
+			&ev.Actor, &ev.Notes, &ev.CreatedAt); err != nil {
+			return t, err
+		}
+		t.Events = append(t.Events, ev)
+	}
+	return t, rows.Err()
+}
+
+// transferColumns and transferJoins select a transfer for a response
+const (
+	transferColumns = `ht.TransferID, ht.PatientID, p.FullName,
+		ht.FromHospitalID, COALESCE(fh.Address, ''), ht.ToHospitalID, COALESCE(th.Address, ''),
+		COALESCE(ht.FromBedID, 0), COALESCE(ht.FromAdmissionID, 0), ht.BedType, ht.Urgency,
+		COALESCE(ht.Reason, ''), COALESCE(ht.ClinicalSummary, ''), ht.Status,
+		COALESCE(ht.ReservationID, 0), COALESCE(ht.ToBedID, 0), COALESCE(ht.ToAdmissionID, 0),
+		COALESCE(ht.ToAssignmentID, 0), COALESCE(ht.TransportMode, ''),
+		DATE_FORMAT(ht.RequestedAt, '%Y-%m-%d %H:%i'),
+		COALESCE(DATE_FORMAT(ht.DepartedAt, '%Y-%m-%d %H:%i'), ''),
+		COALESCE(DATE_FORMAT(ht.ArrivedAt, '%Y-%m-%d %H:%i'), '')`
+	transferJoins = `JOIN Patients p ON ht.PatientID = p.PatientID
+		LEFT JOIN Hospital fh ON ht.FromHospitalID = fh.HospitalID
+		LEFT JOIN Hospital th ON ht.ToHospitalID = th.HospitalID`
+)
+
+// scanTransfer reads a row selected with transferColumns
+func scanTransfer(row interface{ Scan(...interface{}) error }) (models.HospitalTransfer, error) {
+	var t models.HospitalTransfer
+	err := row.Scan(&t.TransferID, &t.PatientID, &t.PatientName,
+		&t.FromHospitalID, &t.FromHospital, &t.ToHospitalID, &t.ToHospital,
+		&t.FromBedID, &t.FromAdmissionID, &t.BedType, &t.Urgency,
+		&t.Reason, &t.ClinicalSummary, &t.Status,
+		&t.ReservationID, &t.ToBedID, &t.ToAdmissionID,
+		&t.ToAssignmentID, &t.TransportMode,
+		&t.RequestedAt, &t.DepartedAt, &t.ArrivedAt)
+	return t, err
+}
+
+// CreateHospitalTransfer requests the transfer of an admitted patient to
+// another hospital. The requesting employee must work at the patient's
+// current hospital. The response includes the receiving hospital's
+// availability for the requested bed type.
+func CreateHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req struct {
+		EmployeeID      int    `json:"employeeId"`
+		PatientID       int    `json:"patientId"`
+		ToHospitalID    int    `json:"toHospitalId"`
+		BedType         string `json:"bedType"` // defaults to the patient's current bed type
+		Urgency         string `json:"urgency"`
+		Reason          string `json:"reason"`
+		ClinicalSummary string `json:"clinicalSummary"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding transfer request: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EmployeeID == 0 || req.PatientID == 0 || req.ToHospitalID == 0 {
+		sendJSONError(w, "employeeId, patientId and toHospitalId are required", http.StatusBadRequest)
+		return
+	}
+	if req.Urgency == "" {
+		req.Urgency = "routine"
+	}
+	if !validBedUrgency(req.Urgency) {
+		sendJSONError(w, "urgency must be routine, urgent or emergency", http.StatusBadRequest)
+		return
+	}
+
+	var hospitalExists bool
+	err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Hospital WHERE HospitalID = ?)", req.ToHospitalID).Scan(&hospitalExists)
+	if err != nil {
+		log.Printf("Error checking receiving hospital: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !hospitalExists {
+		sendJSONError(w, "Receiving hospital not found", http.StatusNotFound)
+		return
+	}
+
+	var transferID int64
+	var a activeAssignment
+	err = withTx(func(tx *sql.Tx) error {
+		var err error
+		a, err = currentAssignment(tx, req.PatientID)
+		if err != nil {
+			return err
+		}
+		if _, err := transferActor(req.EmployeeID, a.Bed.HospitalID); err != nil {
+			return err
+		}
+		if a.Bed.HospitalID == req.ToHospitalID {
+			return errTransferSameSite
+		}
+		if req.BedType == "" {
+			req.BedType = a.Bed.BedType
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO HospitalTransfers (PatientID, FromHospitalID, ToHospitalID, FromBedID, FromAdmissionID,
+			                               BedType, Urgency, Reason, ClinicalSummary, RequestedBy)
+			VALUES (?, ?, ?, ?, NULLIF(?, 0), ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+		`, req.PatientID, a.Bed.HospitalID, req.ToHospitalID, a.Bed.BedID, a.AdmissionID,
+			req.BedType, req.Urgency, req.Reason, req.ClinicalSummary, req.EmployeeID)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return errTransferOpen
+		}
+		if err != nil {
+			return err
+		}
+		transferID, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		t := transfer{TransferID: int(transferID)}
+		return setTransferStatus(tx, &t, "requested", a.Bed.HospitalID, req.EmployeeID, req.Reason)
+	})
+	if err != nil {
+		status, message := transferErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error creating hospital transfer: %v", err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+
+	t, err := loadTransfer(int(transferID))
+	if err != nil {
+		log.Printf("Error fetching transfer %d: %v", transferID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	availability, err := transferAvailability(database.DB, t.ToHospitalID, t.BedType, t.PatientID)
+	if err != nil {
+		log.Printf("Error checking availability for transfer %d: %v", transferID, err)
+	}
+
+	log.Printf("Transfer %d requested: patient %d from hospital %d to %d", transferID, t.PatientID, t.FromHospitalID, t.ToHospitalID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"message":      "Transfer requested successfully",
+		"transfer":     t,
+		"availability": availability,
+	})
+}
+
+// GetHospitalTransfers lists transfers involving hospitalId, optionally only
+// those arriving (direction=incoming) or leaving (direction=outgoing), and
+// filtered by status or patientId
+func GetHospitalTransfers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	var conditions []string
+	var args []interface{}
+	if value := query.Get("hospitalId"); value != "" {
+		hospitalID, err := strconv.Atoi(value)
+		if err != nil {
+			sendJSONError(w, "Invalid hospitalId", http.StatusBadRequest)
+			return
+		}
+		switch query.Get("direction") {
+		case "incoming":
+			conditions = append(conditions, "ht.ToHospitalID = ?")
+			args = append(args, hospitalID)
+		case "outgoing":
+			conditions = append(conditions, "ht.FromHospitalID = ?")
+			args = append(args, hospitalID)
+		case "":
+			conditions = append(conditions, "? IN (ht.FromHospitalID, ht.ToHospitalID)")
+			args = append(args, hospitalID)
+		default:
+			sendJSONError(w, "direction must be incoming or outgoing", http.StatusBadRequest)
+			return
+		}
+	}
+	if status := query.Get("status"); status != "" {
+		conditions = append(conditions, "ht.Status = ?")
+		args = append(args, status)
+	}
+	if value := query.Get("patientId"); value != "" {
+		patientID, err := strconv.Atoi(value)
+		if err != nil {
+			sendJSONError(w, "Invalid patientId", http.StatusBadRequest)
+			return
+		}
+		conditions = append(conditions, "ht.PatientID = ?")
+		args = append(args, patientID)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+transferColumns+`
+		FROM HospitalTransfers ht
+		`+transferJoins+`
+		`+where+`
+		ORDER BY FIELD(ht.Status, 'in_transit', 'accepted', 'requested') DESC, ht.RequestedAt DESC
+	`, args...)
+	if err != nil {
+		log.Printf("Error querying hospital transfers: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	transfers := []models.HospitalTransfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			log.Printf("Error scanning hospital transfer row: %v", err)
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// GetHospitalTransfer returns a transfer with its audit trail and, while it
+// awaits a decision, the receiving hospital's availability
+func GetHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	transferID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	t, err := loadTransfer(transferID)
+	if err == errTransferNotFound {
+		sendJSONError(w, "Transfer not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching transfer %d: %v", transferID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{"transfer": t}
+	if t.Status == "requested" {
+		availability, err := transferAvailability(database.DB, t.ToHospitalID, t.BedType, t.PatientID)
+		if err != nil {
+			log.Printf("Error checking availability for transfer %d: %v", transferID, err)
+		}
+		response["availability"] = availability
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// transferStepRequest is the body accepted by every transfer step; each step
+// uses the fields that apply to it
+type transferStepRequest struct {
+	EmployeeID       int    `json:"employeeId"`
+	BedID            int    `json:"bedId"`
+	ExpectedStayDays int    `json:"expectedStayDays"`
+	TransportMode    string `json:"transportMode"`
+	Notes            string `json:"notes"`
+}
+
+// transferStep advances a locked transfer. It returns the ID of a bed it
+// released, if any, so the bed can be offered to the queue after commit.
+type transferStep func(tx *sql.Tx, t *transfer, req transferStepRequest) (int, error)
+
+// runTransferStep decodes a step request, runs the step in a transaction and
+// responds with the updated transfer
+func runTransferStep(w http.ResponseWriter, r *http.Request, message string, step transferStep) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	transferID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+	var req transferStepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding transfer step: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EmployeeID == 0 {
+		sendJSONError(w, "employeeId is required", http.StatusBadRequest)
+		return
+	}
+
+	var freedBedID int
+	err = withTx(func(tx *sql.Tx) error {
+		t, err := lockTransfer(tx, transferID)
+		if err != nil {
+			return err
+		}
+		freedBedID, err = step(tx, &t, req)
+		return err
+	})
+	if err != nil {
+		status, message := transferErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error updating transfer %d: %v", transferID, err)
+		}
+		sendJSONError(w, message, status)
+		return
+	}
+	if freedBedID != 0 {
+		go fillBedFromQueue(freedBedID)
+	}
+
+	t, err := loadTransfer(transferID)
+	if err != nil {
+		log.Printf("Error fetching transfer %d: %v", transferID, err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Transfer %d is now %s", transferID, t.Status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"transfer": t,
+	})
+}
+
+// releaseTransferBed cancels the reservation holding a transfer's bed and
+// returns the bed's ID. A reservation that already expired is not an error.
+func releaseTransferBed(tx *sql.Tx, t *transfer, reason string) (int, error) {
+	if t.ReservationID == 0 {
+		return 0, nil
+	}
+	bedID, err := closeReservation(tx, t.ReservationID, "cancelled", reason)
+	if err == errReservationClosed {
+		return 0, nil
+	}
+	return bedID, err
+}
+
+// AcceptHospitalTransfer accepts a requested transfer at the receiving
+// hospital and holds a bed for the patient. Without a bedId the allocator's
+// suggestion for the requested bed type is used.
+func AcceptHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	runTransferStep(w, r, "Transfer accepted", func(tx *sql.Tx, t *transfer, req transferStepRequest) (int, error) {
+		if t.Status != "requested" {
+			return 0, errTransferState
+		}
+		if _, err := transferActor(req.EmployeeID, t.ToHospitalID); err != nil {
+			return 0, err
+		}
+		if req.ExpectedStayDays < 1 {
+			req.ExpectedStayDays = transferStayDays
+		}
+
+		bedID := req.BedID
+		if bedID == 0 {
+			var gender sql.NullString
+			if err := tx.QueryRow("SELECT Gender FROM Patients WHERE PatientID = ?", t.PatientID).Scan(&gender); err != nil {
+				return 0, err
+			}
+			s, err := suggestBed(tx, bedRequest{
+				PatientID:  t.PatientID,
+				HospitalID: t.ToHospitalID,
+				BedType:    t.BedType,
+				Gender:     strings.ToLower(strings.TrimSpace(gender.String)),
+			})
+			if err != nil {
+				return 0, err
+			}
+			bedID = s.BedID
+		}
+
+		var hospitalID int
+		var bedStatus string
+		err := tx.QueryRow("SELECT HospitalID, Status FROM BedInventory WHERE BedID = ? FOR UPDATE", bedID).Scan(&hospitalID, &bedStatus)
+		if err == sql.ErrNoRows {
+			return 0, errBedNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		if hospitalID != t.ToHospitalID {
+			return 0, errTransferWrongSide
+		}
+		if bedStatus == "maintenance" || bedStatus == "blocked" {
+			return 0, errBedUnavailable
+		}
+		if err := checkWardGender(tx, bedID, t.PatientID); err != nil {
+			return 0, err
+		}
+
+		today := time.Now()
+		reservationID, err := insertReservation(tx, bedID, bedStatus, t.PatientID,
+			today.Format("2006-01-02"), today.AddDate(0, 0, req.ExpectedStayDays).Format("2006-01-02"),
+			req.ExpectedStayDays, today.Add(transferBedHold),
+			fmt.Sprintf("Held for transfer #%d", t.TransferID), req.EmployeeID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`
+			UPDATE HospitalTransfers
+			SET ReservationID = ?, ToBedID = ?, RespondedBy = ?, RespondedAt = NOW()
+			WHERE TransferID = ?
+		`, reservationID, bedID, req.EmployeeID, t.TransferID)
+		if err != nil {
+			return 0, err
+		}
+		return 0, setTransferStatus(tx, t, "accepted", t.ToHospitalID, req.EmployeeID, req.Notes)
+	})
+}
+
+// RejectHospitalTransfer declines a requested transfer at the receiving hospital
+func RejectHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	runTransferStep(w, r, "Transfer rejected", func(tx *sql.Tx, t *transfer, req transferStepRequest) (int, error) {
+		if t.Status != "requested" {
+			return 0, errTransferState
+		}
+		if _, err := transferActor(req.EmployeeID, t.ToHospitalID); err != nil {
+			return 0, err
+		}
+		_, err := tx.Exec(
+			"UPDATE HospitalTransfers SET RespondedBy = ?, RespondedAt = NOW() WHERE TransferID = ?",
+			req.EmployeeID, t.TransferID)
+		if err != nil {
+			return 0, err
+		}
+		return 0, setTransferStatus(tx, t, "rejected", t.ToHospitalID, req.EmployeeID, req.Notes)
+	})
+}
+
+// CancelHospitalTransfer withdraws a transfer at the sending hospital before
+// the patient leaves, releasing any bed held at the receiving hospital
+func CancelHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	runTransferStep(w, r, "Transfer cancelled", func(tx *sql.Tx, t *transfer, req transferStepRequest) (int, error) {
+		if t.Status != "requested" && t.Status != "accepted" {
+			return 0, errTransferState
+		}
+		if _, err := transferActor(req.EmployeeID, t.FromHospitalID); err != nil {
+			return 0, err
+		}
+		bedID, err := releaseTransferBed(tx, t, fmt.Sprintf("Transfer #%d cancelled", t.TransferID))
+		if err != nil {
+			return 0, err
+		}
+		return bedID, setTransferStatus(tx, t, "cancelled", t.FromHospitalID, req.EmployeeID, req.Notes)
+	})
+}
+
+// DepartHospitalTransfer records the patient leaving the sending hospital.
+// They are discharged there as transfer-out and the transfer is in transit.
+func DepartHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	runTransferStep(w, r, "Patient is in transit", func(tx *sql.Tx, t *transfer, req transferStepRequest) (int, error) {
+		if t.Status != "accepted" {
+			return 0, errTransferState
+		}
+		if _, err := transferActor(req.EmployeeID, t.FromHospitalID); err != nil {
+			return 0, err
+		}
+
+		// The patient must not leave if the bed at the other end has been released
+		var reservationStatus string
+		err := tx.QueryRow("SELECT Status FROM BedReservations WHERE ReservationID = ?", t.ReservationID).Scan(&reservationStatus)
+		if err != nil {
+			return 0, err
+		}
+		if reservationStatus != "active" {
+			return 0, errReservationClosed
+		}
+
+		a, err := currentAssignment(tx, t.PatientID)
+		if err != nil {
+			return 0, err
+		}
+		if a.Bed.HospitalID != t.FromHospitalID {
+			return 0, errTransferState
+		}
+		today := time.Now().Format("2006-01-02")
+		err = vacateBed(tx, a, today, bedMove{
+			Reason:  fmt.Sprintf("Transferred out to hospital #%d (transfer #%d)", t.ToHospitalID, t.TransferID),
+			Notes:   req.Notes,
+			ActorID: req.EmployeeID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("UPDATE BedAssignments SET DischargeType = 'transfer-out' WHERE AssignmentID = ?", a.AssignmentID)
+		if err == nil && a.AdmissionID != 0 {
+			_, err = tx.Exec("UPDATE Admissions SET DischargeType = 'transfer-out' WHERE AdmissionID = ?", a.AdmissionID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`
+				UPDATE HospitalTransfers
+				SET DepartedAt = NOW(), TransportMode = NULLIF(?, ''), FromAdmissionID = NULLIF(?, 0)
+				WHERE TransferID = ?
+			`, req.TransportMode, a.AdmissionID, t.TransferID)
+		}
+		if err != nil {
+			return 0, err
+		}
+		notes := req.Notes
+		if req.TransportMode != "" {
+			notes = strings.TrimSpace("Transport: " + req.TransportMode + ". " + notes)
+		}
+		return 0, setTransferStatus(tx, t, "in_transit", t.FromHospitalID, req.EmployeeID, notes)
+	})
+}
+
+// ArriveHospitalTransfer admits a transferred patient at the receiving
+// hospital, to the held bed unless another bedId is given. The new admission
+// carries over the diagnosis from the sending hospital.
+func ArriveHospitalTransfer(w http.ResponseWriter, r *http.Request) {
+	runTransferStep(w, r, "Patient admitted at the receiving hospital", func(tx *sql.Tx, t *transfer, req transferStepRequest) (int, error) {
+		if t.Status != "in_transit" {
+			return 0, errTransferState
+		}
+		doctorID, err := transferActor(req.EmployeeID, t.ToHospitalID)
+		if err != nil {
+			return 0, err
+		}
+
+		var diagnosis string
+		if t.FromAdmissionID != 0 {
+			err := tx.QueryRow("SELECT COALESCE(Diagnosis, '') FROM Admissions WHERE AdmissionID = ?", t.FromAdmissionID).Scan(&diagnosis)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		bedID := req.BedID
+		if bedID == 0 {
+			bedID = t.ToBedID
+		}
+		today := time.Now().Format("2006-01-02")
+		assignmentID, bed, err := occupyBed(tx, bedID, t.PatientID, today, bedMove{
+			Reason:  fmt.Sprintf("Transferred in from hospital #%d (transfer #%d)", t.FromHospitalID, t.TransferID),
+			Notes:   req.Notes,
+			ActorID: req.EmployeeID,
+		}, admissionDetails{AdmittingDoctorID: doctorID, Diagnosis: diagnosis})
+		if err != nil {
+			return 0, err
+		}
+		if bed.HospitalID != t.ToHospitalID {
+			return 0, errTransferWrongSide
+		}
+
+		// A different bed was used; the held one goes back into service
+		var freedBedID int
+		if bedID != t.ToBedID {
+			freedBedID, err = releaseTransferBed(tx, t, fmt.Sprintf("Transfer #%d admitted to bed %d instead", t.TransferID, bedID))
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		var admissionID int64
+		err = tx.QueryRow("SELECT COALESCE(AdmissionID, 0) FROM BedAssignments WHERE AssignmentID = ?", assignmentID).Scan(&admissionID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`
+			UPDATE HospitalTransfers
+			SET ToBedID = ?, ToAssignmentID = ?, ToAdmissionID = NULLIF(?, 0), ArrivedAt = NOW()
+			WHERE TransferID = ?
+		`, bedID, assignmentID, admissionID, t.TransferID)
+		if err != nil {
+			return 0, err
+		}
+		return freedBedID, setTransferStatus(tx, t, "completed", t.ToHospitalID, req.EmployeeID, req.Notes)
+	})
+}
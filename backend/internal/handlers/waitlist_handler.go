@@ -0,0 +1,524 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"hospital-management/backend/internal/notify"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// waitlistHoldDuration is how long a waitlisted patient has to confirm an offered slot
+const waitlistHoldDuration = 2 * time.Hour
+
+// waitlistExpiryInterval is how often expired holds are passed down the list
+const waitlistExpiryInterval = time.Minute
+
+// offerFreedSlot offers a slot that has just been freed by a cancellation or
+// reschedule to the next eligible waitlisted patient. Patients who were already
+// offered this exact slot are skipped. It returns the offer ID, or 0 if nobody
+// on the waitlist wants the slot.
+func offerFreedSlot(tx *sql.Tx, doctorID int, date, slot string) (int64, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return 0, err
+	}
+	start, err := slotStart(day, slot)
+	if err != nil || !start.After(time.Now()) {
+		// Unknown or already started slots can't be offered
+		return 0, nil
+	}
+
+	taken, err := bookedSlots(tx, doctorID, date)
+	if err != nil {
+		return 0, err
+	}
+	if taken[slot] {
+		return 0, nil
+	}
+
+	var waitlistID, patientID int
+	err = tx.QueryRow(`
+		SELECT wl.WaitlistID, wl.PatientID
+		FROM AppointmentWaitlist wl
+		JOIN Doctors d ON d.DoctorID = ?
+		WHERE wl.Status = 'waiting'
+		AND (wl.DoctorID = d.DoctorID OR (wl.DoctorID IS NULL AND wl.Department = d.Department))
+		AND ? BETWEEN wl.PreferredFrom AND wl.PreferredTo
+		AND NOT EXISTS (
+			SELECT 1 FROM SlotOffers so
+			WHERE so.WaitlistID = wl.WaitlistID
+			AND so.DoctorID = ? AND so.OfferDate = ? AND so.OfferTime = ?
+		)
+		ORDER BY wl.CreatedAt, wl.WaitlistID
+		LIMIT 1
+		FOR UPDATE
+	`, doctorID, date, doctorID, date, slot).Scan(&waitlistID, &patientID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// The hold never outlasts the slot itself
+	expiresAt := time.Now().Add(waitlistHoldDuration)
+	if expiresAt.After(start) {
+		expiresAt = start
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO SlotOffers (WaitlistID, DoctorID, OfferDate, OfferTime, ExpiresAt, Status)
+		VALUES (?, ?, ?, ?, ?, 'held')
+	`, waitlistID, doctorID, date, slot, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE AppointmentWaitlist SET Status = 'offered' WHERE WaitlistID = ?", waitlistID)
+	if err != nil {
+		return 0, err
+	}
+
+	offerID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Offered %s %s with doctor %d to waitlist entry %d (patient %d) until %s",
+		date, slot, doctorID, waitlistID, patientID, expiresAt.Format(time.RFC3339))
+	return offerID, nil
+}
+
+// JoinWaitlist adds a patient to the waitlist for a doctor or a whole department
+func JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req struct {
+		PatientID     int    `json:"patient_id"`
+		DoctorID      int    `json:"doctor_id"`
+		Department    string `json:"department"`
+		PreferredFrom string `json:"preferred_from"`
+		PreferredTo   string `json:"preferred_to"`
+		Notes         string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.PatientID == 0 || (req.DoctorID == 0 && req.Department == "") {
+		sendJSONError(w, "patient_id and either doctor_id or department are required", http.StatusBadRequest)
+		return
+	}
+	if req.PreferredFrom == "" {
+		req.PreferredFrom = time.Now().Format("2006-01-02")
+	}
+	if req.PreferredTo == "" {
+		req.PreferredTo = req.PreferredFrom
+	}
+	from, err1 := time.Parse("2006-01-02", req.PreferredFrom)
+	to, err2 := time.Parse("2006-01-02", req.PreferredTo)
+	if err1 != nil || err2 != nil || to.Before(from) {
+		sendJSONError(w, "preferred_from and preferred_to must be dates with from <= to", http.StatusBadRequest)
+		return
+	}
+
+	var doctorID, department interface{}
+	if req.DoctorID != 0 {
+		doctorID = req.DoctorID
+	} else {
+		department = req.Department
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO AppointmentWaitlist (PatientID, DoctorID, Department, PreferredFrom, PreferredTo, Notes, Status)
+		VALUES (?, ?, ?, ?, ?, ?, 'waiting')
+	`, req.PatientID, doctorID, department, req.PreferredFrom, req.PreferredTo, req.Notes)
+	if err != nil {
+		log.Printf("Error adding waitlist entry: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	waitlistID, _ := result.LastInsertId()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "success",
+		"message":     "Patient added to waitlist",
+		"waitlist_id": waitlistID,
+	})
+}
+
+// GetWaitlist returns open waitlist entries with each patient's position in
+// the list for their doctor or department, and any slot currently held for them
+func GetWaitlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := `
+		SELECT
+			wl.WaitlistID, wl.PatientID, p.FullName,
+			COALESCE(wl.DoctorID, 0), COALESCE(d.FullName, ''), COALESCE(wl.Department, d.Department),
+			DATE_FORMAT(wl.PreferredFrom, '%Y-%m-%d'), DATE_FORMAT(wl.PreferredTo, '%Y-%m-%d'),
+			COALESCE(wl.Notes, ''), wl.Status, DATE_FORMAT(wl.CreatedAt, '%Y-%m-%d %H:%i:%s'),
+			COALESCE(so.OfferID, 0), COALESCE(DATE_FORMAT(so.OfferDate, '%Y-%m-%d'), ''),
+			COALESCE(so.OfferTime, ''), COALESCE(DATE_FORMAT(so.ExpiresAt, '%Y-%m-%d %H:%i:%s'), '')
+		FROM AppointmentWaitlist wl
+		JOIN Patients p ON wl.PatientID = p.PatientID
+		LEFT JOIN Doctors d ON wl.DoctorID = d.DoctorID
+		LEFT JOIN SlotOffers so ON so.WaitlistID = wl.WaitlistID AND so.Status = 'held'
+		WHERE wl.Status IN ('waiting', 'offered')
+	`
+	var args []interface{}
+	if doctorID := r.URL.Query().Get("doctorId"); doctorID != "" {
+		query += " AND wl.DoctorID = ?"
+		args = append(args, doctorID)
+	}
+	if department := r.URL.Query().Get("department"); department != "" && department != "all" {
+		query += " AND COALESCE(wl.Department, d.Department) = ?"
+		args = append(args, department)
+	}
+	query += " ORDER BY wl.CreatedAt, wl.WaitlistID"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying waitlist: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	positions := map[string]int{}
+	for rows.Next() {
+		var e models.WaitlistEntry
+		err := rows.Scan(&e.WaitlistID, &e.PatientID, &e.PatientName, &e.DoctorID, &e.DoctorName, &e.Department,
+			&e.PreferredFrom, &e.PreferredTo, &e.Notes, &e.Status, &e.CreatedAt,
+			&e.OfferID, &e.OfferDate, &e.OfferTime, &e.OfferExpiresAt)
+		if err != nil {
+			log.Printf("Error scanning waitlist row: %v", err)
+			continue
+		}
+
+		// Doctor-specific and department-wide lists are separate queues
+		key := "department:" + e.Department
+		if e.DoctorID != 0 {
+			key = fmt.Sprintf("doctor:%d", e.DoctorID)
+		}
+		positions[key]++
+		e.Position = positions[key]
+
+		entries = append(entries, e)
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
+
+// CancelWaitlistEntry removes a patient from the waitlist and passes on any slot held for them
+func CancelWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	waitlistID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid waitlist ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE AppointmentWaitlist SET Status = 'cancelled'
+		WHERE WaitlistID = ? AND Status IN ('waiting', 'offered')
+	`, waitlistID)
+	if err != nil {
+		log.Printf("Error cancelling waitlist entry: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		sendJSONError(w, "Open waitlist entry not found", http.StatusNotFound)
+		return
+	}
+
+	if err := releaseHeldOffers(tx, "declined", "WaitlistID = ?", waitlistID); err != nil {
+		log.Printf("Error releasing held offers: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Waitlist entry cancelled",
+	})
+}
+
+// ConfirmSlotOffer books the held slot for the waitlisted patient
+func ConfirmSlotOffer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	offerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid offer ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var offer struct {
+		WaitlistID  int
+		PatientID   int
+		DoctorID    int
+		Date        string
+		Time        string
+		Notes       string
+		Status      string
+		HoldExpired bool
+	}
+	err = tx.QueryRow(`
+		SELECT so.WaitlistID, wl.PatientID, so.DoctorID, DATE_FORMAT(so.OfferDate, '%Y-%m-%d'), so.OfferTime,
+			COALESCE(wl.Notes, ''), so.Status, so.ExpiresAt <= NOW()
+		FROM SlotOffers so
+		JOIN AppointmentWaitlist wl ON so.WaitlistID = wl.WaitlistID
+		WHERE so.OfferID = ?
+		FOR UPDATE
+	`, offerID).Scan(&offer.WaitlistID, &offer.PatientID, &offer.DoctorID, &offer.Date, &offer.Time,
+		&offer.Notes, &offer.Status, &offer.HoldExpired)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Offer not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error loading offer: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if offer.Status != "held" || offer.HoldExpired {
+		sendJSONError(w, "This offer is no longer available", http.StatusGone)
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO Appointment (PatientID, DoctorID, AppointmentDate, AppointmentTime, Description, Status)
+		VALUES (?, ?, ?, ?, ?, 'scheduled')
+	`, offer.PatientID, offer.DoctorID, offer.Date, offer.Time, offer.Notes)
+	if err != nil {
+		log.Printf("Error booking offered slot: %v", err)
+		sendJSONError(w, "Error creating appointment record", http.StatusInternalServerError)
+		return
+	}
+	appointmentID, _ := result.LastInsertId()
+
+	_, err = tx.Exec("UPDATE SlotOffers SET Status = 'confirmed', AppointmentID = ? WHERE OfferID = ?", appointmentID, offerID)
+	if err == nil {
+		_, err = tx.Exec("UPDATE AppointmentWaitlist SET Status = 'booked' WHERE WaitlistID = ?", offer.WaitlistID)
+	}
+	if err != nil {
+		log.Printf("Error confirming offer: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	go notifyAppointment(appointmentID, notify.EventConfirmation, notify.TemplateData{})
+
+	log.Printf("Waitlist entry %d confirmed offer %d as appointment %d", offer.WaitlistID, offerID, appointmentID)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "success",
+		"message":        "Appointment booked from waitlist",
+		"appointment_id": appointmentID,
+	})
+}
+
+// DeclineSlotOffer releases a held slot and offers it to the next patient on the list
+func DeclineSlotOffer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	offerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid offer ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT Status FROM SlotOffers WHERE OfferID = ? FOR UPDATE", offerID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Offer not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error loading offer: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if status != "held" {
+		sendJSONError(w, "This offer is no longer available", http.StatusGone)
+		return
+	}
+
+	if err := releaseHeldOffers(tx, "declined", "OfferID = ?", offerID); err != nil {
+		log.Printf("Error declining offer: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Offer declined",
+	})
+}
+
+// releaseHeldOffers ends the held offers matching the condition with the given
+// status, puts the patients back on the waitlist and passes each slot on to
+// the next eligible patient
+func releaseHeldOffers(tx *sql.Tx, status, condition string, args ...interface{}) error {
+	rows, err := tx.Query(`
+		SELECT OfferID, WaitlistID, DoctorID, DATE_FORMAT(OfferDate, '%Y-%m-%d'), OfferTime
+		FROM SlotOffers
+		WHERE Status = 'held' AND `+condition+`
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	type heldOffer struct {
+		OfferID, WaitlistID, DoctorID int
+		Date, Time                    string
+	}
+	var offers []heldOffer
+	for rows.Next() {
+		var o heldOffer
+		if err := rows.Scan(&o.OfferID, &o.WaitlistID, &o.DoctorID, &o.Date, &o.Time); err != nil {
+			rows.Close()
+			return err
+		}
+		offers = append(offers, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range offers {
+		if _, err := tx.Exec("UPDATE SlotOffers SET Status = ? WHERE OfferID = ?", status, o.OfferID); err != nil {
+			return err
+		}
+		// The patient keeps their place for other slots
+		_, err := tx.Exec(`
+			UPDATE AppointmentWaitlist SET Status = 'waiting'
+			WHERE WaitlistID = ? AND Status = 'offered'
+		`, o.WaitlistID)
+		if err != nil {
+			return err
+		}
+		if _, err := offerFreedSlot(tx, o.DoctorID, o.Date, o.Time); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireSlotOffers passes every hold that ran out on to the next patient
+func expireSlotOffers() error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := releaseHeldOffers(tx, "expired", "ExpiresAt <= NOW()"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// StartWaitlistExpiry runs a background loop that expires unconfirmed holds
+func StartWaitlistExpiry() {
+	go func() {
+		ticker := time.NewTicker(waitlistExpiryInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := expireSlotOffers(); err != nil {
+				log.Printf("Error expiring waitlist offers: %v", err)
+			}
+		}
+	}()
+	log.Println("Waitlist offer expiry started")
+}
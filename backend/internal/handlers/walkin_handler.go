@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultOverbookQuota is the number of walk-ins a doctor session accepts
+// beyond its regular slots when no quota has been configured
+const defaultOverbookQuota = 2
+
+// WalkInRequest is the body accepted by CreateWalkInAppointment. Either
+// PatientID or Patient must be provided.
+type WalkInRequest struct {
+	DoctorID    int            `json:"doctor_id"`
+	PatientID   int            `json:"patient_id"`
+	Patient     models.Patient `json:"patient"`
+	Description string         `json:"description"`
+	Priority    string         `json:"priority"`
+}
+
+// overbookQuota returns the configured overbook quota for a doctor's session
+func overbookQuota(q queryer, doctorID int, session string) (int, error) {
+	var quota int
+	err := q.QueryRow(
+		"SELECT OverbookQuota FROM DoctorSessionQuota WHERE DoctorID = ? AND Session = ?",
+		doctorID, session).Scan(&quota)
+	if err == sql.ErrNoRows {
+		return defaultOverbookQuota, nil
+	}
+	return quota, err
+}
+
+// CreateWalkInAppointment registers a same-day walk-in. The walk-in is placed in
+// the first free slot left in the running session, or overbooked into the
+// session if the doctor's overbook quota allows it. The patient is checked in
+// and given a queue token behind everyone already waiting.
+func CreateWalkInAppointment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var req WalkInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding walk-in request: %v", err)
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.DoctorID == 0 {
+		sendJSONError(w, "doctor_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.PatientID == 0 && (req.Patient.FullName == "" || req.Patient.ContactNumber == "" || req.Patient.Email == "") {
+		sendJSONError(w, "Either patient_id or patient name, contact number and email are required", http.StatusBadRequest)
+		return
+	}
+	if req.Priority == "" {
+		req.Priority = "normal"
+	}
+	if !validQueuePriority(req.Priority) {
+		sendJSONError(w, "Priority must be normal, elderly or emergency", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	session := currentSession(now)
+	if session == "" {
+		sendJSONError(w, "Walk-ins can only be registered during a consultation session", http.StatusConflict)
+		return
+	}
+	today := now.Format("2006-01-02")
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the doctor row so concurrent walk-ins see each other's slots and quota use
+	var department string
+	err = tx.QueryRow("SELECT Department FROM Doctors WHERE DoctorID = ? FOR UPDATE", req.DoctorID).Scan(&department)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Doctor not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching doctor: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	patientID := int64(req.PatientID)
+	if patientID == 0 {
+		patientID, err = findOrCreatePatient(tx, req.Patient)
+		if err != nil {
+			log.Printf("Error creating walk-in patient: %v", err)
+			sendJSONError(w, "Error creating patient record", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		var exists bool
+		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM Patients WHERE PatientID = ?)", patientID).Scan(&exists)
+		if err != nil {
+			log.Printf("Error fetching walk-in patient: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			sendJSONError(w, "Patient not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	taken, err := bookedSlots(tx, req.DoctorID, today)
+	if err != nil {
+		log.Printf("Error fetching booked slots: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Fit the walk-in into the first gap that hasn't already ended
+	var slotTime, lastSlot string
+	for _, s := range appointmentSlots {
+		if s.Session != session {
+			continue
+		}
+		lastSlot = s.Time
+		start, _ := slotStart(now, s.Time)
+		if slotTime == "" && !taken[s.Time] && now.Before(start.Add(slotDuration)) {
+			slotTime = s.Time
+		}
+	}
+
+	// Overbooked walk-ins are seen at the end of the session, so they are
+	// counted against the quota by the session's last slot
+	overbooked := false
+	if slotTime == "" {
+		quota, err := overbookQuota(tx, req.DoctorID, session)
+		if err != nil {
+			log.Printf("Error fetching overbook quota: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		var used int
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM Appointment
+			WHERE DoctorID = ? AND AppointmentDate = ? AND AppointmentTime = ?
+			AND Overbooked = TRUE AND Status <> 'cancelled'
+		`, req.DoctorID, today, lastSlot).Scan(&used)
+		if err != nil {
+			log.Printf("Error counting overbooked walk-ins: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if used >= quota {
+			sendJSONError(w, "No free slots and the overbook quota for this session is used up", http.StatusConflict)
+			return
+		}
+
+		slotTime = lastSlot
+		overbooked = true
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO Appointment (PatientID, DoctorID, AppointmentDate, AppointmentTime, Description, Status, AppointmentType, Overbooked)
+		VALUES (?, ?, ?, ?, ?, 'checked-in', 'walk-in', ?)
+	`, patientID, req.DoctorID, today, slotTime, req.Description, overbooked)
+	if err != nil {
+		log.Printf("Error inserting walk-in appointment: %v", err)
+		sendJSONError(w, "Error creating appointment record", http.StatusInternalServerError)
+		return
+	}
+
+	appointmentID, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Error getting appointment ID: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Walk-ins join the back of the queue for their priority
+	tokenID, tokenNumber, err := issueQueueToken(tx, req.DoctorID, int(patientID), int(appointmentID), req.Priority)
+	if err != nil {
+		log.Printf("Error issuing queue token: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Walk-in appointment %d created for doctor %d at %s (overbooked=%t)",
+		appointmentID, req.DoctorID, slotTime, overbooked)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "success",
+		"message":          "Walk-in registered successfully",
+		"appointment_id":   appointmentID,
+		"patient_id":       patientID,
+		"appointment_time": slotTime,
+		"session":          session,
+		"overbooked":       overbooked,
+		"department":       department,
+		"token_id":         tokenID,
+		"token_number":     tokenNumber,
+	})
+}
+
+// GetOverbookQuota returns the overbook quota for each session of a doctor
+func GetOverbookQuota(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	doctorID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+
+	quotas := map[string]int{}
+	for _, session := range []string{"morning", "afternoon"} {
+		quota, err := overbookQuota(database.DB, doctorID, session)
+		if err != nil {
+			log.Printf("Error fetching overbook quota: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		quotas[session] = quota
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"doctor_id": doctorID,
+		"quotas":    quotas,
+	})
+}
+
+// UpdateOverbookQuota sets how many walk-ins a doctor session may take beyond its slots
+func UpdateOverbookQuota(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	doctorID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Session string `json:"session"`
+		Quota   int    `json:"quota"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Session != "morning" && req.Session != "afternoon" {
+		sendJSONError(w, "Session must be morning or afternoon", http.StatusBadRequest)
+		return
+	}
+	if req.Quota < 0 {
+		sendJSONError(w, "Quota cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO DoctorSessionQuota (DoctorID, Session, OverbookQuota)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE OverbookQuota = VALUES(OverbookQuota)
+	`, doctorID, req.Session, req.Quota)
+	if err != nil {
+		log.Printf("Error updating overbook quota: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"doctor_id": doctorID,
+		"session":   req.Session,
+		"quota":     req.Quota,
+	})
+}
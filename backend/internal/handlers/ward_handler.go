@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hospital-management/backend/internal/database"
+	"hospital-management/backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+)
+
+// mysqlNoReferencedRow is MySQL's ER_NO_REFERENCED_ROW_2 error number, a
+// foreign key pointing at a row that doesn't exist
+const mysqlNoReferencedRow = 1452
+
+// sendWardWriteError reports a failed write to the ward hierarchy: a unique
+// key violation as a conflict, a missing parent row as not found, and
+// anything else as a database error
+func sendWardWriteError(w http.ResponseWriter, err error, action, duplicate, missingParent string) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			sendJSONError(w, duplicate, http.StatusConflict)
+			return
+		case mysqlNoReferencedRow:
+			sendJSONError(w, missingParent, http.StatusNotFound)
+			return
+		}
+	}
+	log.Printf("Error %s: %v", action, err)
+	sendJSONError(w, "Database error", http.StatusInternalServerError)
+}
+
+// bedLocation formats a bed's place in the ward hierarchy, e.g. "Ward 3B, Room 12, Bed 2".
+// Beds that haven't been placed in a room fall back to their ID.
+func bedLocation(bedID int, wardName, roomNumber, label string) string {
+	var parts []string
+	if wardName != "" {
+		parts = append(parts, "Ward "+wardName)
+	}
+	if roomNumber != "" {
+		parts = append(parts, "Room "+roomNumber)
+	}
+	if label != "" {
+		parts = append(parts, "Bed "+label)
+	} else {
+		parts = append(parts, fmt.Sprintf("Bed #%d", bedID))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bedWardColumns and bedWardJoins add a bed's ward and room to a query on
+// BedInventory bi; scan the columns into a bedPlacement
+const (
+	bedWardColumns = `COALESCE(wd.WardID, 0), COALESCE(wd.Name, ''), COALESCE(wd.Floor, 0),
+		COALESCE(rm.RoomNumber, ''), COALESCE(bi.Label, '')`
+	bedWardJoins = `LEFT JOIN Rooms rm ON bi.RoomID = rm.RoomID
+		LEFT JOIN Wards wd ON rm.WardID = wd.WardID`
+	bedWardOrder = `wd.WardID IS NULL, wd.Floor, wd.Name, rm.RoomNumber, bi.Label`
+)
+
+// bedPlacement is where a bed sits in the ward hierarchy
+type bedPlacement struct {
+	WardID     int
+	WardName   string
+	Floor      int
+	RoomNumber string
+	Label      string
+}
+
+// dest returns scan destinations matching bedWardColumns
+func (p *bedPlacement) dest() []interface{} {
+	return []interface{}{&p.WardID, &p.WardName, &p.Floor, &p.RoomNumber, &p.Label}
+}
+
+// addTo adds the placement fields to a bed in a JSON response
+func (p bedPlacement) addTo(bed map[string]interface{}, bedID int) {
+	bed["wardId"] = p.WardID
+	bed["wardName"] = p.WardName
+	bed["floor"] = p.Floor
+	bed["roomNumber"] = p.RoomNumber
+	bed["label"] = p.Label
+	bed["location"] = bedLocation(bedID, p.WardName, p.RoomNumber, p.Label)
+}
+
+// wardGroup is a ward and its beds in a grouped bed listing
+type wardGroup struct {
+	WardID   int           `json:"wardId"`
+	WardName string        `json:"wardName"`
+	Floor    int           `json:"floor"`
+	Beds     []interface{} `json:"beds"`
+}
+
+// addToWardGroup appends a bed to the last group if it is in the same ward,
+// otherwise starts a new group. Beds must be ordered by ward.
+func addToWardGroup(groups []wardGroup, wardID int, wardName string, floor int, bed interface{}) []wardGroup {
+	if n := len(groups); n > 0 && groups[n-1].WardID == wardID {
+		groups[n-1].Beds = append(groups[n-1].Beds, bed)
+		return groups
+	}
+	if wardID == 0 {
+		wardName = "Unassigned"
+	}
+	return append(groups, wardGroup{WardID: wardID, WardName: wardName, Floor: floor, Beds: []interface{}{bed}})
+}
+
+// groupBedsByWard groups an inventory listing that is ordered by ward
+func groupBedsByWard(beds []models.Bed) []wardGroup {
+	groups := []wardGroup{}
+	for _, bed := range beds {
+		groups = addToWardGroup(groups, bed.WardID, bed.WardName, bed.Floor, bed)
+	}
+	return groups
+}
+
+func validGenderRestriction(g string) bool {
+	return g == "any" || g == "male" || g == "female"
+}
+
+// GetWards lists wards with their room and bed counts, optionally for one hospital
+func GetWards(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := `
+		SELECT w.WardID, w.HospitalID, w.Name, COALESCE(w.Department, ''), w.Floor,
+		       w.GenderRestriction, COALESCE(w.NurseStation, ''),
+		       COUNT(DISTINCT r.RoomID), COUNT(bi.BedID)
+		FROM Wards w
+		LEFT JOIN Rooms r ON r.WardID = w.WardID
+		LEFT JOIN BedInventory bi ON bi.RoomID = r.RoomID
+	`
+	var args []interface{}
+	if hospitalID := r.URL.Query().Get("hospitalId"); hospitalID != "" {
+		query += " WHERE w.HospitalID = ?"
+		args = append(args, hospitalID)
+	}
+	query += " GROUP BY w.WardID ORDER BY w.HospitalID, w.Floor, w.Name"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error querying wards: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	wards := []models.Ward{}
+	for rows.Next() {
+		var ward models.Ward
+		err := rows.Scan(&ward.WardID, &ward.HospitalID, &ward.Name, &ward.Department, &ward.Floor,
+			&ward.GenderRestriction, &ward.NurseStation, &ward.RoomCount, &ward.BedCount)
+		if err != nil {
+			log.Printf("Error scanning ward row: %v", err)
+			continue
+		}
+		wards = append(wards, ward)
+	}
+
+	json.NewEncoder(w).Encode(wards)
+}
+
+// decodeWard reads and validates a ward from the request body
+func decodeWard(r *http.Request) (models.Ward, error) {
+	var ward models.Ward
+	if err := json.NewDecoder(r.Body).Decode(&ward); err != nil {
+		return ward, fmt.Errorf("Invalid request body")
+	}
+	ward.Name = strings.TrimSpace(ward.Name)
+	if ward.Name == "" || ward.HospitalID == 0 {
+		return ward, fmt.Errorf("hospitalID and name are required")
+	}
+	if ward.GenderRestriction == "" {
+		ward.GenderRestriction = "any"
+	}
+	if !validGenderRestriction(ward.GenderRestriction) {
+		return ward, fmt.Errorf("genderRestriction must be any, male or female")
+	}
+	return ward, nil
+}
+
+// CreateWard adds a ward to a hospital
+func CreateWard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ward, err := decodeWard(r)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO Wards (HospitalID, Name, Department, Floor, GenderRestriction, NurseStation)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, ward.HospitalID, ward.Name, ward.Department, ward.Floor, ward.GenderRestriction, ward.NurseStation)
+	if err != nil {
+		sendWardWriteError(w, err, "creating ward", "A ward with this name already exists in the hospital", "Hospital not found")
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	ward.WardID = int(id)
+	json.NewEncoder(w).Encode(ward)
+}
+
+// UpdateWard changes a ward's details
+func UpdateWard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	wardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid ward ID", http.StatusBadRequest)
+		return
+	}
+
+	ward, err := decodeWard(r)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE Wards
+		SET HospitalID = ?, Name = ?, Department = ?, Floor = ?, GenderRestriction = ?, NurseStation = ?
+		WHERE WardID = ?
+	`, ward.HospitalID, ward.Name, ward.Department, ward.Floor, ward.GenderRestriction, ward.NurseStation, wardID)
+	if err != nil {
+		sendWardWriteError(w, err, "updating ward", "A ward with this name already exists in the hospital", "Hospital not found")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Wards WHERE WardID = ?)", wardID).Scan(&exists)
+		if !exists {
+			sendJSONError(w, "Ward not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	ward.WardID = wardID
+	json.NewEncoder(w).Encode(ward)
+}
+
+// DeleteWard removes a ward that has no rooms
+func DeleteWard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	wardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid ward ID", http.StatusBadRequest)
+		return
+	}
+
+	var rooms int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM Rooms WHERE WardID = ?", wardID).Scan(&rooms); err != nil {
+		log.Printf("Error counting ward rooms: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if rooms > 0 {
+		sendJSONError(w, "Remove the ward's rooms before deleting it", http.StatusConflict)
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM Wards WHERE WardID = ?", wardID)
+	if err != nil {
+		log.Printf("Error deleting ward: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		sendJSONError(w, "Ward not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Ward deleted",
+	})
+}
+
+// GetWardRooms lists the rooms of a ward with their bed counts
+func GetWardRooms(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	wardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid ward ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT r.RoomID, r.WardID, r.RoomNumber, r.Isolation, COUNT(bi.BedID)
+		FROM Rooms r
+		LEFT JOIN BedInventory bi ON bi.RoomID = r.RoomID
+		WHERE r.WardID = ?
+		GROUP BY r.RoomID
+		ORDER BY r.RoomNumber
+	`, wardID)
+	if err != nil {
+		log.Printf("Error querying rooms: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rooms := []models.Room{}
+	for rows.Next() {
+		var room models.Room
+		if err := rows.Scan(&room.RoomID, &room.WardID, &room.RoomNumber, &room.Isolation, &room.BedCount); err != nil {
+			log.Printf("Error scanning room row: %v", err)
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+
+	json.NewEncoder(w).Encode(rooms)
+}
+
+// CreateRoom adds a room to a ward
+func CreateRoom(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	wardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid ward ID", http.StatusBadRequest)
+		return
+	}
+
+	var room models.Room
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	room.RoomNumber = strings.TrimSpace(room.RoomNumber)
+	if room.RoomNumber == "" {
+		sendJSONError(w, "roomNumber is required", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	if err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Wards WHERE WardID = ?)", wardID).Scan(&exists); err != nil {
+		log.Printf("Error checking ward: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		sendJSONError(w, "Ward not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := database.DB.Exec("INSERT INTO Rooms (WardID, RoomNumber, Isolation) VALUES (?, ?, ?)",
+		wardID, room.RoomNumber, room.Isolation)
+	if err != nil {
+		sendWardWriteError(w, err, "creating room", "A room with this number already exists in the ward", "Ward not found")
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	room.RoomID = int(id)
+	room.WardID = wardID
+	json.NewEncoder(w).Encode(room)
+}
+
+// UpdateRoom renumbers a room or moves it to another ward
+func UpdateRoom(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	roomID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid room ID", http.StatusBadRequest)
+		return
+	}
+
+	var room models.Room
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	room.RoomNumber = strings.TrimSpace(room.RoomNumber)
+	if room.RoomNumber == "" || room.WardID == 0 {
+		sendJSONError(w, "wardID and roomNumber are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec("UPDATE Rooms SET WardID = ?, RoomNumber = ?, Isolation = ? WHERE RoomID = ?",
+		room.WardID, room.RoomNumber, room.Isolation, roomID)
+	if err != nil {
+		sendWardWriteError(w, err, "updating room", "A room with this number already exists in the ward", "Ward not found")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM Rooms WHERE RoomID = ?)", roomID).Scan(&exists)
+		if !exists {
+			sendJSONError(w, "Room not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	room.RoomID = roomID
+	json.NewEncoder(w).Encode(room)
+}
+
+// DeleteRoom removes a room that has no beds
+func DeleteRoom(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	roomID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid room ID", http.StatusBadRequest)
+		return
+	}
+
+	var beds int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM BedInventory WHERE RoomID = ?", roomID).Scan(&beds); err != nil {
+		log.Printf("Error counting room beds: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if beds > 0 {
+		sendJSONError(w, "Move the room's beds before deleting it", http.StatusConflict)
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM Rooms WHERE RoomID = ?", roomID)
+	if err != nil {
+		log.Printf("Error deleting room: %v", err)
+		sendJSONError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		sendJSONError(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Room deleted",
+	})
+}
+
+// UpdateBedLocation places a bed in a room and sets its label within the room
+func UpdateBedLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	bedID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendJSONError(w, "Invalid bed ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		RoomID int    `json:"roomID"`
+		Label  string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Label = strings.TrimSpace(req.Label)
+	if req.RoomID == 0 || req.Label == "" {
+		sendJSONError(w, "roomID and label are required", http.StatusBadRequest)
+		return
+	}
+
+	// The room's ward must belong to the bed's hospital
+	var wardName, roomNumber string
+	err = database.DB.QueryRow(`
+		SELECT w.Name, r.RoomNumber
+		FROM Rooms r
+		JOIN Wards w ON r.WardID = w.WardID
+		JOIN BedInventory bi ON bi.HospitalID = w.HospitalID
+		WHERE r.RoomID = ? AND bi.BedID = ?
+	`, req.RoomID, bedID).Scan(&wardName, &roomNumber)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Bed or room not found in the same hospital", http.StatusNotFound)
+		} else {
+			log.Printf("Error checking room: %v", err)
+			sendJSONError(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	_, err = database.DB.Exec("UPDATE BedInventory SET RoomID = ?, Label = ? WHERE BedID = ?", req.RoomID, req.Label, bedID)
+	if err != nil {
+		sendWardWriteError(w, err, "updating bed location", "Another bed in this room already has that label", "Room not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"bedId":    bedID,
+		"roomID":   req.RoomID,
+		"label":    req.Label,
+		"location": bedLocation(bedID, wardName, roomNumber, req.Label),
+	})
+}
@@ -0,0 +1,26 @@
+package models
+
+// Admission is an inpatient encounter. It owns the bed assignments of a stay
+// and records who admitted the patient, why, and how they were discharged.
+type Admission struct {
+	AdmissionID         int             `json:"admissionID"`
+	PatientID           int             `json:"patientID"`
+	PatientName         string          `json:"patientName,omitempty"`
+	HospitalID          int             `json:"hospitalID"`
+	AppointmentID       int             `json:"appointmentID,omitempty"`
+	AdmittingDoctorID   int             `json:"admittingDoctorID,omitempty"`
+	AdmittingDoctor     string          `json:"admittingDoctor,omitempty"`
+	AttendingDoctorID   int             `json:"attendingDoctorID,omitempty"`
+	AttendingDoctor     string          `json:"attendingDoctor,omitempty"`
+	Diagnosis           string          `json:"diagnosis,omitempty"`
+	AdmittedAt          string          `json:"admittedAt"`
+	DischargedAt        string          `json:"dischargedAt,omitempty"`
+	DischargeType       string          `json:"dischargeType,omitempty"`
+	DischargingDoctorID int             `json:"dischargingDoctorID,omitempty"`
+	DischargeSummary    string          `json:"dischargeSummary,omitempty"`
+	Status              string          `json:"status"`          // admitted, discharged
+	BedID               int             `json:"bedID,omitempty"` // current bed while admitted
+	BedType             string          `json:"bedType,omitempty"`
+	Location            string          `json:"location,omitempty"`
+	Assignments         []BedAssignment `json:"assignments,omitempty"`
+}
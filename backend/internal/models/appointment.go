@@ -23,3 +23,46 @@ type AppointmentRequest struct {
 	AppointmentTime string `json:"appointment_time"`
 	Description     string `json:"description"`
 }
+
+// AppointmentSeries is a recurring set of appointments generated from an RRULE
+type AppointmentSeries struct {
+	SeriesID        int                `json:"series_id"`
+	PatientID       int                `json:"patient_id"`
+	DoctorID        int                `json:"doctor_id"`
+	RRule           string             `json:"rrule"`
+	StartDate       string             `json:"start_date"`
+	AppointmentTime string             `json:"appointment_time"`
+	Description     string             `json:"description"`
+	Status          string             `json:"status"` // active, cancelled
+	Occurrences     []SeriesOccurrence `json:"occurrences"`
+}
+
+// SeriesOccurrence is one appointment in a series
+type SeriesOccurrence struct {
+	AppointmentID   int    `json:"appointment_id,omitempty"`
+	SeriesIndex     int    `json:"series_index"`
+	AppointmentDate string `json:"appointment_date"`
+	AppointmentTime string `json:"appointment_time"`
+	Status          string `json:"status"`
+	Resolution      string `json:"resolution,omitempty"` // booked, moved, skipped, conflict
+}
+
+// WaitlistEntry is a patient waiting for a slot with a doctor or in a department
+type WaitlistEntry struct {
+	WaitlistID     int    `json:"waitlist_id"`
+	Position       int    `json:"position"`
+	PatientID      int    `json:"patient_id"`
+	PatientName    string `json:"patient_name"`
+	DoctorID       int    `json:"doctor_id,omitempty"`
+	DoctorName     string `json:"doctor_name,omitempty"`
+	Department     string `json:"department"`
+	PreferredFrom  string `json:"preferred_from"`
+	PreferredTo    string `json:"preferred_to"`
+	Notes          string `json:"notes,omitempty"`
+	Status         string `json:"status"` // waiting, offered, booked, cancelled
+	CreatedAt      string `json:"created_at"`
+	OfferID        int    `json:"offer_id,omitempty"`
+	OfferDate      string `json:"offer_date,omitempty"`
+	OfferTime      string `json:"offer_time,omitempty"`
+	OfferExpiresAt string `json:"offer_expires_at,omitempty"`
+}
@@ -5,17 +5,33 @@ type Bed struct {
 	BedID        int    `json:"bedID"`
 	HospitalID   int    `json:"hospitalID"`
 	BedType      string `json:"bedType"`
-	Status       string `json:"status"` // available, occupied, maintenance
+	Status       string `json:"status"` // available, occupied, reserved, cleaning, maintenance, blocked
 	HospitalName string `json:"hospitalName,omitempty"`
+	WardID       int    `json:"wardID,omitempty"`
+	WardName     string `json:"wardName,omitempty"`
+	Floor        int    `json:"floor,omitempty"`
+	RoomID       int    `json:"roomID,omitempty"`
+	RoomNumber   string `json:"roomNumber,omitempty"`
+	Label        string `json:"label,omitempty"`
+	Location     string `json:"location,omitempty"`     // e.g. "Ward 3B, Room 12, Bed 2"
+	ReservedFrom string `json:"reservedFrom,omitempty"` // start of the next reservation, in availability searches
 }
 
 // BedType represents a type of hospital bed
 type BedType struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-	Total       int    `json:"total"`
-	Occupied    int    `json:"occupied"`
-	Vacant      int    `json:"vacant"`
+	Type             string  `json:"type"`
+	Description      string  `json:"description"`
+	Total            int     `json:"total"`
+	Occupied         int     `json:"occupied"`
+	Vacant           int     `json:"vacant"`
+	AcuityLevel      int     `json:"acuityLevel"` // 1 (general care) to 5 (critical care)
+	DailyTariff      float64 `json:"dailyTariff"`
+	NeedsVentilator  bool    `json:"needsVentilator"`
+	NeedsMonitor     bool    `json:"needsMonitor"`
+	BedsPerNurse     float64 `json:"bedsPerNurse"`
+	NurseToBedRatio  string  `json:"nurseToBedRatio"` // e.g. "1:2"
+	IsolationCapable bool    `json:"isolationCapable"`
+	Retired          bool    `json:"retired"`
 }
 
 // BedAssignment represents a patient's assignment to a bed
@@ -28,7 +44,23 @@ type BedAssignment struct {
 	AdmissionDate string `json:"admissionDate"`
 	DischargeDate string `json:"dischargeDate,omitempty"`
 	Status        string `json:"status"` // current, discharged
-	Notes         string `json:"notes,omitempty"`
+	Notes         string `json:"notes,omitempty"` // stored with the movement that opened the assignment
+}
+
+// BedReservation holds a bed for a planned admission
+type BedReservation struct {
+	ReservationID    int    `json:"reservationID"`
+	BedID            int    `json:"bedID"`
+	PatientID        int    `json:"patientID"`
+	PatientName      string `json:"patientName,omitempty"`
+	StartDate        string `json:"startDate"`
+	ExpectedStayDays int    `json:"expectedStayDays"`
+	EndDate          string `json:"endDate"` // StartDate + ExpectedStayDays, exclusive
+	ExpiresAt        string `json:"expiresAt"`
+	Status           string `json:"status"` // active, converted, cancelled, expired
+	AssignmentID     int    `json:"assignmentID,omitempty"`
+	Notes            string `json:"notes,omitempty"`
+	Location         string `json:"location,omitempty"`
 }
 
 // BedStats represents statistics for bed occupancy
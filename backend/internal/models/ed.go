@@ -0,0 +1,52 @@
+package models
+
+// EDVisit is a patient's attendance at the emergency department, from
+// registration to disposition
+type EDVisit struct {
+	VisitID               int                `json:"visitID"`
+	HospitalID            int                `json:"hospitalID"`
+	PatientID             int                `json:"patientID"`
+	PatientName           string             `json:"patientName"`
+	Gender                string             `json:"gender,omitempty"`
+	Unidentified          bool               `json:"unidentified"`
+	ArrivalMode           string             `json:"arrivalMode"` // walk-in, ambulance, police, referral, other
+	ChiefComplaint        string             `json:"chiefComplaint"`
+	ArrivedAt             string             `json:"arrivedAt"`
+	TriageLevel           int                `json:"triageLevel,omitempty"` // 1 (resuscitation) to 5 (non-urgent), 0 until triaged
+	TriageCategory        string             `json:"triageCategory,omitempty"`
+	TriagedAt             string             `json:"triagedAt,omitempty"`
+	DoctorID              int                `json:"doctorID,omitempty"`
+	DoctorName            string             `json:"doctorName,omitempty"`
+	SeenAt                string             `json:"seenAt,omitempty"`
+	Status                string             `json:"status"`                // waiting, triaged, in_treatment, awaiting_bed, admitted, discharged, transferred, left
+	WaitMinutes           int                `json:"waitMinutes"`           // arrival to doctor, or until now
+	TargetMinutes         int                `json:"targetMinutes"`         // time to doctor for the triage level
+	Overdue               bool               `json:"overdue"`               // not seen within the target
+	Disposition           string             `json:"disposition,omitempty"` // admit, discharge, transfer, left
+	DispositionAt         string             `json:"dispositionAt,omitempty"`
+	DispositionNotes      string             `json:"dispositionNotes,omitempty"`
+	DestinationHospitalID int                `json:"destinationHospitalID,omitempty"`
+	DestinationName       string             `json:"destinationName,omitempty"`
+	BedRequestID          int                `json:"bedRequestID,omitempty"`
+	AdmissionID           int                `json:"admissionID,omitempty"`
+	LatestVitals          *TriageAssessment  `json:"latestVitals,omitempty"`
+	Assessments           []TriageAssessment `json:"assessments,omitempty"`
+}
+
+// TriageAssessment is one triage of an ED visit with the vitals taken. Vitals
+// that weren't measured are omitted.
+type TriageAssessment struct {
+	AssessmentID    int      `json:"assessmentID"`
+	TriageLevel     int      `json:"triageLevel"`
+	HeartRate       *int     `json:"heartRate,omitempty"`
+	SystolicBP      *int     `json:"systolicBP,omitempty"`
+	DiastolicBP     *int     `json:"diastolicBP,omitempty"`
+	RespiratoryRate *int     `json:"respiratoryRate,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	SpO2            *int     `json:"spo2,omitempty"`
+	PainScore       *int     `json:"painScore,omitempty"`
+	GCS             *int     `json:"gcs,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+	AssessedBy      int      `json:"assessedBy,omitempty"`
+	AssessedAt      string   `json:"assessedAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// HousekeepingTask is the cleaning of a bed between patients
+type HousekeepingTask struct {
+	TaskID       int    `json:"taskID"`
+	BedID        int    `json:"bedID"`
+	HospitalID   int    `json:"hospitalID"`
+	WardID       int    `json:"wardID,omitempty"`
+	WardName     string `json:"wardName,omitempty"`
+	Location     string `json:"location,omitempty"`
+	Source       string `json:"source"` // turnover, manual
+	Reason       string `json:"reason,omitempty"`
+	Status       string `json:"status"` // pending, assigned, in_progress, completed, verified, cancelled
+	AssignedTo   int    `json:"assignedTo,omitempty"`
+	AssigneeName string `json:"assigneeName,omitempty"`
+	AssignedAt   string `json:"assignedAt,omitempty"`
+	StartedAt    string `json:"startedAt,omitempty"`
+	CompletedAt  string `json:"completedAt,omitempty"`
+	CompletedBy  int    `json:"completedBy,omitempty"`
+	VerifiedAt   string `json:"verifiedAt,omitempty"`
+	VerifiedBy   int    `json:"verifiedBy,omitempty"`
+	FailedChecks int    `json:"failedChecks"`
+	Notes        string `json:"notes,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// QueueToken represents a patient's place in a doctor's outpatient queue for a day
+type QueueToken struct {
+	TokenID       int    `json:"tokenId"`
+	DoctorID      int    `json:"doctorId"`
+	DoctorName    string `json:"doctorName,omitempty"`
+	Room          string `json:"room,omitempty"`
+	PatientID     int    `json:"patientId"`
+	PatientName   string `json:"patientName,omitempty"`
+	AppointmentID int    `json:"appointmentId,omitempty"`
+	QueueDate     string `json:"queueDate"`
+	TokenNumber   int    `json:"tokenNumber"`
+	Priority      string `json:"priority"` // normal, elderly, emergency
+	Status        string `json:"status"`   // waiting, called, skipped, completed
+	CheckedInAt   string `json:"checkedInAt,omitempty"`
+	CalledAt      string `json:"calledAt,omitempty"`
+}
+
+// QueueDisplayRoom is one row of the waiting-room display feed
+type QueueDisplayRoom struct {
+	DoctorID     int    `json:"doctorId"`
+	DoctorName   string `json:"doctorName"`
+	Room         string `json:"room"`
+	CurrentToken int    `json:"currentToken,omitempty"`
+	NextTokens   []int  `json:"nextTokens"`
+	WaitingCount int    `json:"waitingCount"`
+}
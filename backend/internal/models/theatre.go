@@ -0,0 +1,50 @@
+package models
+
+// Theatre is an operating room in a hospital
+type Theatre struct {
+	TheatreID       int              `json:"theatreID"`
+	HospitalID      int              `json:"hospitalID"`
+	Name            string           `json:"name"`
+	Specialty       string           `json:"specialty,omitempty"`
+	TurnoverMinutes int              `json:"turnoverMinutes"` // kept free between cases
+	Active          bool             `json:"active"`
+	Sessions        []TheatreSession `json:"sessions"`
+}
+
+// TheatreSession is a weekly period during which a theatre takes cases
+type TheatreSession struct {
+	SessionID   int    `json:"sessionID"`
+	Weekday     int    `json:"weekday"` // 0 = Sunday
+	WeekdayName string `json:"weekdayName"`
+	StartTime   string `json:"startTime"` // HH:MM
+	EndTime     string `json:"endTime"`
+	Department  string `json:"department,omitempty"` // empty when open to any department
+}
+
+// SurgicalCase is an operation booked into a theatre
+type SurgicalCase struct {
+	CaseID           int    `json:"caseID"`
+	TheatreID        int    `json:"theatreID"`
+	TheatreName      string `json:"theatreName"`
+	HospitalID       int    `json:"hospitalID"`
+	PatientID        int    `json:"patientID"`
+	PatientName      string `json:"patientName"`
+	Procedure        string `json:"procedure"`
+	SurgeonID        int    `json:"surgeonID"`
+	SurgeonName      string `json:"surgeonName"`
+	AnaesthetistID   int    `json:"anaesthetistID,omitempty"`
+	AnaesthetistName string `json:"anaesthetistName,omitempty"`
+	ScheduledStart   string `json:"scheduledStart"`
+	ScheduledEnd     string `json:"scheduledEnd"`
+	EstimatedMinutes int    `json:"estimatedMinutes"`
+	Emergency        bool   `json:"emergency"`
+	Status           string `json:"status"` // scheduled, in_progress, in_recovery, done, cancelled
+	StartedAt        string `json:"startedAt,omitempty"`
+	SurgeryEndedAt   string `json:"surgeryEndedAt,omitempty"`
+	CompletedAt      string `json:"completedAt,omitempty"`
+	Notes            string `json:"notes,omitempty"`
+	CancelReason     string `json:"cancelReason,omitempty"`
+	BookedBy         int    `json:"bookedBy,omitempty"`
+	OutsideSession   bool   `json:"outsideSession,omitempty"` // not within one of the theatre's sessions
+	Overrunning      bool   `json:"overrunning,omitempty"`    // in theatre past its scheduled end
+}
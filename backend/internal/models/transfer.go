@@ -0,0 +1,39 @@
+package models
+
+// HospitalTransfer moves an admitted patient from one hospital to another
+type HospitalTransfer struct {
+	TransferID      int             `json:"transferID"`
+	PatientID       int             `json:"patientID"`
+	PatientName     string          `json:"patientName,omitempty"`
+	FromHospitalID  int             `json:"fromHospitalID"`
+	FromHospital    string          `json:"fromHospital,omitempty"`
+	ToHospitalID    int             `json:"toHospitalID"`
+	ToHospital      string          `json:"toHospital,omitempty"`
+	FromBedID       int             `json:"fromBedID,omitempty"`
+	FromAdmissionID int             `json:"fromAdmissionID,omitempty"`
+	BedType         string          `json:"bedType"`
+	Urgency         string          `json:"urgency"`
+	Reason          string          `json:"reason,omitempty"`
+	ClinicalSummary string          `json:"clinicalSummary,omitempty"`
+	Status          string          `json:"status"` // requested, accepted, rejected, in_transit, completed, cancelled
+	ReservationID   int             `json:"reservationID,omitempty"`
+	ToBedID         int             `json:"toBedID,omitempty"`
+	ToAdmissionID   int             `json:"toAdmissionID,omitempty"`
+	ToAssignmentID  int             `json:"toAssignmentID,omitempty"`
+	TransportMode   string          `json:"transportMode,omitempty"`
+	RequestedAt     string          `json:"requestedAt"`
+	DepartedAt      string          `json:"departedAt,omitempty"`
+	ArrivedAt       string          `json:"arrivedAt,omitempty"`
+	Events          []TransferEvent `json:"events,omitempty"`
+}
+
+// TransferEvent is one step in a transfer's audit trail
+type TransferEvent struct {
+	EventID    int    `json:"eventID"`
+	HospitalID int    `json:"hospitalID"`
+	FromStatus string `json:"fromStatus,omitempty"`
+	ToStatus   string `json:"toStatus"`
+	Actor      string `json:"actor,omitempty"`
+	Notes      string `json:"notes,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+}
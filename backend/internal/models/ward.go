@@ -0,0 +1,23 @@
+package models
+
+// Ward is a nursing unit within a hospital, made up of rooms of beds
+type Ward struct {
+	WardID            int    `json:"wardID"`
+	HospitalID        int    `json:"hospitalID"`
+	Name              string `json:"name"`
+	Department        string `json:"department"`
+	Floor             int    `json:"floor"`
+	GenderRestriction string `json:"genderRestriction"` // any, male, female
+	NurseStation      string `json:"nurseStation"`
+	RoomCount         int    `json:"roomCount"`
+	BedCount          int    `json:"bedCount"`
+}
+
+// Room is a room within a ward
+type Room struct {
+	RoomID     int    `json:"roomID"`
+	WardID     int    `json:"wardID"`
+	RoomNumber string `json:"roomNumber"`
+	Isolation  bool   `json:"isolation"` // single room suitable for isolating infectious patients
+	BedCount   int    `json:"bedCount"`
+}
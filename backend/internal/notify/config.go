@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"os"
+	"strconv"
+)
+
+// defaultOutboxPath is where messages are written when no real channel is configured
+const defaultOutboxPath = "notifications_outbox.log"
+
+// FromEnv builds channels from the environment:
+//
+//	SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASSWORD, SMTP_FROM  email over SMTP
+//	SMS_API_URL, SMS_API_KEY, SMS_SENDER                      SMS gateway
+//	NOTIFY_OUTBOX                                             outbox file path
+//
+// Email or SMS that is not configured falls back to the outbox file, so
+// development setups never send real messages.
+func FromEnv() []Channel {
+	outbox := os.Getenv("NOTIFY_OUTBOX")
+	if outbox == "" {
+		outbox = defaultOutboxPath
+	}
+
+	var chs []Channel
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil || port == 0 {
+			port = 587
+		}
+		chs = append(chs, &SMTPChannel{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		})
+	} else {
+		chs = append(chs, &OutboxChannel{Path: outbox, Channel: "email"})
+	}
+
+	if url := os.Getenv("SMS_API_URL"); url != "" {
+		chs = append(chs, &SMSChannel{Provider: &HTTPSMSProvider{
+			URL:    url,
+			APIKey: os.Getenv("SMS_API_KEY"),
+			Sender: os.Getenv("SMS_SENDER"),
+		}})
+	} else {
+		chs = append(chs, &OutboxChannel{Path: outbox, Channel: "sms"})
+	}
+
+	return chs
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPChannel sends email through an SMTP server
+type SMTPChannel struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (c *SMTPChannel) Name() string { return "email" }
+
+func (c *SMTPChannel) Kind() string { return "email" }
+
+// Send delivers a plain-text email, as a multipart message when it has attachments
+func (c *SMTPChannel) Send(msg Message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		b.WriteString(body)
+	} else {
+		writeMultipart(&b, body, msg.Attachments)
+	}
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", c.Host, c.Port)
+	return smtp.SendMail(addr, auth, c.From, []string{msg.To}, []byte(b.String()))
+}
+
+// mimeBoundary separates the parts of a multipart email
+const mimeBoundary = "pulsepoint-notification-boundary"
+
+// writeMultipart writes a multipart/mixed body with the text first and each
+// attachment base64 encoded in 76 character lines
+func writeMultipart(b *strings.Builder, body string, attachments []Attachment) {
+	fmt.Fprintf(b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mimeBoundary)
+
+	fmt.Fprintf(b, "--%s\r\n", mimeBoundary)
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(body)
+	b.WriteString("\r\n")
+
+	for _, a := range attachments {
+		fmt.Fprintf(b, "--%s\r\n", mimeBoundary)
+		fmt.Fprintf(b, "Content-Type: %s; name=%q\r\n", a.ContentType, a.Filename)
+		fmt.Fprintf(b, "Content-Disposition: attachment; filename=%q\r\n", a.Filename)
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(b, "--%s--\r\n", mimeBoundary)
+}
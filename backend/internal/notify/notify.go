@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Message is a single notification to one recipient
+type Message struct {
+	To          string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent along with an email. Channels that can't carry
+// files, such as SMS, ignore attachments.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Channel delivers messages. Kind says which patient contact the channel
+// uses: "email" channels are given an email address, "sms" channels a phone number.
+type Channel interface {
+	Name() string
+	Kind() string
+	Send(msg Message) error
+}
+
+// Retry settings for Deliver
+const (
+	maxAttempts  = 4
+	initialDelay = 2 * time.Second
+)
+
+var (
+	mu       sync.RWMutex
+	channels []Channel
+)
+
+// Configure replaces the set of channels notifications are sent through
+func Configure(chs ...Channel) {
+	mu.Lock()
+	defer mu.Unlock()
+	channels = chs
+	for _, ch := range chs {
+		log.Printf("Notification channel enabled: %s (%s)", ch.Name(), ch.Kind())
+	}
+}
+
+// Channels returns the configured channels
+func Channels() []Channel {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Channel(nil), channels...)
+}
+
+// Deliver sends a message through a channel, retrying with exponential backoff.
+// It returns the number of attempts made and the last error, if every attempt failed.
+func Deliver(ch Channel, msg Message) (int, error) {
+	if msg.To == "" {
+		return 0, fmt.Errorf("no %s address for recipient", ch.Kind())
+	}
+
+	delay := initialDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = ch.Send(msg); err == nil {
+			return attempt, nil
+		}
+		log.Printf("Notification via %s to %s failed (attempt %d/%d): %v", ch.Name(), msg.To, attempt, maxAttempts, err)
+		if attempt < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return maxAttempts, err
+}
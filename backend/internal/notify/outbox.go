@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// OutboxChannel appends messages as JSON lines to a local file instead of
+// sending them. It is used in development and tests in place of SMTP or SMS.
+type OutboxChannel struct {
+	Path    string
+	Channel string // "email" or "sms"
+
+	mu sync.Mutex
+}
+
+func (c *OutboxChannel) Name() string { return "outbox:" + c.Channel }
+
+func (c *OutboxChannel) Kind() string { return c.Channel }
+
+// Send writes the message to the outbox file
+func (c *OutboxChannel) Send(msg Message) error {
+	attachments := []string{}
+	for _, a := range msg.Attachments {
+		attachments = append(attachments, a.Filename)
+	}
+
+	line, err := json.Marshal(map[string]interface{}{
+		"channel":     c.Channel,
+		"to":          msg.To,
+		"subject":     msg.Subject,
+		"body":        msg.Body,
+		"attachments": attachments,
+		"sentAt":      time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SMSProvider is implemented by adapters for SMS gateways
+type SMSProvider interface {
+	SendSMS(to, body string) error
+}
+
+// SMSChannel sends text messages through an SMSProvider
+type SMSChannel struct {
+	Provider SMSProvider
+}
+
+func (c *SMSChannel) Name() string { return "sms" }
+
+func (c *SMSChannel) Kind() string { return "sms" }
+
+// Send delivers the message body as a text message; the subject is not used
+func (c *SMSChannel) Send(msg Message) error {
+	return c.Provider.SendSMS(msg.To, msg.Body)
+}
+
+// HTTPSMSProvider posts messages as JSON to a gateway's HTTP API using a bearer API key
+type HTTPSMSProvider struct {
+	URL    string
+	APIKey string
+	Sender string
+	Client *http.Client
+}
+
+// SendSMS posts {"from", "to", "text"} to the gateway and treats any non-2xx status as a failure
+func (p *HTTPSMSProvider) SendSMS(to, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"from": p.Sender,
+		"to":   to,
+		"text": body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS gateway returned %s", resp.Status)
+	}
+	return nil
+}
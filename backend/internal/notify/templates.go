@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Notification events
+const (
+	EventConfirmation = "confirmation"
+	EventReminder     = "reminder"
+	EventReschedule   = "reschedule"
+	EventCancellation = "cancellation"
+	EventOTP          = "otp"
+)
+
+// TemplateData holds the appointment details available to message templates
+type TemplateData struct {
+	PatientName string
+	DoctorName  string
+	Department  string
+	Date        string
+	Time        string
+	OldDate     string
+	OldTime     string
+	Code        string // one-time passcode, only set for EventOTP
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	email   *template.Template
+	sms     *template.Template
+}
+
+func newMessageTemplate(event, subject, email, sms string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(event + ".subject").Parse(subject)),
+		email:   template.Must(template.New(event + ".email").Parse(email)),
+		sms:     template.Must(template.New(event + ".sms").Parse(sms)),
+	}
+}
+
+var templates = map[string]messageTemplate{
+	EventConfirmation: newMessageTemplate(EventConfirmation,
+		"Appointment confirmed for {{.Date}} at {{.Time}}",
+		`Dear {{.PatientName}},
+
+Your appointment with {{.DoctorName}} ({{.Department}}) is confirmed for {{.Date}} at {{.Time}}.
+
+Please arrive 15 minutes early and check in at the reception desk.
+
+PulsePoint Hospital`,
+		"PulsePoint: appointment with {{.DoctorName}} confirmed for {{.Date}} {{.Time}}."),
+	EventReminder: newMessageTemplate(EventReminder,
+		"Reminder: appointment on {{.Date}} at {{.Time}}",
+		`Dear {{.PatientName}},
+
+This is a reminder of your appointment with {{.DoctorName}} ({{.Department}}) on {{.Date}} at {{.Time}}.
+
+If you can no longer attend, please cancel or reschedule so the slot can be offered to another patient.
+
+PulsePoint Hospital`,
+		"PulsePoint reminder: appointment with {{.DoctorName}} on {{.Date}} {{.Time}}."),
+	EventReschedule: newMessageTemplate(EventReschedule,
+		"Appointment moved to {{.Date}} at {{.Time}}",
+		`Dear {{.PatientName}},
+
+Your appointment with {{.DoctorName}} ({{.Department}}) has been moved{{if .OldDate}} from {{.OldDate}} at {{.OldTime}}{{end}} to {{.Date}} at {{.Time}}.
+
+PulsePoint Hospital`,
+		"PulsePoint: appointment with {{.DoctorName}} moved to {{.Date}} {{.Time}}."),
+	EventCancellation: newMessageTemplate(EventCancellation,
+		"Appointment on {{.Date}} cancelled",
+		`Dear {{.PatientName}},
+
+Your appointment with {{.DoctorName}} ({{.Department}}) on {{.Date}} at {{.Time}} has been cancelled.
+
+Please contact us if you would like to book a new appointment.
+
+PulsePoint Hospital`,
+		"PulsePoint: appointment with {{.DoctorName}} on {{.Date}} {{.Time}} cancelled."),
+	EventOTP: newMessageTemplate(EventOTP,
+		"Your PulsePoint verification code",
+		`Your PulsePoint verification code is {{.Code}}.
+
+It expires in 10 minutes. If you did not request it, you can ignore this message.
+
+PulsePoint Hospital`,
+		"PulsePoint verification code: {{.Code}}. It expires in 10 minutes."),
+}
+
+// Render builds the message for an event. kind selects the email or SMS body.
+func Render(event, kind string, data TemplateData) (subject, body string, err error) {
+	t, ok := templates[event]
+	if !ok {
+		return "", "", fmt.Errorf("unknown notification event %q", event)
+	}
+
+	var sb, bb strings.Builder
+	if err := t.subject.Execute(&sb, data); err != nil {
+		return "", "", err
+	}
+	bodyTemplate := t.email
+	if kind == "sms" {
+		bodyTemplate = t.sms
+	}
+	if err := bodyTemplate.Execute(&bb, data); err != nil {
+		return "", "", err
+	}
+	return sb.String(), bb.String(), nil
+}
@@ -593,6 +593,24 @@ document.addEventListener('DOMContentLoaded', function() {
         renderAppointments(filteredAppointments);
     }
 
+    // Reload the bed management tab when the server pushes a change to a bed
+    // in the doctor's hospital. Only one stream is opened per page.
+    let bedEvents = null;
+    function watchBedEvents(hospitalId) {
+        if (bedEvents || !window.EventSource || !hospitalId) return;
+        bedEvents = new EventSource(`http://localhost:8080/api/beds/events?hospitalId=${hospitalId}`);
+        let reloadTimer = null;
+        const reload = () => {
+            const tab = document.getElementById('bed-management');
+            if (!tab || !tab.classList.contains('active')) return;
+            clearTimeout(reloadTimer);
+            reloadTimer = setTimeout(loadBeds, 300);
+        };
+        ['assigned', 'transferred', 'swapped', 'discharged', 'cleaned', 'status', 'reset'].forEach(type => {
+            bedEvents.addEventListener(type, reload);
+        });
+    }
+
     function loadBeds() {
         console.log('Loading beds data...');
         const employeeId = localStorage.getItem('employeeId') || sessionStorage.getItem('employeeId');
@@ -618,6 +636,7 @@ document.addEventListener('DOMContentLoaded', function() {
             })
             .then(data => {
                 console.log('Bed data loaded:', data);
+                watchBedEvents(data.hospitalId);
                 
                 // Update bed statistics
                 loadBedStatistics(data);
@@ -360,3 +360,598 @@ JOIN BedInventory bi
 LEFT JOIN BedAssignments ba 
     ON bi.BedID = ba.BedID 
        AND ba.DischargeDate IS NULL;
+
+
+-- Outpatient queue: one token per check-in, numbered per doctor per day
+ALTER TABLE Doctors ADD COLUMN Room VARCHAR(50);
+
+ALTER TABLE Appointment MODIFY Status ENUM('scheduled', 'checked-in', 'completed', 'cancelled') DEFAULT 'scheduled';
+
+CREATE TABLE QueueTokens (
+    TokenID INT AUTO_INCREMENT PRIMARY KEY,
+    DoctorID INT NOT NULL,
+    PatientID INT NOT NULL,
+    AppointmentID INT,  -- NULL for patients queued without an appointment
+    QueueDate DATE NOT NULL,
+    TokenNumber INT NOT NULL,
+    Priority ENUM('normal', 'elderly', 'emergency') NOT NULL DEFAULT 'normal',
+    Status ENUM('waiting', 'called', 'skipped', 'completed') NOT NULL DEFAULT 'waiting',
+    CheckedInAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CalledAt TIMESTAMP NULL,
+    UNIQUE KEY unique_doctor_day_token (DoctorID, QueueDate, TokenNumber),
+    UNIQUE KEY unique_appointment_token (AppointmentID),
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (AppointmentID) REFERENCES Appointment(AppointmentID)
+);
+
+
+-- Walk-in appointments and per-session overbooking
+ALTER TABLE Appointment
+    ADD COLUMN AppointmentType ENUM('booked', 'walk-in') NOT NULL DEFAULT 'booked',
+    ADD COLUMN Overbooked BOOLEAN NOT NULL DEFAULT FALSE;  -- TRUE when squeezed in beyond the session's slots
+
+CREATE TABLE DoctorSessionQuota (
+    DoctorID INT NOT NULL,
+    Session ENUM('morning', 'afternoon') NOT NULL,
+    OverbookQuota INT NOT NULL DEFAULT 2,
+    PRIMARY KEY (DoctorID, Session),
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID)
+);
+
+
+-- Recurring appointment series (physiotherapy, dialysis, post-op follow-up)
+CREATE TABLE AppointmentSeries (
+    SeriesID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    DoctorID INT NOT NULL,
+    RRule VARCHAR(255) NOT NULL,  -- e.g. FREQ=WEEKLY;COUNT=8
+    StartDate DATE NOT NULL,
+    AppointmentTime VARCHAR(10) NOT NULL,
+    Description TEXT,
+    Status ENUM('active', 'cancelled') NOT NULL DEFAULT 'active',
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID)
+);
+
+ALTER TABLE Appointment
+    ADD COLUMN SeriesID INT NULL,
+    ADD COLUMN SeriesIndex INT NULL,  -- 1-based position of the occurrence in its series
+    ADD FOREIGN KEY (SeriesID) REFERENCES AppointmentSeries(SeriesID);
+
+
+-- Appointment waitlist with time-limited slot offers
+CREATE TABLE AppointmentWaitlist (
+    WaitlistID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    DoctorID INT,              -- NULL means any doctor in Department
+    Department VARCHAR(50),
+    PreferredFrom DATE NOT NULL,
+    PreferredTo DATE NOT NULL,
+    Notes TEXT,
+    Status ENUM('waiting', 'offered', 'booked', 'cancelled') NOT NULL DEFAULT 'waiting',
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID)
+);
+
+CREATE TABLE SlotOffers (
+    OfferID INT AUTO_INCREMENT PRIMARY KEY,
+    WaitlistID INT NOT NULL,
+    DoctorID INT NOT NULL,
+    OfferDate DATE NOT NULL,
+    OfferTime VARCHAR(10) NOT NULL,
+    ExpiresAt DATETIME NOT NULL,
+    Status ENUM('held', 'confirmed', 'declined', 'expired') NOT NULL DEFAULT 'held',
+    AppointmentID INT,  -- set once the offer is confirmed
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (WaitlistID) REFERENCES AppointmentWaitlist(WaitlistID),
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (AppointmentID) REFERENCES Appointment(AppointmentID)
+);
+
+-- Appointment notifications: delivery log and per-patient opt-out
+CREATE TABLE NotificationLog (
+    NotificationID INT AUTO_INCREMENT PRIMARY KEY,
+    AppointmentID INT NOT NULL,
+    PatientID INT NOT NULL,
+    Channel VARCHAR(30) NOT NULL,   -- channel name, e.g. email, sms, outbox:email
+    Event ENUM('confirmation', 'reminder', 'reschedule', 'cancellation') NOT NULL,
+    Recipient VARCHAR(255) NOT NULL,
+    Status ENUM('sent', 'failed', 'skipped') NOT NULL,
+    Attempts INT NOT NULL DEFAULT 0,
+    Error TEXT,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_notification_appointment (AppointmentID, Event),
+    FOREIGN KEY (AppointmentID) REFERENCES Appointment(AppointmentID),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID)
+);
+
+CREATE TABLE PatientNotificationOptOut (
+    PatientID INT NOT NULL,
+    Channel ENUM('email', 'sms') NOT NULL,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (PatientID, Channel),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID)
+);
+
+-- iCalendar feeds: SEQUENCE for calendar updates and revocable doctor feed tokens
+ALTER TABLE Appointment ADD COLUMN Sequence INT NOT NULL DEFAULT 0;
+
+CREATE TABLE DoctorCalendarTokens (
+    TokenID INT AUTO_INCREMENT PRIMARY KEY,
+    DoctorID INT NOT NULL,
+    Token CHAR(64) NOT NULL UNIQUE,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    RevokedAt DATETIME,
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID)
+);
+
+-- Patient portal: one-time sign-in codes and sessions (only hashes are stored)
+CREATE TABLE PatientOTPs (
+    OTPID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    CodeHash CHAR(64) NOT NULL,
+    Channel ENUM('email', 'sms') NOT NULL,
+    ExpiresAt DATETIME NOT NULL,
+    Attempts INT NOT NULL DEFAULT 0,
+    UsedAt DATETIME,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_otp_patient (PatientID, Channel, ExpiresAt),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID)
+);
+
+CREATE TABLE PatientSessions (
+    SessionID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    TokenHash CHAR(64) NOT NULL UNIQUE,
+    ExpiresAt DATETIME NOT NULL,
+    RevokedAt DATETIME,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID)
+);
+
+-- Discharge workflow: discharge details, planned discharges and bed cleaning
+ALTER TABLE BedAssignments
+    ADD COLUMN DischargeType ENUM('routine', 'against-advice', 'transfer-out', 'deceased'),
+    ADD COLUMN DischargingDoctorID INT,
+    ADD COLUMN PlannedDischargeDate DATE,  -- set while a discharge is planned; the bed shows as releasing
+    ADD COLUMN DischargeNotes TEXT,
+    ADD FOREIGN KEY (DischargingDoctorID) REFERENCES Doctors(DoctorID);
+
+-- Occupancy still comes from BedAssignments; Status covers beds that are empty but not usable
+ALTER TABLE BedInventory ADD COLUMN Status ENUM('available', 'cleaning', 'maintenance') NOT NULL DEFAULT 'available';
+
+-- At most one active assignment per bed and per patient. The generated
+-- columns are NULL once an assignment is discharged, and UNIQUE allows any
+-- number of NULLs. Existing duplicate active assignments must be discharged
+-- before these constraints can be added.
+ALTER TABLE BedAssignments
+    ADD COLUMN ActiveBedID INT AS (IF(DischargeDate IS NULL, BedID, NULL)) STORED,
+    ADD COLUMN ActivePatientID INT AS (IF(DischargeDate IS NULL, PatientID, NULL)) STORED,
+    ADD UNIQUE KEY uq_active_bed (ActiveBedID),
+    ADD UNIQUE KEY uq_active_patient (ActivePatientID);
+
+-- Bed lifecycle states with a transition log. Status is now the source of
+-- truth for whether a bed can be assigned, including occupied beds.
+ALTER TABLE BedInventory MODIFY COLUMN Status
+    ENUM('available', 'occupied', 'reserved', 'cleaning', 'maintenance', 'blocked') NOT NULL DEFAULT 'available';
+
+UPDATE BedInventory bi
+JOIN BedAssignments ba ON ba.BedID = bi.BedID AND ba.DischargeDate IS NULL
+SET bi.Status = 'occupied';
+
+CREATE TABLE BedStatusLog (
+    LogID INT AUTO_INCREMENT PRIMARY KEY,
+    BedID INT NOT NULL,
+    FromStatus VARCHAR(20),
+    ToStatus VARCHAR(20) NOT NULL,
+    Reason VARCHAR(255),
+    ChangedBy INT,  -- EmployeeID, NULL for system changes
+    ChangedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_bed_status_log (BedID, ChangedAt),
+    FOREIGN KEY (BedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (ChangedBy) REFERENCES Employees(EmployeeID)
+);
+
+-- Ward, room and bed label hierarchy
+CREATE TABLE Wards (
+    WardID INT AUTO_INCREMENT PRIMARY KEY,
+    HospitalID INT NOT NULL,
+    Name VARCHAR(50) NOT NULL,  -- e.g. 3B
+    Department VARCHAR(100),
+    Floor INT NOT NULL DEFAULT 0,
+    GenderRestriction ENUM('any', 'male', 'female') NOT NULL DEFAULT 'any',
+    NurseStation VARCHAR(100),
+    UNIQUE KEY uq_ward_name (HospitalID, Name),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID)
+);
+
+CREATE TABLE Rooms (
+    RoomID INT AUTO_INCREMENT PRIMARY KEY,
+    WardID INT NOT NULL,
+    RoomNumber VARCHAR(20) NOT NULL,
+    UNIQUE KEY uq_room_number (WardID, RoomNumber),
+    FOREIGN KEY (WardID) REFERENCES Wards(WardID)
+);
+
+ALTER TABLE BedInventory
+    ADD COLUMN RoomID INT,
+    ADD COLUMN Label VARCHAR(20),  -- bed label within the room, e.g. 2
+    ADD UNIQUE KEY uq_bed_label (RoomID, Label),
+    ADD FOREIGN KEY (RoomID) REFERENCES Rooms(RoomID);
+
+-- Bed reservations for planned admissions. EndDate is exclusive.
+CREATE TABLE BedReservations (
+    ReservationID INT AUTO_INCREMENT PRIMARY KEY,
+    BedID INT NOT NULL,
+    PatientID INT NOT NULL,
+    StartDate DATE NOT NULL,
+    ExpectedStayDays INT NOT NULL,
+    EndDate DATE AS (DATE_ADD(StartDate, INTERVAL ExpectedStayDays DAY)) STORED,
+    ExpiresAt DATETIME NOT NULL,  -- released automatically if not converted by then
+    Status ENUM('active', 'converted', 'cancelled', 'expired') NOT NULL DEFAULT 'active',
+    AssignmentID INT,
+    Notes TEXT,
+    CreatedBy INT,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_reservation_bed (BedID, Status, StartDate),
+    INDEX idx_reservation_expiry (Status, ExpiresAt),
+    FOREIGN KEY (BedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (AssignmentID) REFERENCES BedAssignments(AssignmentID),
+    FOREIGN KEY (CreatedBy) REFERENCES Employees(EmployeeID)
+);
+
+-- Queue of patients waiting for a bed, filled by the allocator
+ALTER TABLE Rooms ADD COLUMN Isolation BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE BedRequests (
+    RequestID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    HospitalID INT NOT NULL,
+    BedType VARCHAR(50) NOT NULL,
+    Urgency ENUM('routine', 'urgent', 'emergency') NOT NULL DEFAULT 'routine',
+    Isolation BOOLEAN NOT NULL DEFAULT FALSE,
+    Gender VARCHAR(10),  -- for ward gender restrictions, from the patient record
+    PreferredWardID INT,
+    RequestedBy INT,  -- DoctorID
+    Status ENUM('pending', 'fulfilled', 'cancelled') NOT NULL DEFAULT 'pending',
+    BedID INT,
+    AssignmentID INT,
+    FulfilledBy INT,  -- EmployeeID, NULL when filled automatically
+    FulfilledAt DATETIME,
+    Notes TEXT,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_bed_request_queue (Status, HospitalID, BedType),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (PreferredWardID) REFERENCES Wards(WardID),
+    FOREIGN KEY (RequestedBy) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (BedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (AssignmentID) REFERENCES BedAssignments(AssignmentID),
+    FOREIGN KEY (FulfilledBy) REFERENCES Employees(EmployeeID)
+);
+
+-- Ledger of bed movements: admissions, transfers, swaps and discharges
+CREATE TABLE BedMovements (
+    MovementID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    MovementType ENUM('admit', 'transfer', 'swap', 'discharge') NOT NULL,
+    FromBedID INT,
+    FromAssignmentID INT,  -- the assignment the movement closed
+    ToBedID INT,
+    ToAssignmentID INT,  -- the assignment the movement opened
+    Reason VARCHAR(255),
+    Notes TEXT,
+    ActorID INT,  -- EmployeeID, NULL for system changes
+    MovedAt DATETIME NOT NULL,
+    RecordedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_movement_patient (PatientID, MovedAt),
+    INDEX idx_movement_to_bed (ToBedID, MovedAt),
+    INDEX idx_movement_from_assignment (FromAssignmentID),
+    INDEX idx_movement_to_assignment (ToAssignmentID),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (FromBedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (ToBedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (FromAssignmentID) REFERENCES BedAssignments(AssignmentID),
+    FOREIGN KEY (ToAssignmentID) REFERENCES BedAssignments(AssignmentID),
+    FOREIGN KEY (ActorID) REFERENCES Employees(EmployeeID)
+);
+
+-- Seed the ledger from existing assignments so history covers earlier stays
+INSERT INTO BedMovements (PatientID, MovementType, ToBedID, ToAssignmentID, Reason, MovedAt)
+SELECT PatientID, 'admit', BedID, AssignmentID, 'Imported from bed assignments', AdmissionDate
+FROM BedAssignments;
+
+INSERT INTO BedMovements (PatientID, MovementType, FromBedID, FromAssignmentID, Reason, MovedAt)
+SELECT PatientID, 'discharge', BedID, AssignmentID, 'Imported from bed assignments', DischargeDate
+FROM BedAssignments
+WHERE DischargeDate IS NOT NULL;
+
+-- Admissions (inpatient encounters). An admission owns the bed assignments of
+-- one stay, from the first bed to discharge, across transfers and swaps.
+CREATE TABLE Admissions (
+    AdmissionID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    HospitalID INT NOT NULL,
+    AppointmentID INT,  -- the appointment the patient was admitted from
+    AdmittingDoctorID INT,
+    AttendingDoctorID INT,
+    Diagnosis TEXT,
+    AdmittedAt DATE NOT NULL,
+    DischargedAt DATE,
+    DischargeType ENUM('routine', 'against-advice', 'transfer-out', 'deceased'),
+    DischargingDoctorID INT,
+    DischargeSummary TEXT,
+    Status ENUM('admitted', 'discharged') NOT NULL DEFAULT 'admitted',
+    ActivePatientID INT AS (IF(Status = 'admitted', PatientID, NULL)) STORED,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_admitted_patient (ActivePatientID),
+    INDEX idx_admission_patient (PatientID, AdmittedAt),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (AppointmentID) REFERENCES Appointment(AppointmentID),
+    FOREIGN KEY (AdmittingDoctorID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (AttendingDoctorID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (DischargingDoctorID) REFERENCES Doctors(DoctorID)
+);
+
+ALTER TABLE BedAssignments
+    ADD COLUMN AdmissionID INT,
+    ADD INDEX idx_assignment_admission (AdmissionID),
+    ADD FOREIGN KEY (AdmissionID) REFERENCES Admissions(AdmissionID);
+
+-- Existing stays become admissions: transfers kept the original AdmissionDate,
+-- so the assignments of one stay share a patient and admission date
+INSERT INTO Admissions (PatientID, HospitalID, AdmittedAt, DischargedAt, DischargeType, DischargingDoctorID, Status)
+SELECT ba.PatientID, MIN(bi.HospitalID), ba.AdmissionDate,
+       IF(COUNT(ba.DischargeDate) = COUNT(*), MAX(ba.DischargeDate), NULL),
+       MAX(ba.DischargeType), MAX(ba.DischargingDoctorID),
+       IF(COUNT(ba.DischargeDate) = COUNT(*), 'discharged', 'admitted')
+FROM BedAssignments ba
+JOIN BedInventory bi ON bi.BedID = ba.BedID
+GROUP BY ba.PatientID, ba.AdmissionDate;
+
+UPDATE BedAssignments ba
+JOIN Admissions ad ON ad.PatientID = ba.PatientID AND ad.AdmittedAt = ba.AdmissionDate
+SET ba.AdmissionID = ad.AdmissionID;
+
+-- Transfers of admitted patients between hospitals. The sending hospital
+-- requests the transfer, the receiving hospital accepts it with a reserved
+-- bed, and the patient is discharged as transfer-out on departure and
+-- admitted at the destination on arrival.
+CREATE TABLE HospitalTransfers (
+    TransferID INT AUTO_INCREMENT PRIMARY KEY,
+    PatientID INT NOT NULL,
+    FromHospitalID INT NOT NULL,
+    ToHospitalID INT NOT NULL,
+    FromBedID INT,
+    FromAdmissionID INT,
+    BedType VARCHAR(50) NOT NULL,
+    Urgency ENUM('routine', 'urgent', 'emergency') NOT NULL DEFAULT 'routine',
+    Reason VARCHAR(255),
+    ClinicalSummary TEXT,
+    Status ENUM('requested', 'accepted', 'rejected', 'in_transit', 'completed', 'cancelled') NOT NULL DEFAULT 'requested',
+    ReservationID INT,  -- the bed held at the receiving hospital once accepted
+    ToBedID INT,
+    ToAdmissionID INT,
+    ToAssignmentID INT,
+    TransportMode VARCHAR(50),
+    RequestedBy INT NOT NULL,
+    RequestedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    RespondedBy INT,
+    RespondedAt DATETIME,
+    DepartedAt DATETIME,
+    ArrivedAt DATETIME,
+    -- At most one open transfer per patient
+    OpenPatientID INT AS (IF(Status IN ('requested', 'accepted', 'in_transit'), PatientID, NULL)) STORED,
+    UNIQUE KEY uq_open_transfer (OpenPatientID),
+    INDEX idx_transfer_from (FromHospitalID, Status),
+    INDEX idx_transfer_to (ToHospitalID, Status),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (FromHospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (ToHospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (FromBedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (FromAdmissionID) REFERENCES Admissions(AdmissionID),
+    FOREIGN KEY (ReservationID) REFERENCES BedReservations(ReservationID),
+    FOREIGN KEY (ToBedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (ToAdmissionID) REFERENCES Admissions(AdmissionID),
+    FOREIGN KEY (ToAssignmentID) REFERENCES BedAssignments(AssignmentID),
+    FOREIGN KEY (RequestedBy) REFERENCES Employees(EmployeeID),
+    FOREIGN KEY (RespondedBy) REFERENCES Employees(EmployeeID)
+);
+
+-- Audit trail of every step of a transfer, from either hospital
+CREATE TABLE HospitalTransferEvents (
+    EventID INT AUTO_INCREMENT PRIMARY KEY,
+    TransferID INT NOT NULL,
+    HospitalID INT NOT NULL,  -- the side that acted
+    FromStatus VARCHAR(20),
+    ToStatus VARCHAR(20) NOT NULL,
+    ActorID INT,  -- EmployeeID
+    Notes TEXT,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_transfer_event (TransferID, CreatedAt),
+    FOREIGN KEY (TransferID) REFERENCES HospitalTransfers(TransferID),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (ActorID) REFERENCES Employees(EmployeeID)
+);
+
+-- Housekeeping tasks for bed turnover. A task is opened whenever a bed goes
+-- for cleaning and closed when it leaves that state; completing a task
+-- returns its bed to available.
+CREATE TABLE HousekeepingTasks (
+    TaskID INT AUTO_INCREMENT PRIMARY KEY,
+    BedID INT NOT NULL,
+    HospitalID INT NOT NULL,
+    Source ENUM('turnover', 'manual') NOT NULL,  -- turnover: the patient left the bed
+    Reason VARCHAR(255),
+    Status ENUM('pending', 'assigned', 'in_progress', 'completed', 'verified', 'cancelled') NOT NULL DEFAULT 'pending',
+    AssignedTo INT,
+    AssignedAt DATETIME,
+    StartedAt DATETIME,
+    CompletedAt DATETIME,
+    CompletedBy INT,
+    VerifiedAt DATETIME,
+    VerifiedBy INT,
+    FailedChecks INT NOT NULL DEFAULT 0,  -- verifications that sent the bed back for cleaning
+    Notes TEXT,
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- At most one open task per bed
+    OpenBedID INT AS (IF(Status IN ('pending', 'assigned', 'in_progress'), BedID, NULL)) STORED,
+    UNIQUE KEY uq_open_task (OpenBedID),
+    INDEX idx_task_queue (HospitalID, Status, CreatedAt),
+    INDEX idx_task_assignee (AssignedTo, Status),
+    FOREIGN KEY (BedID) REFERENCES BedInventory(BedID),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (AssignedTo) REFERENCES Employees(EmployeeID),
+    FOREIGN KEY (CompletedBy) REFERENCES Employees(EmployeeID),
+    FOREIGN KEY (VerifiedBy) REFERENCES Employees(EmployeeID)
+);
+
+-- Beds already waiting for cleaning get a task
+INSERT INTO HousekeepingTasks (BedID, HospitalID, Source, Reason)
+SELECT BedID, HospitalID, 'turnover', 'Imported: bed awaiting cleaning'
+FROM BedInventory
+WHERE Status = 'cleaning';
+
+-- Bed type catalogue attributes. Retired types keep their beds but can't be
+-- used for new ones.
+ALTER TABLE BedTypes
+    ADD COLUMN AcuityLevel TINYINT NOT NULL DEFAULT 1,  -- 1 (general care) to 5 (critical care)
+    ADD COLUMN DailyTariff DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN NeedsVentilator BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN NeedsMonitor BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN BedsPerNurse DECIMAL(4, 1) NOT NULL DEFAULT 4,  -- nurse-to-bed ratio 1:BedsPerNurse
+    ADD COLUMN IsolationCapable BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN RetiredAt DATETIME,
+    ADD CONSTRAINT chk_bed_type_acuity CHECK (AcuityLevel BETWEEN 1 AND 5),
+    ADD CONSTRAINT chk_bed_type_ratio CHECK (BedsPerNurse > 0);
+
+-- Emergency department visits. Patients are registered with minimal details;
+-- unidentified patients get a placeholder record that is filled in once they
+-- are identified. TriageLevel is a five-level acuity scale from 1
+-- (resuscitation) to 5 (non-urgent). A patient has at most one open visit.
+ALTER TABLE Patients ADD COLUMN Unidentified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE EDVisits (
+    VisitID INT AUTO_INCREMENT PRIMARY KEY,
+    HospitalID INT NOT NULL,
+    PatientID INT NOT NULL,
+    ArrivalMode ENUM('walk-in', 'ambulance', 'police', 'referral', 'other') NOT NULL DEFAULT 'walk-in',
+    ChiefComplaint VARCHAR(255) NOT NULL,
+    ArrivedAt DATETIME NOT NULL,
+    RegisteredBy INT,  -- EmployeeID
+    TriageLevel TINYINT,  -- NULL until triaged
+    TriagedAt DATETIME,
+    DoctorID INT,
+    SeenAt DATETIME,  -- first doctor assigned
+    Status ENUM('waiting', 'triaged', 'in_treatment', 'awaiting_bed', 'admitted', 'discharged', 'transferred', 'left') NOT NULL DEFAULT 'waiting',
+    Disposition ENUM('admit', 'discharge', 'transfer', 'left'),
+    DispositionAt DATETIME,
+    DispositionBy INT,  -- EmployeeID
+    DispositionNotes TEXT,
+    DestinationHospitalID INT,  -- transfers to another hospital in the system
+    DestinationName VARCHAR(255),  -- transfers elsewhere
+    BedRequestID INT,  -- while awaiting a bed
+    AdmissionID INT,
+    ActivePatientID INT AS (IF(Status IN ('waiting', 'triaged', 'in_treatment', 'awaiting_bed'), PatientID, NULL)) STORED,
+    UNIQUE KEY uq_ed_open_patient (ActivePatientID),
+    INDEX idx_ed_board (HospitalID, Status, TriageLevel, ArrivedAt),
+    INDEX idx_ed_bed_request (BedRequestID),
+    CONSTRAINT chk_ed_triage_level CHECK (TriageLevel BETWEEN 1 AND 5),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (RegisteredBy) REFERENCES Employees(EmployeeID),
+    FOREIGN KEY (DoctorID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (DispositionBy) REFERENCES Employees(EmployeeID),
+    FOREIGN KEY (DestinationHospitalID) REFERENCES Hospital(HospitalID),
+    FOREIGN KEY (BedRequestID) REFERENCES BedRequests(RequestID),
+    FOREIGN KEY (AdmissionID) REFERENCES Admissions(AdmissionID)
+);
+
+-- Triage assessments with vitals; a visit can be re-triaged while it waits
+CREATE TABLE EDTriageAssessments (
+    AssessmentID INT AUTO_INCREMENT PRIMARY KEY,
+    VisitID INT NOT NULL,
+    TriageLevel TINYINT NOT NULL,
+    HeartRate SMALLINT,  -- beats per minute
+    SystolicBP SMALLINT,  -- mmHg
+    DiastolicBP SMALLINT,
+    RespiratoryRate SMALLINT,  -- breaths per minute
+    Temperature DECIMAL(4, 1),  -- degrees Celsius
+    SpO2 TINYINT,  -- percent
+    PainScore TINYINT,  -- 0 to 10
+    GCS TINYINT,  -- Glasgow Coma Scale, 3 to 15
+    Notes TEXT,
+    AssessedBy INT,  -- EmployeeID
+    AssessedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_triage_visit (VisitID, AssessedAt),
+    CONSTRAINT chk_triage_level CHECK (TriageLevel BETWEEN 1 AND 5),
+    FOREIGN KEY (VisitID) REFERENCES EDVisits(VisitID),
+    FOREIGN KEY (AssessedBy) REFERENCES Employees(EmployeeID)
+);
+
+-- Operating theatres. Each theatre has weekly sessions (Weekday 0 = Sunday)
+-- during which cases are booked; a session can be allocated to a department.
+-- TurnoverMinutes is kept free between cases in the same theatre for cleaning
+-- and setup.
+CREATE TABLE Theatres (
+    TheatreID INT AUTO_INCREMENT PRIMARY KEY,
+    HospitalID INT NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Specialty VARCHAR(50),  -- NULL for a general theatre
+    TurnoverMinutes INT NOT NULL DEFAULT 15,
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    UNIQUE KEY unique_hospital_theatre (HospitalID, Name),
+    CONSTRAINT chk_theatre_turnover CHECK (TurnoverMinutes BETWEEN 0 AND 240),
+    FOREIGN KEY (HospitalID) REFERENCES Hospital(HospitalID)
+);
+
+CREATE TABLE TheatreSessions (
+    SessionID INT AUTO_INCREMENT PRIMARY KEY,
+    TheatreID INT NOT NULL,
+    Weekday TINYINT NOT NULL,
+    StartTime TIME NOT NULL,
+    EndTime TIME NOT NULL,
+    Department VARCHAR(50),  -- NULL when open to any department
+    INDEX idx_theatre_session (TheatreID, Weekday, StartTime),
+    CONSTRAINT chk_session_weekday CHECK (Weekday BETWEEN 0 AND 6),
+    CONSTRAINT chk_session_times CHECK (StartTime < EndTime),
+    FOREIGN KEY (TheatreID) REFERENCES Theatres(TheatreID) ON DELETE CASCADE
+);
+
+-- Surgical cases booked into a theatre. Emergency cases may be booked outside
+-- the theatre's sessions.
+CREATE TABLE SurgicalCases (
+    CaseID INT AUTO_INCREMENT PRIMARY KEY,
+    TheatreID INT NOT NULL,
+    PatientID INT NOT NULL,
+    ProcedureName VARCHAR(255) NOT NULL,
+    SurgeonID INT NOT NULL,
+    AnaesthetistID INT,  -- may be assigned after booking; required to start
+    ScheduledStart DATETIME NOT NULL,
+    EstimatedMinutes INT NOT NULL,
+    ScheduledEnd DATETIME AS (ScheduledStart + INTERVAL EstimatedMinutes MINUTE) STORED,
+    Emergency BOOLEAN NOT NULL DEFAULT FALSE,
+    Status ENUM('scheduled', 'in_progress', 'in_recovery', 'done', 'cancelled') NOT NULL DEFAULT 'scheduled',
+    StartedAt DATETIME,
+    SurgeryEndedAt DATETIME,  -- patient out of theatre
+    CompletedAt DATETIME,  -- patient discharged from recovery
+    Notes TEXT,
+    CancelReason VARCHAR(255),
+    BookedBy INT,  -- EmployeeID
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_case_theatre (TheatreID, ScheduledStart),
+    INDEX idx_case_surgeon (SurgeonID, ScheduledStart),
+    INDEX idx_case_anaesthetist (AnaesthetistID, ScheduledStart),
+    INDEX idx_case_patient (PatientID, ScheduledStart),
+    CONSTRAINT chk_case_duration CHECK (EstimatedMinutes BETWEEN 1 AND 1440),
+    FOREIGN KEY (TheatreID) REFERENCES Theatres(TheatreID),
+    FOREIGN KEY (PatientID) REFERENCES Patients(PatientID),
+    FOREIGN KEY (SurgeonID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (AnaesthetistID) REFERENCES Doctors(DoctorID),
+    FOREIGN KEY (BookedBy) REFERENCES Employees(EmployeeID)
+);
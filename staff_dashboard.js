@@ -102,6 +102,9 @@ document.addEventListener('DOMContentLoaded', function() {
     // Load the staff profile data
     fetchStaffProfile();
     
+    // Keep the bed status tab up to date from the bed event stream
+    watchBedEvents();
+
     // Initialize dashboard with data
     loadSampleData();
     
@@ -1884,6 +1887,23 @@ document.addEventListener('DOMContentLoaded', function() {
         console.log('Populated select with sample doctor data:', selectId);
     }
 
+    // Reload the bed status tab when the server pushes a bed change. Bursts of
+    // events (a transfer updates two beds and the counters) cause one reload.
+    function watchBedEvents() {
+        if (!window.EventSource) return;
+        const events = new EventSource('http://localhost:8080/api/beds/events');
+        let reloadTimer = null;
+        const reload = () => {
+            const tab = document.getElementById('bed-status');
+            if (!tab || !tab.classList.contains('active')) return;
+            clearTimeout(reloadTimer);
+            reloadTimer = setTimeout(loadBeds, 300);
+        };
+        ['assigned', 'transferred', 'swapped', 'discharged', 'cleaned', 'status', 'reset'].forEach(type => {
+            events.addEventListener(type, reload);
+        });
+    }
+
     // Function to load and render beds
     async function loadBeds() {
         try {